@@ -1,11 +1,15 @@
 package main
 
 import (
+	"bytes"
 	"crypto/tls"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"strings"
@@ -38,7 +42,7 @@ func list() {
 	}
 }
 
-func restart(nsServerAddr, username, password string) {
+func eventingNodes(nsServerAddr string) []string {
 	svc := "eventingSSL"
 	cinfo, err := util.FetchNewClusterInfoCache(nsServerAddr)
 	if err != nil {
@@ -54,6 +58,11 @@ func restart(nsServerAddr, username, password string) {
 		}
 		nodes = append(nodes, addr)
 	}
+	return nodes
+}
+
+func restart(nsServerAddr, username, password string) {
+	nodes := eventingNodes(nsServerAddr)
 	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
 
 	for _, node := range nodes {
@@ -90,6 +99,39 @@ func flush(nsServerAddr, username, password string) {
 	restart(nsServerAddr, username, password)
 }
 
+func checkTimers(nsServerAddr, username, password, appName string, repair bool) {
+	nodes := eventingNodes(nsServerAddr)
+	if len(nodes) == 0 {
+		log.Fatalf("No eventing nodes found")
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+
+	addr := fmt.Sprintf("https://%s/api/v1/functions/%s/timers/check?repair=%t", nodes[0], url.PathEscape(appName), repair)
+	request, err := http.NewRequest("POST", addr, nil)
+	if err != nil {
+		log.Fatalf("Unable to create request, err: %v", err)
+	}
+	request.SetBasicAuth(username, password)
+
+	log.Printf("Checking timers of function %s via %s, repair: %t", appName, nodes[0], repair)
+	response, err := client.Do(request)
+	if err != nil {
+		log.Fatalf("Unable to check timers, err: %v", err)
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		log.Fatalf("Unable to read timer check report, err: %v", err)
+	}
+
+	var report bytes.Buffer
+	if response.StatusCode != http.StatusOK || json.Indent(&report, body, "", "  ") != nil {
+		log.Fatalf("Timer check failed, status: %s response: %s", response.Status, body)
+	}
+	fmt.Println(report.String())
+}
+
 func main() {
 	listOption := flag.Bool("list", false,
 		fmt.Sprintf("list all metakv entries under %s", eventingPath))
@@ -97,11 +139,30 @@ func main() {
 		fmt.Sprintf("deletes all metakv paths (except the following -\n%s\n%s\n%s) and restarts all eventing nodes",
 			rebalanceTokenPath, keepNodesPath, settingsConfigPath))
 
+	checkTimersOption := flag.String("checkTimers", "",
+		"check consistency of timer stores of the named function and print a report")
+	repairOption := flag.Bool("repair", false,
+		"with -checkTimers, delete orphan timer documents and rewrite inconsistent spans")
+
+	redactOption := flag.Bool("redact", false,
+		"write redacted copies of eventing and application log files or directories given as arguments, along with their rotated files")
+	outOption := flag.String("out", "", "with -redact, directory to write redacted logs to")
+	saltOption := flag.String("salt", "", "with -redact, salt to hash user data with, generated if not given")
+	redactModeOption := flag.String("redactMode", redactHash,
+		fmt.Sprintf("with -redact, %s user data with salt or %s it", redactHash, redactRemove))
+	logTypeOption := flag.String("logType", logTypeAuto,
+		fmt.Sprintf("with -redact, redact files as %s or %s logs, or %s to tell from their first line", logTypeEventing, logTypeApp, logTypeAuto))
+
 	userOption := flag.String("user", "", "Username")
 	pwdOption := flag.String("password", "", "Password")
 	hostOption := flag.String("host", "", "Host:Port of the Couchbase node. Example - localhost:8091")
 
 	flag.Parse()
+	if *redactOption {
+		redact(flag.Args(), *outOption, *saltOption, *redactModeOption, *logTypeOption)
+		return
+	}
+
 	if *userOption == "" || *pwdOption == "" || *hostOption == "" {
 		flag.Usage()
 		log.Fatal("Options -user, -password, -host all are necessary")
@@ -130,4 +191,8 @@ func main() {
 	if *flushOption {
 		flush(*hostOption, *userOption, *pwdOption)
 	}
+
+	if *checkTimersOption != "" {
+		checkTimers(*hostOption, *userOption, *pwdOption, *checkTimersOption, *repairOption)
+	}
 }
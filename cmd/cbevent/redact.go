@@ -0,0 +1,326 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	udOpen  = "<ud>"
+	udClose = "</ud>"
+)
+
+// Ways of redacting user data
+const (
+	redactHash   = "hash"
+	redactRemove = "remove"
+)
+
+// Types of log a file can be redacted as
+const (
+	logTypeAuto     = "auto"
+	logTypeEventing = "eventing"
+	logTypeApp      = "app"
+)
+
+var (
+	// Files rotated by eventing or ns_server carry an index, and possibly are gzip compressed
+	rotatedSuffixRegex = regexp.MustCompile(`^\.\d+(\.gz)?$`)
+
+	// Application log lines are "<timestamp> [<level>] <message>", message being user data
+	appLogLineRegex = regexp.MustCompile(`^(\S+ \[\w+\] )(.*)$`)
+
+	// Eventing log lines are "<timestamp> [<level>] <message>" too, but with levels named as
+	// logging package does, unlike upper case levels of application logs, or are JSON objects
+	eventingLogLineRegex = regexp.MustCompile(`^(\S+ \[(Fatal|Error|Warn|Info|Verbose|Timing|Debug|Trace)\] |\{")`)
+)
+
+type redactSummary struct {
+	file     string
+	lines    int64
+	redacted int64
+	bytes    int64
+}
+
+// redactor replaces user data, tagged with <ud></ud> or making up messages of application
+// logs, by its salted hash or with nothing. Tags may nest and span lines, so state carries
+// across lines of a file. Type of log is worked out from its first line, unless given
+type redactor struct {
+	salt    string
+	mode    string
+	logType string
+	depth   int
+	pending strings.Builder
+	summary *redactSummary
+}
+
+func (r *redactor) line(line string) string {
+	r.summary.lines++
+
+	if r.logType == logTypeAuto && strings.TrimSpace(line) != "" {
+		r.logType = detectLogType(line)
+	}
+
+	if r.logType == logTypeApp && r.depth == 0 {
+		if match := appLogLineRegex.FindStringSubmatch(line); match != nil {
+			return match[1] + r.userData(match[2])
+		}
+		// Continuation of a multi line message
+		return r.userData(line)
+	}
+
+	var out strings.Builder
+	for len(line) > 0 {
+		if r.depth == 0 {
+			i := strings.Index(line, udOpen)
+			if i < 0 {
+				out.WriteString(line)
+				break
+			}
+			out.WriteString(line[:i])
+			line = line[i+len(udOpen):]
+			r.depth = 1
+			r.pending.Reset()
+			continue
+		}
+
+		openAt, closeAt := strings.Index(line, udOpen), strings.Index(line, udClose)
+		switch {
+		case closeAt < 0 && openAt < 0:
+			r.pending.WriteString(line + "\n")
+			line = ""
+
+		case openAt >= 0 && (closeAt < 0 || openAt < closeAt):
+			r.pending.WriteString(line[:openAt+len(udOpen)])
+			line = line[openAt+len(udOpen):]
+			r.depth++
+
+		default:
+			r.depth--
+			if r.depth > 0 {
+				r.pending.WriteString(line[:closeAt+len(udClose)])
+				line = line[closeAt+len(udClose):]
+				continue
+			}
+			r.pending.WriteString(line[:closeAt])
+			line = line[closeAt+len(udClose):]
+			out.WriteString(r.userData(r.pending.String()))
+		}
+	}
+	return out.String()
+}
+
+// Flushes user data left open at end of file, so none of it goes out unredacted
+func (r *redactor) finish() string {
+	if r.depth == 0 {
+		return ""
+	}
+	r.depth = 0
+	return r.userData(strings.TrimSuffix(r.pending.String(), "\n"))
+}
+
+func (r *redactor) userData(data string) string {
+	r.summary.redacted++
+	r.summary.bytes += int64(len(data))
+
+	if r.mode == redactRemove {
+		return udOpen + udClose
+	}
+	sum := sha1.Sum([]byte(r.salt + data))
+	return udOpen + hex.EncodeToString(sum[:]) + udClose
+}
+
+// Tells eventing logs apart from application logs by how their lines are laid out. Lines
+// that look like neither are taken to be of an application log, so all of it is redacted
+func detectLogType(line string) string {
+	if eventingLogLineRegex.MatchString(line) {
+		return logTypeEventing
+	}
+	return logTypeApp
+}
+
+// Expands paths into log files, taking in every regular file of a directory and rotated
+// files of a log along with it
+func logFiles(paths []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(file string) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if fi.IsDir() {
+			entries, err := ioutil.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if entry.Mode().IsRegular() {
+					add(filepath.Join(path, entry.Name()))
+				}
+			}
+			continue
+		}
+
+		add(path)
+		rotated, err := filepath.Glob(path + ".*")
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(rotated)
+		for _, file := range rotated {
+			if rotatedSuffixRegex.MatchString(strings.TrimPrefix(file, path)) {
+				add(file)
+			}
+		}
+	}
+	return files, nil
+}
+
+func redactFile(path, outDir, salt, mode, logType string) (*redactSummary, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	compressed := strings.HasSuffix(path, ".gz")
+	if compressed {
+		gz, err := gzip.NewReader(in)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read gzip file: %s, err: %v", path, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	outPath := filepath.Join(outDir, filepath.Base(path))
+	out, err := os.OpenFile(outPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	// Closed again once written, to catch errors, which a second close ignores
+	defer out.Close()
+
+	var writer io.Writer = out
+	var gzOut *gzip.Writer
+	if compressed {
+		gzOut = gzip.NewWriter(out)
+		writer = gzOut
+	}
+	w := bufio.NewWriter(writer)
+
+	summary := &redactSummary{file: path}
+	r := &redactor{salt: salt, mode: mode, logType: logType, summary: summary}
+
+	br := bufio.NewReader(reader)
+	for {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			newline := strings.HasSuffix(line, "\n")
+			redacted := r.line(strings.TrimSuffix(line, "\n"))
+			w.WriteString(redacted)
+			// Lines within user data spanning lines fold into its redacted form
+			if newline && r.depth == 0 {
+				w.WriteString("\n")
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read file: %s, err: %v", path, err)
+		}
+	}
+
+	if rest := r.finish(); rest != "" {
+		w.WriteString(rest + "\n")
+	}
+
+	// Write errors are held by buffered writer till it's flushed
+	if err = w.Flush(); err != nil {
+		return nil, fmt.Errorf("unable to write file: %s, err: %v", outPath, err)
+	}
+	if gzOut != nil {
+		if err = gzOut.Close(); err != nil {
+			return nil, fmt.Errorf("unable to write file: %s, err: %v", outPath, err)
+		}
+	}
+	if err = out.Close(); err != nil {
+		return nil, fmt.Errorf("unable to write file: %s, err: %v", outPath, err)
+	}
+	return summary, nil
+}
+
+// Writes redacted copies of eventing and application logs to outDir, with user data hashed
+// using salt or removed, and prints what was redacted in each of them
+func redact(paths []string, outDir, salt, mode, logType string) {
+	if mode != redactHash && mode != redactRemove {
+		log.Fatalf("Redaction mode should be %s or %s", redactHash, redactRemove)
+	}
+	if logType != logTypeAuto && logType != logTypeEventing && logType != logTypeApp {
+		log.Fatalf("Log type should be %s, %s or %s", logTypeAuto, logTypeEventing, logTypeApp)
+	}
+	if outDir == "" {
+		log.Fatal("Option -out is necessary to redact logs")
+	}
+	if len(paths) == 0 {
+		log.Fatal("No log files given to redact")
+	}
+
+	files, err := logFiles(paths)
+	if err != nil {
+		log.Fatalf("Unable to list log files, err: %v", err)
+	}
+
+	if err = os.MkdirAll(outDir, 0700); err != nil {
+		log.Fatalf("Unable to create directory: %s, err: %v", outDir, err)
+	}
+
+	if mode == redactHash && salt == "" {
+		buf := make([]byte, 16)
+		if _, err = rand.Read(buf); err != nil {
+			log.Fatalf("Unable to generate salt, err: %v", err)
+		}
+		salt = hex.EncodeToString(buf)
+		log.Printf("Hashing user data with generated salt: %s, keep it to match hashes with user data", salt)
+	}
+
+	var total redactSummary
+	fmt.Printf("%-60s %12s %12s %14s\n", "FILE", "LINES", "REDACTED", "BYTES")
+	for _, file := range files {
+		summary, err := redactFile(file, outDir, salt, mode, logType)
+		if err != nil {
+			log.Fatalf("Unable to redact %s, err: %v", file, err)
+		}
+		fmt.Printf("%-60s %12d %12d %14d\n", summary.file, summary.lines, summary.redacted, summary.bytes)
+
+		total.lines += summary.lines
+		total.redacted += summary.redacted
+		total.bytes += summary.bytes
+	}
+	fmt.Printf("%-60s %12d %12d %14d\n", fmt.Sprintf("TOTAL (%d files)", len(files)),
+		total.lines, total.redacted, total.bytes)
+	fmt.Printf("Redacted logs written to %s\n", outDir)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// Redacts lines the way redactFile does, folding lines within user data spanning lines
+func redactLines(r *redactor, lines ...string) string {
+	var out strings.Builder
+	for _, line := range lines {
+		out.WriteString(r.line(line))
+		if r.depth == 0 {
+			out.WriteString("\n")
+		}
+	}
+	if rest := r.finish(); rest != "" {
+		out.WriteString(rest + "\n")
+	}
+	return out.String()
+}
+
+func TestRedactTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		out      string
+		redacted int64
+	}{
+		{
+			name:  "no user data",
+			lines: []string{"2021-06-01T10:00:00.000+00:00 [Info] Consumer::run started"},
+			out:   "2021-06-01T10:00:00.000+00:00 [Info] Consumer::run started\n",
+		},
+		{
+			name:     "tags on a line",
+			lines:    []string{"key: <ud>ssn_1</ud> cas: 1 doc: <ud>{\"a\":1}</ud> done"},
+			out:      "key: <ud></ud> cas: 1 doc: <ud></ud> done\n",
+			redacted: 2,
+		},
+		{
+			name:     "nested tags",
+			lines:    []string{"err: <ud>outer <ud>inner</ud> rest</ud> done"},
+			out:      "err: <ud></ud> done\n",
+			redacted: 1,
+		},
+		{
+			name:     "tag spanning lines",
+			lines:    []string{"doc: <ud>{", "  \"a\": 1", "}</ud> done", "next line"},
+			out:      "doc: <ud></ud> done\nnext line\n",
+			redacted: 1,
+		},
+		{
+			name:     "tag left open",
+			lines:    []string{"doc: <ud>{", "  \"a\": 1"},
+			out:      "doc: <ud></ud>\n",
+			redacted: 1,
+		},
+		{
+			name:  "stray close tag",
+			lines: []string{"text </ud> more"},
+			out:   "text </ud> more\n",
+		},
+	}
+
+	for _, test := range tests {
+		r := &redactor{mode: redactRemove, logType: logTypeEventing, summary: &redactSummary{}}
+		if out := redactLines(r, test.lines...); out != test.out {
+			t.Errorf("%s: expected %q, got %q", test.name, test.out, out)
+		}
+		if r.summary.redacted != test.redacted {
+			t.Errorf("%s: expected %d redactions, got %d", test.name, test.redacted, r.summary.redacted)
+		}
+		if r.summary.lines != int64(len(test.lines)) {
+			t.Errorf("%s: expected %d lines, got %d", test.name, len(test.lines), r.summary.lines)
+		}
+	}
+}
+
+func TestRedactHash(t *testing.T) {
+	redactWith := func(salt, line string) string {
+		r := &redactor{salt: salt, mode: redactHash, logType: logTypeEventing, summary: &redactSummary{}}
+		return r.line(line)
+	}
+
+	first, again := redactWith("salt", "key: <ud>ssn_1</ud>"), redactWith("salt", "key: <ud>ssn_1</ud>")
+	if first != again {
+		t.Errorf("Expected same user data to hash alike, got %q and %q", first, again)
+	}
+	if strings.Contains(first, "ssn_1") || !strings.HasPrefix(first, "key: <ud>") || !strings.HasSuffix(first, "</ud>") {
+		t.Errorf("Expected user data replaced by its hash within tags, got %q", first)
+	}
+	if other := redactWith("pepper", "key: <ud>ssn_1</ud>"); other == first {
+		t.Errorf("Expected hash to depend on salt, got %q for both", first)
+	}
+}
+
+func TestRedactAppLog(t *testing.T) {
+	r := &redactor{mode: redactRemove, logType: logTypeAuto, summary: &redactSummary{}}
+	out := redactLines(r,
+		"2021-06-01T10:00:00.000+00:00 [INFO] \"credit score\" 720",
+		"continued message",
+		"2021-06-01T10:00:01.000+00:00 [ERROR] <ud>ssn_1</ud> failed")
+
+	expected := "2021-06-01T10:00:00.000+00:00 [INFO] <ud></ud>\n<ud></ud>\n2021-06-01T10:00:01.000+00:00 [ERROR] <ud></ud>\n"
+	if out != expected {
+		t.Errorf("Expected %q, got %q", expected, out)
+	}
+}
+
+func TestDetectLogType(t *testing.T) {
+	tests := []struct {
+		line    string
+		logType string
+	}{
+		{line: "2021-06-01T10:00:00.000+00:00 [Info] ServiceMgr::start started", logType: logTypeEventing},
+		{line: "2021-06-01T10:00:00.000+00:00 [Trace] Consumer::run vb: 1", logType: logTypeEventing},
+		{line: `{"ts":"2021-06-01T10:00:00.000+00:00","level":"Info","msg":"started"}`, logType: logTypeEventing},
+		{line: "2021-06-01T10:00:00.000+00:00 [INFO] \"credit score\" 720", logType: logTypeApp},
+		{line: "2021-06-01T10:00:00.000+00:00 [WARNING] slow", logType: logTypeApp},
+		{line: "garbled", logType: logTypeApp},
+	}
+
+	for _, test := range tests {
+		if logType := detectLogType(test.line); logType != test.logType {
+			t.Errorf("Expected %q to be of %s log, got %s", test.line, test.logType, logType)
+		}
+	}
+
+	// Type is worked out from first line that isn't blank
+	r := &redactor{mode: redactRemove, logType: logTypeAuto, summary: &redactSummary{}}
+	redactLines(r, "", "2021-06-01T10:00:00.000+00:00 [Info] ServiceMgr::start started")
+	if r.logType != logTypeEventing {
+		t.Errorf("Expected log type to be detected past blank line, got %s", r.logType)
+	}
+}
@@ -94,6 +94,18 @@ func main() {
 		}
 	}(s)
 
+	// For replaying vbuckets of deployed functions
+	go func(s *supervisor.SuperSupervisor) {
+		cancelCh := make(chan struct{})
+		for {
+			err := metakv.RunObserveChildren(supervisor.MetakvAppsReprocessPath, s.AppsReprocessCallback, cancelCh)
+			if err != nil {
+				logging.Errorf("Eventing::main metakv observe error for apps reprocess, err: %v. Retrying.", err)
+				time.Sleep(2 * time.Second)
+			}
+		}
+	}(s)
+
 	// For starting debugger
 	go func(s *supervisor.SuperSupervisor) {
 		cancelCh := make(chan struct{})
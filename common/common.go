@@ -3,14 +3,17 @@ package common
 import (
 	"errors"
 	"net"
+	"time"
 )
 
 type DcpStreamBoundary string
 
 const (
-	DcpEverything = DcpStreamBoundary("everything")
-	DcpFromNow    = DcpStreamBoundary("from_now")
-	DcpFromPrior  = DcpStreamBoundary("from_prior")
+	DcpEverything    = DcpStreamBoundary("everything")
+	DcpFromNow       = DcpStreamBoundary("from_now")
+	DcpFromPrior     = DcpStreamBoundary("from_prior")
+	DcpFromSeqNos    = DcpStreamBoundary("from_seqnos")
+	DcpFromTimestamp = DcpStreamBoundary("from_timestamp")
 )
 
 type ChangeType string
@@ -64,13 +67,19 @@ var ErrRetryTimeout = errors.New("retry timeout")
 type EventingProducer interface {
 	AddMetadataPrefix(key string) Key
 	Auth() string
+	BackfillJobSeqNos() []uint64
+	BackfillJobStatus() *BackfillJobStatus
 	CfgData() string
 	CheckpointBlobDump() map[string]interface{}
 	CleanupMetadataBucket(skipCheckpointBlobs bool) error
+	CancelTimer(callback, reference string) (bool, error)
+	CheckTimers(repair bool) *TimerCheckReport
 	CleanupUDSs()
 	ClearEventStats()
+	CrashHistory() []*CrashEntry
 	DcpFeedBoundary() string
 	GetAppCode() string
+	GetAppLogSinkStats() map[string]*AppLogSinkStats
 	GetDcpEventsRemainingToProcess() uint64
 	GetDebuggerURL() (string, error)
 	GetEventingConsumerPids() map[string]int
@@ -80,13 +89,17 @@ type EventingProducer interface {
 	GetHandlerCode() string
 	GetLatencyStats() map[string]uint64
 	GetCurlLatencyStats() map[string]uint64
+	GetDcpLatencyStats() map[string]uint64
 	GetLcbExceptionsStats() map[string]uint64
 	GetMetaStoreStats() map[string]uint64
+	GetMemoryUsage() *MemoryUsage
 	GetMetadataPrefix() string
 	GetNsServerPort() string
 	GetVbOwner(vb uint16) (string, string, error)
 	GetSeqsProcessed() map[int]int64
 	GetSourceMap() string
+	GetTimerLatenessStats() map[string]uint64
+	GetTimerMaxLateness() int64
 	GetDebuggerToken() string
 	InternalVbDistributionStats() map[string]string
 	IsEventingNodeAlive(eventingHostPortAddr, nodeUUID string) bool
@@ -95,6 +108,7 @@ type EventingProducer interface {
 	KillAndRespawnEventingConsumer(consumer EventingConsumer)
 	KvHostPorts() []string
 	LenRunningConsumers() int
+	ListTimers(filter *TimerFilter) ([]*PendingTimer, error)
 	MetadataBucket() string
 	NotifyInit()
 	NotifyPrepareTopologyChange(ejectNodes, keepNodes []string)
@@ -105,9 +119,13 @@ type EventingProducer interface {
 	NsServerNodeCount() int
 	PauseProducer()
 	PlannerStats() []*PlannerNodeVbMapping
+	QuarantinedEvents() map[uint16]map[uint64]struct{}
 	RebalanceStatus() bool
 	RebalanceTaskProgress() *RebalanceProgress
 	RemoveConsumerToken(workerName string)
+	Reprocess(req *ReprocessRequest)
+	RollbackHistory() []*RollbackEntry
+	ExportTrace(trace *EventTrace)
 	SignalBootstrapFinish()
 	SignalStartDebugger(token string) error
 	SignalStopDebugger() error
@@ -119,10 +137,14 @@ type EventingProducer interface {
 	StopRunningConsumers()
 	String() string
 	TimerDebugStats() map[int]map[string]interface{}
+	TraceSampleRate() float64
 	IsTrapEvent() bool
 	SetTrapEvent(value bool)
+	SetDefaultAppLogSinks(sinks []string)
+	SetMemoryThrottle(throttle bool)
 	UpdateMemoryQuota(quota int64)
 	VbDcpEventsRemainingToProcess() map[int]int64
+	VbLag() []*VbLag
 	VbDistributionStatsFromMetadata() map[string]map[string]string
 	VbSeqnoStats() map[int][]map[string]interface{}
 	WriteAppLog(log string)
@@ -132,10 +154,13 @@ type EventingProducer interface {
 
 // EventingConsumer interface to export functions from eventing_consumer
 type EventingConsumer interface {
+	CancelTimer(callback, reference string) (bool, error)
 	CheckIfQueuesAreDrained() error
+	CheckTimers(repair bool) []*TimerStoreReport
 	ClearEventStats()
 	CloseAllRunningDcpFeeds()
 	ConsumerName() string
+	CrashSuspects() []*CrashSuspect
 	DcpEventsRemainingToProcess() uint64
 	EventingNodeUUIDs() []string
 	EventsProcessedPSec() *EventProcessingStats
@@ -145,13 +170,18 @@ type EventingConsumer interface {
 	GetHandlerCode() string
 	GetLatencyStats() map[string]uint64
 	GetCurlLatencyStats() map[string]uint64
+	GetDcpLatencyStats() map[string]uint64
 	GetLcbExceptionsStats() map[string]uint64
 	GetMetaStoreStats() map[string]uint64
+	GetMemoryUsage() *MemoryUsage
 	GetSourceMap() string
+	GetTimerLatenessStats() map[string]uint64
+	GetTimerMaxLateness() int64
 	HandleV8Worker() error
 	HostPortAddr() string
 	Index() int
 	InternalVbDistributionStats() []uint16
+	ListTimers(filter *TimerFilter) ([]*PendingTimer, error)
 	NodeUUID() string
 	NotifyClusterChange()
 	NotifyRebalanceStop()
@@ -159,11 +189,14 @@ type EventingConsumer interface {
 	Pid() int
 	RebalanceStatus() bool
 	RebalanceTaskProgress() *RebalanceProgress
+	Reprocess(req *ReprocessRequest)
 	ResetBootstrapDone()
+	RollbackHistory() []*RollbackEntry
 	Serve()
 	SetConnHandle(net.Conn)
 	SetFeedbackConnHandle(net.Conn)
 	SetRebalanceStatus(status bool)
+	SetMemoryThrottle(throttle bool)
 	SignalBootstrapFinish()
 	SignalConnected()
 	SignalFeedbackConnected()
@@ -175,6 +208,7 @@ type EventingConsumer interface {
 	UpdateEventingNodesUUIDs(keepNodes, ejectNodes []string)
 	UpdateWorkerQueueMemCap(quota int64)
 	VbDcpEventsRemainingToProcess() map[int]int64
+	VbLag() []*VbLag
 	VbEventingNodeAssignMapUpdate(map[uint16]string)
 	VbProcessingStats() map[uint16]map[string]interface{}
 	VbSeqnoStats() map[int]map[string]interface{}
@@ -183,14 +217,21 @@ type EventingConsumer interface {
 }
 
 type EventingSuperSup interface {
+	BackfillJobStatus(appName string) (*BackfillJobStatus, error)
 	BootstrapAppList() map[string]string
+	CancelTimer(appName, callback, reference string) (bool, error)
 	CheckpointBlobDump(appName string) (interface{}, error)
+	CheckTimers(appName string, repair bool) (*TimerCheckReport, error)
 	ClearEventStats()
 	CleanupProducer(appName string, skipMetaCleanup bool) error
+	CompleteBackfillJob(appName string)
+	CrashHistory(appName string) ([]*CrashEntry, error)
 	DcpFeedBoundary(fnName string) (string, error)
 	DeployedAppList() []string
+	FailFunction(appName string)
 	GetEventProcessingStats(appName string) map[string]uint64
 	GetAppCode(appName string) string
+	GetAppLogSinkStats(appName string) map[string]*AppLogSinkStats
 	GetAppState(appName string) int8
 	GetDcpEventsRemainingToProcess(appName string) uint64
 	GetDebuggerURL(appName string) (string, error)
@@ -201,25 +242,32 @@ type EventingSuperSup interface {
 	GetHandlerCode(appName string) string
 	GetLatencyStats(appName string) map[string]uint64
 	GetCurlLatencyStats(appName string) map[string]uint64
+	GetDcpLatencyStats(appName string) map[string]uint64
 	GetLcbExceptionsStats(appName string) map[string]uint64
 	GetLocallyDeployedApps() map[string]string
 	GetMetaStoreStats(appName string) map[string]uint64
+	GetMemoryStats(appName string) *FunctionMemoryStats
 	GetSeqsProcessed(appName string) map[int]int64
 	GetSourceMap(appName string) string
+	GetTimerLatenessStats(appName string) map[string]uint64
+	GetTimerMaxLateness(appName string) int64
 	InternalVbDistributionStats(appName string) map[string]string
 	KillAllConsumers()
+	ListTimers(appName string, filter *TimerFilter) ([]*PendingTimer, error)
 	NotifyPrepareTopologyChange(ejectNodes, keepNodes []string)
 	PlannerStats(appName string) []*PlannerNodeVbMapping
 	RebalanceStatus() bool
 	RebalanceTaskProgress(appName string) (*RebalanceProgress, error)
 	RemoveProducerToken(appName string)
 	RestPort() string
+	RollbackHistory(appName string) ([]*RollbackEntry, error)
 	SignalStopDebugger(appName string) error
 	SpanBlobDump(appName string) (interface{}, error)
 	StopProducer(appName string, skipMetaCleanup bool)
 	TimerDebugStats(appName string) (map[int]map[string]interface{}, error)
 	VbDcpEventsRemainingToProcess(appName string) map[int]int64
 	VbDistributionStatsFromMetadata(appName string) map[string]map[string]string
+	VbLag(appName string) ([]*VbLag, error)
 	VbSeqnoStats(appName string) (map[int][]map[string]interface{}, error)
 	WriteDebuggerURL(appName, url string)
 	WriteDebuggerToken(appName, token string, hostnames []string)
@@ -253,6 +301,262 @@ type RebalanceProgress struct {
 	NodeLevelStats        interface{}
 }
 
+// BackfillJobStatus captures progress of a function deployed as one-shot backfill job
+type BackfillJobStatus struct {
+	Completed      bool    `json:"completed"`
+	DrainingTimers bool    `json:"draining_timers"`
+	Progress       float64 `json:"progress"`
+	StartTimestamp string  `json:"start_timestamp"`
+}
+
+// CrashEntry captures an eventing-consumer crash or respawn along with events that were
+// in flight when it happened
+type CrashEntry struct {
+	Action     string          `json:"action,omitempty"`
+	Suspects   []*CrashSuspect `json:"suspects,omitempty"`
+	Timestamp  string          `json:"timestamp"`
+	WorkerName string          `json:"worker_name"`
+}
+
+// CrashSuspect is an event sent to eventing-consumer, but not yet processed by it when it crashed
+type CrashSuspect struct {
+	Count   int    `json:"count"`
+	Key     string `json:"key"`
+	SeqNo   uint64 `json:"seq_no"`
+	Vbucket uint16 `json:"vb"`
+}
+
+// PendingTimer is a timer yet to fire, as stored in metadata bucket
+type PendingTimer struct {
+	AlarmKey  string `json:"alarm_key"`
+	Callback  string `json:"callback"`
+	Context   string `json:"context"`
+	Due       int64  `json:"due"`
+	Reference string `json:"reference,omitempty"`
+	Seq       int64  `json:"seq"`
+	Vbucket   uint16 `json:"vb"`
+}
+
+// TimerFilter narrows down pending timers listed for a function. Negative Vbucket
+// matches all vbuckets and zero From/To leave due time range open. Only Limit
+// earliest matching timers are returned, past After if set
+type TimerFilter struct {
+	After     *TimerCursor
+	Callback  string
+	From      int64
+	Limit     int
+	Reference string
+	To        int64
+	Vbucket   int
+}
+
+// TimerCursor is position of a pending timer in the order timers are listed, which is
+// by due time, then vbucket and then seq among timers due at the same time
+type TimerCursor struct {
+	Due     int64
+	Seq     int64
+	Vbucket uint16
+}
+
+// AppLogFilter narrows down entries of application log of a function. Zero Since leaves
+// start open and Grep is a regular expression matched against messages. Only Limit latest
+// entries are returned. Non-negative Offset reads entries written past it instead, to follow
+// the log
+type AppLogFilter struct {
+	Grep   string
+	Limit  int
+	Offset int64
+	Since  time.Time
+}
+
+// AppLogEntry is a message logged by a handler, lines of multi line messages joined
+type AppLogEntry struct {
+	Level     string    `json:"level"`
+	Message   string    `json:"msg"`
+	Node      string    `json:"node,omitempty"`
+	Timestamp time.Time `json:"ts"`
+}
+
+// AppLogSinkStats counts application log records forwarded to a sink. Records are dropped when
+// sink queue is full and failed when collector couldn't be reached or rejected them
+type AppLogSinkStats struct {
+	Dropped uint64 `json:"dropped"`
+	Failed  uint64 `json:"failed"`
+	Sent    uint64 `json:"sent"`
+}
+
+// LatencySummary sums up a latency histogram, with values in microseconds
+type LatencySummary struct {
+	Count uint64 `json:"count"`
+	Max   int64  `json:"max"`
+	P50   int64  `json:"p50"`
+	P90   int64  `json:"p90"`
+	P99   int64  `json:"p99"`
+	P999  int64  `json:"p99_9"`
+}
+
+// StatsSample is activity of a function on a node over an interval of stats history. Latency
+// of handler executions over the interval is a histogram as util.Histogram marshals into
+type StatsSample struct {
+	DcpBacklog      uint64            `json:"dcp_backlog"`
+	EventsProcessed uint64            `json:"events_processed"`
+	Failures        uint64            `json:"failures"`
+	Latency         map[string]uint64 `json:"latency,omitempty"`
+	TimersFired     uint64            `json:"timers_fired"`
+	Timestamp       time.Time         `json:"ts"`
+}
+
+// VbLag is how far processing of a vbucket is behind, as of its owner. Throughput is in seq nos
+// processed per sec over recent minutes, and EtaSecs nil if vbucket has backlog but isn't
+// progressing
+type VbLag struct {
+	Vbucket        uint16  `json:"vb"`
+	Node           string  `json:"node"`
+	Worker         string  `json:"worker"`
+	StreamStatus   string  `json:"stream_status"`
+	HighSeqNo      uint64  `json:"high_seq_no"`
+	ProcessedSeqNo uint64  `json:"processed_seq_no"`
+	Backlog        uint64  `json:"backlog"`
+	Throughput     float64 `json:"throughput"`
+	EtaSecs        *int64  `json:"eta_secs"`
+}
+
+// EventTrace is a sampled event, with unix time in ns it reached each stage of its way from
+// DCP to its handler being done
+type EventTrace struct {
+	TraceID   string `json:"trace_id"`
+	Function  string `json:"function"`
+	Worker    string `json:"worker"`
+	Opcode    string `json:"opcode"`
+	Vbucket   uint16 `json:"vb"`
+	SeqNo     uint64 `json:"seq_no"`
+	DcpTs     int64  `json:"dcp_ts"`
+	SendTs    int64  `json:"send_ts"`
+	WriteTs   int64  `json:"write_ts"`
+	DequeueTs int64  `json:"dequeue_ts"`
+	DoneTs    int64  `json:"done_ts"`
+}
+
+// AppLogTail is application log read on a node, along with offset into current log file to
+// follow it from
+type AppLogTail struct {
+	Entries []*AppLogEntry `json:"entries"`
+	Offset  int64          `json:"offset"`
+}
+
+// MemoryUsage is memory held by queues of a function, in bytes. Worker queue is reported by
+// eventing-consumer processes, so it lags behind by a stats interval
+type MemoryUsage struct {
+	DcpFeed     int64 `json:"dcp_feed"`
+	TimerQueues int64 `json:"timer_queues"`
+	WorkerQueue int64 `json:"worker_queue"`
+}
+
+// Total returns memory held across all queues
+func (m *MemoryUsage) Total() int64 {
+	return m.DcpFeed + m.TimerQueues + m.WorkerQueue
+}
+
+// FunctionMemoryStats is memory usage of a function against allowance given to it out of node
+// memory quota. Throttled functions have their DCP feed slowed down until node usage drops
+type FunctionMemoryStats struct {
+	Allowance int64       `json:"allowance"`
+	Throttled bool        `json:"throttled"`
+	Usage     MemoryUsage `json:"usage"`
+	Used      int64       `json:"used"`
+}
+
+// TimerStoreReport is outcome of checking consistency of timer store of a vbucket. Orphans are
+// alarms without a context pointing back at them and contexts whose alarm is gone, rows outside
+// span are row counters that span doesn't cover, so their timers would never be scanned
+type TimerStoreReport struct {
+	AlarmsChecked   int      `json:"alarms_checked"`
+	Error           string   `json:"error,omitempty"`
+	OrphanAlarms    []string `json:"orphan_alarms,omitempty"`
+	OrphanContexts  []string `json:"orphan_contexts,omitempty"`
+	OrphansRemoved  int      `json:"orphans_removed,omitempty"`
+	RowsChecked     int      `json:"rows_checked"`
+	RowsOutsideSpan []int64  `json:"rows_outside_span,omitempty"`
+	SpanMismatch    bool     `json:"span_mismatch,omitempty"`
+	SpanRewritten   bool     `json:"span_rewritten,omitempty"`
+	Vbucket         uint16   `json:"vb"`
+}
+
+// Consistent reports if the check found nothing wrong with the store
+func (r *TimerStoreReport) Consistent() bool {
+	return r.Error == "" && len(r.OrphanAlarms) == 0 && len(r.OrphanContexts) == 0 &&
+		len(r.RowsOutsideSpan) == 0 && !r.SpanMismatch
+}
+
+// TimerCheckReport aggregates consistency checks of timer stores of a function. Only stores
+// with inconsistencies or errors are listed
+type TimerCheckReport struct {
+	AlarmsChecked  int                 `json:"alarms_checked"`
+	FunctionName   string              `json:"function_name"`
+	NodeErrors     map[string]string   `json:"node_errors,omitempty"`
+	OrphanAlarms   int                 `json:"orphan_alarms"`
+	OrphanContexts int                 `json:"orphan_contexts"`
+	OrphansRemoved int                 `json:"orphans_removed"`
+	Repair         bool                `json:"repair"`
+	SpanIssues     int                 `json:"span_issues"`
+	SpansRewritten int                 `json:"spans_rewritten"`
+	Stores         []*TimerStoreReport `json:"stores,omitempty"`
+	StoresChecked  int                 `json:"stores_checked"`
+	Timestamp      string              `json:"timestamp"`
+}
+
+// Add accounts a store's report into the function's report
+func (r *TimerCheckReport) Add(store *TimerStoreReport) {
+	r.StoresChecked++
+	r.AlarmsChecked += store.AlarmsChecked
+	r.OrphanAlarms += len(store.OrphanAlarms)
+	r.OrphanContexts += len(store.OrphanContexts)
+	r.OrphansRemoved += store.OrphansRemoved
+	if store.SpanMismatch || len(store.RowsOutsideSpan) > 0 {
+		r.SpanIssues++
+	}
+	if store.SpanRewritten {
+		r.SpansRewritten++
+	}
+
+	if !store.Consistent() {
+		r.Stores = append(r.Stores, store)
+	}
+}
+
+// Merge accounts report of the function from another node into this one
+func (r *TimerCheckReport) Merge(other *TimerCheckReport) {
+	r.StoresChecked += other.StoresChecked
+	r.AlarmsChecked += other.AlarmsChecked
+	r.OrphanAlarms += other.OrphanAlarms
+	r.OrphanContexts += other.OrphanContexts
+	r.OrphansRemoved += other.OrphansRemoved
+	r.SpanIssues += other.SpanIssues
+	r.SpansRewritten += other.SpansRewritten
+	r.Stores = append(r.Stores, other.Stores...)
+}
+
+// ReprocessRequest asks consumers owning listed vbuckets to replay them from Boundary, which
+// is one of everything, from_seqnos(StartSeqNo) or from_timestamp(BoundaryTimestamp, RFC3339).
+// Empty Vbuckets implies all vbuckets
+type ReprocessRequest struct {
+	Boundary          DcpStreamBoundary `json:"boundary"`
+	BoundaryTimestamp string            `json:"boundary_timestamp,omitempty"`
+	StartSeqNo        uint64            `json:"start_seq_no"`
+	Vbuckets          []uint16          `json:"vbuckets"`
+}
+
+// RollbackEntry captures a rollback requested by DCP producer for a vbucket
+type RollbackEntry struct {
+	HostPortAddr string `json:"node"`
+	NewSeqNo     uint64 `json:"new_seq_no"`
+	OldSeqNo     uint64 `json:"old_seq_no"`
+	Timestamp    string `json:"timestamp"`
+	Vbucket      uint16 `json:"vb"`
+	VbUUID       uint64 `json:"vb_uuid"`
+	WorkerName   string `json:"worker_name"`
+}
+
 type EventProcessingStats struct {
 	DcpEventsProcessedPSec   int    `json:"dcp_events_processed_psec"`
 	TimerEventsProcessedPSec int    `json:"timer_events_processed_psec"`
@@ -282,10 +586,17 @@ type PlannerNodeVbMapping struct {
 
 type HandlerConfig struct {
 	AggDCPFeedMemCap         int64
+	BackfillDrainTimers      bool
+	BackfillJob              bool
+	CarryOverTimers          bool
 	CheckpointInterval       int
 	IdleCheckpointInterval   int
 	CleanupTimers            bool
 	CPPWorkerThrCount        int
+	CrashLoopAction          string
+	CrashLoopThreshold       int
+	CrashLoopWindow          int
+	DeliverRollbackEvent     bool
 	ExecuteTimerRoutineCount int
 	ExecutionTimeout         int
 	FeedbackBatchSize        int
@@ -300,11 +611,17 @@ type HandlerConfig struct {
 	SourceBucket             string
 	StatsLogInterval         int
 	StreamBoundary           DcpStreamBoundary
+	StreamBoundarySeqNos     map[uint16]uint64
+	StreamBoundaryTimestamp  int64
+	TimerCheckInterval       int
+	TimerCheckRepair         bool
 	TimerContextSize         int64
 	TimerStorageRoutineCount int
 	TimerStorageChanSize     int
 	TimerQueueMemCap         uint64
 	TimerQueueSize           uint64
+	TimerResolution          int64
+	TimerScanVbLimit         int
 	UndeployRoutineCount     int
 	UsingTimer               bool
 	WorkerCount              int
@@ -357,6 +674,10 @@ func StreamBoundary(boundary string) DcpStreamBoundary {
 		return DcpFromNow
 	case "from_prior":
 		return DcpFromPrior
+	case "from_seqnos":
+		return DcpFromSeqNos
+	case "from_timestamp":
+		return DcpFromTimestamp
 	default:
 		return DcpStreamBoundary("")
 	}
@@ -756,6 +756,32 @@ var getFailoverLogOpCallback = func(args ...interface{}) error {
 	return err
 }
 
+var resolveTimestampBoundaryCallback = func(args ...interface{}) error {
+	logPrefix := "Consumer::resolveTimestampBoundaryCallback"
+
+	c := args[0].(*Consumer)
+	timestamp := args[1].(uint64)
+	flogs := args[2].(couchbase.FailoverLog)
+	vbSeqnos := args[3].([]uint64)
+	timestampSeqNos := args[4].(*map[uint16]uint64)
+
+	if atomic.LoadUint32(&c.isTerminateRunning) == 1 {
+		logging.Tracef("%s [%s:%s:%d] Exiting as worker is terminating",
+			logPrefix, c.workerName, c.tcpPort, c.Pid())
+		return nil
+	}
+
+	seqNos, err := c.resolveTimestampBoundary(timestamp, flogs, vbSeqnos)
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] Failed to resolve boundary timestamp: %d, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), timestamp, err)
+		return err
+	}
+
+	*timestampSeqNos = seqNos
+	return nil
+}
+
 // Fetches failover log from existing feed
 var getEFFailoverLogOpAllVbucketsCallback = func(args ...interface{}) error {
 	logPrefix := "Consumer::getEFFailoverLogOpAllVbucketsCallback"
@@ -51,6 +51,9 @@ func (c *Consumer) doLastSeqNoCheckpoint() {
 
 					vbKey := fmt.Sprintf("%s::vb::%d", c.app.AppName, vb)
 
+					c.updateReprocessStatus(vb)
+					c.updateBackfillJobStatus(vb)
+
 					if c.isVbIdle(vb, &checkpoints[vb]) {
 						continue
 					}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/couchbase/eventing/common"
@@ -95,8 +96,8 @@ func (c *Consumer) controlRoutine() error {
 			}
 
 			if val, ok := settings["timer_context_size"]; ok {
-				c.timerContextSize = int64(val.(float64))
-				c.sendTimerContextSize(c.timerContextSize, false)
+				atomic.StoreInt64(&c.timerContextSize, int64(val.(float64)))
+				c.sendTimerContextSize(atomic.LoadInt64(&c.timerContextSize), false)
 			}
 
 			if val, ok := settings["vb_ownership_giveup_routine_count"]; ok {
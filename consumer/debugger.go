@@ -9,6 +9,7 @@ import (
 	"runtime"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/couchbase/eventing/common"
@@ -302,7 +303,7 @@ func (c *Consumer) startDebugger(e *cb.DcpEvent, instance common.DebuggerInstanc
 		ip, c.eventingDir, c.eventingAdminPort, c.eventingSSLPort,
 		c.getKvNodes()[0], c.producer.CfgData(), c.lcbInstCapacity,
 		c.executionTimeout, int(c.checkpointInterval.Nanoseconds()/(1000*1000)),
-		false, c.timerContextSize)
+		false, atomic.LoadInt64(&c.timerContextSize))
 
 	c.sendInitV8Worker(payload, true, pBuilder)
 	c.sendDebuggerStart()
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"hash/crc32"
+	"math/rand"
 	"net"
 	"os/exec"
 	"sync"
@@ -16,6 +17,7 @@ import (
 	mcd "github.com/couchbase/eventing/dcp/transport"
 	cb "github.com/couchbase/eventing/dcp/transport/client"
 	"github.com/couchbase/eventing/suptree"
+	"github.com/couchbase/eventing/timers"
 	"github.com/couchbase/eventing/util"
 	"github.com/couchbase/gocb"
 	"github.com/google/flatbuffers/go"
@@ -59,6 +61,9 @@ const (
 	socketWriteTimerInterval = time.Duration(5000) * time.Millisecond
 
 	updateCPPStatsTickInterval = time.Duration(1000) * time.Millisecond
+
+	// Max number of DCP rollback entries retained for reporting
+	rollbackHistorySize = 256
 )
 
 const (
@@ -77,6 +82,12 @@ const (
 	xattrPrefix                    = "_eventing"
 )
 
+const (
+	reprocessCompleted   = "completed"
+	reprocessRequested   = "requested"
+	reprocessRestreaming = "restreaming"
+)
+
 type xattrMetadata struct {
 	FunctionInstanceID string `json:"fiid"`
 	SeqNo              string `json:"seqno"`
@@ -101,12 +112,26 @@ type dcpMetadata struct {
 	SeqNo   uint64 `json:"seq"`
 }
 
+type rollbackMetadata struct {
+	NewSeqNo uint64 `json:"new_seq"`
+	OldSeqNo uint64 `json:"old_seq"`
+	Vbucket  uint16 `json:"vb"`
+	VbUUID   uint64 `json:"vb_uuid"`
+}
+
 type vbSeqNo struct {
 	SeqNo   uint64 `json:"seq"`
 	SkipAck int    `json:"skip_ack"` // 0: false 1: true
 	Vbucket uint16 `json:"vb"`
 }
 
+// Report eventing-consumer sends on a sampled event once its handler is done
+type traceReport struct {
+	TraceID   string `json:"trace_id"`
+	DequeueTs int64  `json:"dequeue_ts"`
+	DoneTs    int64  `json:"done_ts"`
+}
+
 // Consumer is responsible interacting with c++ v8 worker over local tcp port
 type Consumer struct {
 	app         *common.AppConfig
@@ -132,6 +157,7 @@ type Consumer struct {
 	workerQueueMemCap int64
 
 	cppThrPartitionMap    map[int][]uint16
+	cppPartitionThrMap    map[uint16]int
 	cppWorkerThrCount     int // No. of worker threads per CPP worker process
 	crcTable              *crc32.Table
 	debugConn             net.Conn // Interface to support communication between Go and C++ worker spawned for debugging
@@ -145,6 +171,7 @@ type Consumer struct {
 	aggDCPFeed                    chan *cb.DcpEvent
 	aggDCPFeedMem                 int64
 	aggDCPFeedMemCap              int64
+	memoryThrottled               int32 // Set by node memory governor, slows down DCP feed
 	cbBucket                      *couchbase.Bucket
 	cbBucketRWMutex               *sync.RWMutex
 	checkpointInterval            time.Duration
@@ -155,6 +182,7 @@ type Consumer struct {
 	dcpFeedsClosed                bool
 	dcpFeedVbMap                  map[*couchbase.DcpFeed][]uint16 // Access controlled by default lock
 	debuggerPort                  string
+	deliverRollbackEvent          bool
 	ejectNodesUUIDs               []string
 	eventingAdminPort             string
 	eventingDir                   string
@@ -175,6 +203,8 @@ type Consumer struct {
 	ipcType                       string // ipc mechanism used to communicate with cpp workers - af_inet/af_unix
 	isBootstrapping               bool
 	isRebalanceOngoing            bool
+	inflightEvents                map[int][]*common.CrashSuspect // Events sent per cpp worker thread, access controlled by inflightEventsRWMutex
+	inflightEventsRWMutex         *sync.RWMutex
 	isTerminateRunning            uint32                        // To signify if Consumer::Stop is running
 	kvHostDcpFeedMap              map[string]*couchbase.DcpFeed // Access controlled by hostDcpFeedRWMutex
 	hostDcpFeedRWMutex            *sync.RWMutex
@@ -184,13 +214,23 @@ type Consumer struct {
 	logLevel                      string
 	numVbuckets                   int
 	nsServerPort                  string
+	quarantinedEvents             map[uint16]map[uint64]struct{} // Seq nos of events skipped after crashing eventing-consumer repeatedly
+	reprocessSeqNos               map[uint16]uint64              // Access controlled by reprocessRWMutex
+	reprocessRWMutex              *sync.RWMutex
+	backfillJobSeqNos             []uint64            // Seq nos recorded when function was deployed as backfill job, events past them aren't processed
+	backfillJobPastVbs            map[uint16]struct{} // Vbuckets that streamed past seq nos of backfill job, access controlled by backfillJobRWMutex
+	backfillJobRWMutex            *sync.RWMutex
 	reqStreamCh                   chan *streamRequestInfo
 	resetBootstrapDone            bool
+	rollbackHistory               []*common.RollbackEntry // Access controlled by rollbackHistoryRWMutex
+	rollbackHistoryRWMutex        *sync.RWMutex
 	statsTickDuration             time.Duration
 	streamReqRWMutex              *sync.RWMutex
 	stoppingConsumer              bool
 	superSup                      common.EventingSuperSup
-	timerContextSize              int64
+	timerContextSize              int64 // Enforced by createTimer, and on contexts after compression when timers are stored
+	timerResolution               int64
+	timerScanVbLimit              int
 	timerStorageChanSize          int
 	timerQueuesAreDrained         bool
 	timerQueueSize                uint64
@@ -200,6 +240,8 @@ type Consumer struct {
 	timerStorageQueues            []*util.BoundedQueue // Access controlled by timerStorageMetaChsRWMutex
 	usingTimer                    bool
 	vbDcpEventsRemaining          map[int]int64 // Access controlled by statsRWMutex
+	vbLags                        map[uint16]*vbLag
+	vbLagRWMutex                  *sync.RWMutex
 	vbDcpFeedMap                  map[uint16]*couchbase.DcpFeed
 	vbEventingNodeAssignMap       map[uint16]string // Access controlled by vbEventingNodeAssignMapRWMutex
 	vbEventingNodeAssignRWMutex   *sync.RWMutex
@@ -225,9 +267,15 @@ type Consumer struct {
 	failureStats      map[string]interface{} // Access controlled by statsRWMutex
 	latencyStats      map[string]uint64      // Access controlled by statsRWMutex
 	curlLatencyStats  map[string]uint64      // Access controlled by statsRWMutex
+	dcpLatencyStats   map[string]uint64      // Access controlled by statsRWMutex
 	lcbExceptionStats map[string]uint64      // Access controlled by statsRWMutex
+	timerLateness     *util.Histogram        // Access controlled by statsRWMutex
 	statsRWMutex      *sync.RWMutex
 
+	// Latency stats as of when event stats were last cleared, keyed by kind of latency. Access
+	// controlled by statsRWMutex
+	latencyBaselines map[string]map[string]uint64
+
 	// Time when last response from CPP worker was received on main loop
 	workerRespMainLoopTs        atomic.Value
 	workerRespMainLoopThreshold int
@@ -250,6 +298,11 @@ type Consumer struct {
 
 	dcpStreamBoundary common.DcpStreamBoundary
 
+	// Start seq nos per vbucket for from_seqnos boundary and cutoff(in ns) for
+	// from_timestamp boundary, which is resolved into seq nos by cas of mutations
+	dcpStreamBoundarySeqNos    map[uint16]uint64
+	dcpStreamBoundaryTimestamp uint64
+
 	// Map that needed to short circuits failover log to dcp stream request routine
 	vbFlogChan chan *vbFlogEntry
 
@@ -268,6 +321,17 @@ type Consumer struct {
 	socketWriteLoopStopCh    chan struct{}
 	socketWriteLoopStopAckCh chan struct{}
 
+	// Traces of sampled events in sendMsgBuffer, yet to be written to socket. Access controlled
+	// by sendMsgBufferRWMutex
+	bufferedTraces []*common.EventTrace
+
+	// Traces of sampled events eventing-consumer is yet to report on, keyed by trace id
+	pendingTraces        map[string]*common.EventTrace
+	pendingTracesRWMutex *sync.RWMutex
+
+	// Samples events and generates their trace ids, only accessed by processEvents routine
+	traceRand *rand.Rand
+
 	// host:port handle for current eventing node
 	hostPortAddr string
 
@@ -326,6 +390,7 @@ type Consumer struct {
 
 	dcpCloseStreamCounter    uint64
 	dcpCloseStreamErrCounter uint64
+	dcpRollbackCounter       uint64
 	dcpStreamReqCounter      uint64
 	dcpStreamReqErrCounter   uint64
 
@@ -342,6 +407,8 @@ type Consumer struct {
 	timerMessagesProcessedPSec   int
 	suppressedDCPDeletionCounter uint64
 	suppressedDCPMutationCounter uint64
+	skippedQuarantinedCounter    uint64
+	skippedBackfillJobCounter    uint64
 
 	// metastore related timer stats
 	metastoreDeleteCounter      uint64
@@ -352,6 +419,20 @@ type Consumer struct {
 	metastoreScanErrCounter     uint64
 	metastoreSetCounter         uint64
 	metastoreSetErrCounter      uint64
+	timerContextTooLargeCounter uint64
+
+	// recurring timer stats
+	recurringTimerCompletedCounter       uint64
+	recurringTimerFiredCounter           uint64
+	recurringTimerInvalidScheduleCounter uint64
+	recurringTimerRearmErrCounter        uint64
+	recurringTimerScheduledCounter       uint64
+
+	// timer scheduling stats
+	timerMaxLateness        int64 // Seconds, for timers fired in timerLatenessMinute, access controlled by statsRWMutex
+	timerMaxLatenessPrev    int64 // Seconds, for timers fired in the minute before, access controlled by statsRWMutex
+	timerLatenessMinute     int64 // Minutes since epoch, access controlled by statsRWMutex
+	timerScanVbLimitCounter uint64
 
 	// capture dcp operation stats, granularity of these stats depend on statsTickInterval
 	dcpOpsProcessed     uint64
@@ -410,6 +491,7 @@ type vbucketKVBlob struct {
 	LastCheckpointTime        string           `json:"last_checkpoint_time"`
 	LastDocTimerFeedbackSeqNo uint64           `json:"last_doc_timer_feedback_seqno"`
 	LastSeqNoProcessed        uint64           `json:"last_processed_seq_no"`
+	QuarantinedSeqNos         []uint64         `json:"quarantined_seq_nos,omitempty"`
 	NodeUUID                  string           `json:"node_uuid"`
 	NodeRequestedVbStream     string           `json:"node_requested_vb_stream"`
 	NodeUUIDRequestedVbStream string           `json:"node_uuid_requested_vb_stream"`
@@ -452,6 +534,7 @@ type msgToTransmit struct {
 	prioritize     bool
 	headerBuilder  *flatbuffers.Builder
 	payloadBuilder *flatbuffers.Builder
+	trace          *common.EventTrace
 }
 
 type cppQueueSize struct {
@@ -468,12 +551,13 @@ type streamRequestInfo struct {
 
 // TimerInfo is the struct sent by C++ worker to create the timer
 type TimerInfo struct {
-	Epoch     int64  `json:"epoch"`
-	Vb        uint64 `json:"vb"`
-	SeqNum    uint64 `json:"seq_num"`
-	Callback  string `json:"callback"`
-	Reference string `json:"reference"`
-	Context   string `json:"context"`
+	Epoch      int64              `json:"epoch"`
+	Vb         uint64             `json:"vb"`
+	SeqNum     uint64             `json:"seq_num"`
+	Callback   string             `json:"callback"`
+	Reference  string             `json:"reference"`
+	Context    string             `json:"context"`
+	Recurrence *timers.Recurrence `json:"recurrence,omitempty"`
 }
 
 // Size returns aggregate size of timer entry sent from CPP to Go
@@ -485,12 +569,55 @@ func (info *TimerInfo) Size() uint64 {
 // This is struct that will be stored in
 // the meta store as the timer's context
 type timerContext struct {
-	Callback  string `json:"callback"`
-	Vb        uint64 `json:"vb"`
-	Context   string `json:"context"` // This is the context provided by the user
-	reference string
+	Callback   string             `json:"callback"`
+	Vb         uint64             `json:"vb"`
+	Context    string             `json:"context"`             // This is the context provided by the user
+	Reference  string             `json:"reference,omitempty"` // Reference provided by the user, to look up timer
+	Recurrence *timers.Recurrence `json:"recurrence,omitempty"`
+	reference  string
 }
 
 func (ctx *timerContext) Size() uint64 {
 	return uint64(unsafe.Sizeof(*ctx)) + uint64(len(ctx.Callback)) + uint64(len(ctx.Context))
 }
+
+// Next due timer of a vbucket, merged with those of other vbuckets during a scan
+type dueTimer struct {
+	entry    *timers.TimerEntry
+	fired    int // Timers of the vbucket fired in this scan
+	iterator *timers.TimerIter
+	store    *timers.TimerStore
+	vb       uint16
+}
+
+// Min heap of due timers ordered by due time. Among timers due together, vbuckets that fired
+// fewer timers in the scan come first
+type dueTimerHeap []*dueTimer
+
+func (h dueTimerHeap) Len() int { return len(h) }
+
+func (h dueTimerHeap) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	switch {
+	case a.entry.AlarmDue != b.entry.AlarmDue:
+		return a.entry.AlarmDue < b.entry.AlarmDue
+	case a.fired != b.fired:
+		return a.fired < b.fired
+	case a.entry.RequestedDue != b.entry.RequestedDue:
+		return a.entry.RequestedDue < b.entry.RequestedDue
+	}
+	return a.vb < b.vb
+}
+
+func (h dueTimerHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *dueTimerHeap) Push(x interface{}) { *h = append(*h, x.(*dueTimer)) }
+
+func (h *dueTimerHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return t
+}
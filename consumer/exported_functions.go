@@ -13,6 +13,7 @@ import (
 	"unsafe"
 
 	"github.com/couchbase/eventing/common"
+	couchbase "github.com/couchbase/eventing/dcp"
 	mcd "github.com/couchbase/eventing/dcp/transport"
 	"github.com/couchbase/eventing/logging"
 	"github.com/couchbase/eventing/timers"
@@ -30,6 +31,27 @@ func (c *Consumer) ClearEventStats() {
 
 	c.adhocTimerResponsesRecieved = 0
 	c.aggMessagesSentCounter = 0
+
+	// eventing-consumer keeps counting latencies, so counts as of now are taken away from those
+	// reported later on
+	c.statsRWMutex.Lock()
+	defer c.statsRWMutex.Unlock()
+
+	c.latencyBaselines = map[string]map[string]uint64{
+		"curl":      util.HistogramFromBuckets(c.curlLatencyStats).Buckets(),
+		"dcp":       util.HistogramFromBuckets(c.dcpLatencyStats).Buckets(),
+		"execution": util.HistogramFromBuckets(c.latencyStats).Buckets(),
+	}
+}
+
+// Counts of latency buckets since event stats were last cleared. Baseline counting more than
+// eventing-consumer does in some bucket means it restarted since, and so counts afresh
+func (c *Consumer) latencySinceClear(kind string, stats map[string]uint64) map[string]uint64 {
+	h := util.HistogramFromBuckets(stats)
+	if baseline, ok := c.latencyBaselines[kind]; ok {
+		h.Sub(util.HistogramFromBuckets(baseline))
+	}
+	return h.Buckets()
 }
 
 // ConsumerName returns consumer name e.q <event_handler_name>_worker_1
@@ -37,6 +59,27 @@ func (c *Consumer) ConsumerName() string {
 	return c.workerName
 }
 
+// CrashSuspects returns events each cpp worker thread of eventing-consumer is executing,
+// as far as it has reported processed seq nos
+func (c *Consumer) CrashSuspects() []*common.CrashSuspect {
+	suspects := make([]*common.CrashSuspect, 0)
+
+	c.inflightEventsRWMutex.Lock()
+	defer c.inflightEventsRWMutex.Unlock()
+
+	for thr, events := range c.inflightEvents {
+		events = c.dropProcessedEvents(events)
+		c.inflightEvents[thr] = events
+
+		if len(events) > 0 {
+			suspect := *events[0]
+			suspects = append(suspects, &suspect)
+		}
+	}
+
+	return suspects
+}
+
 // EventingNodeUUIDs return list of known eventing node uuids
 func (c *Consumer) EventingNodeUUIDs() []string {
 	return c.eventingNodeUUIDs
@@ -91,6 +134,14 @@ func (c *Consumer) GetEventProcessingStats() map[string]uint64 {
 		stats["dcp_mutation_suppressed_counter"] = c.suppressedDCPMutationCounter
 	}
 
+	if c.skippedQuarantinedCounter > 0 {
+		stats["dcp_quarantined_skipped_counter"] = c.skippedQuarantinedCounter
+	}
+
+	if c.skippedBackfillJobCounter > 0 {
+		stats["dcp_backfill_job_skipped_counter"] = c.skippedBackfillJobCounter
+	}
+
 	if c.dcpCloseStreamCounter > 0 {
 		stats["dcp_stream_close_counter"] = c.dcpCloseStreamCounter
 	}
@@ -99,6 +150,10 @@ func (c *Consumer) GetEventProcessingStats() map[string]uint64 {
 		stats["dcp_stream_close_err_counter"] = c.dcpCloseStreamErrCounter
 	}
 
+	if c.dcpRollbackCounter > 0 {
+		stats["dcp_rollback_counter"] = c.dcpRollbackCounter
+	}
+
 	if c.dcpStreamReqCounter > 0 {
 		stats["dcp_stream_req_counter"] = c.dcpStreamReqCounter
 	}
@@ -261,6 +316,12 @@ func (c *Consumer) GetMetaStoreStats() map[string]uint64 {
 	stats["metastore_scan_err"] = atomic.LoadUint64(&c.metastoreScanErrCounter)
 	stats["metastore_set"] = atomic.LoadUint64(&c.metastoreSetCounter)
 	stats["metastore_set_err"] = atomic.LoadUint64(&c.metastoreSetErrCounter)
+	stats["recurring_timer_completed"] = atomic.LoadUint64(&c.recurringTimerCompletedCounter)
+	stats["recurring_timer_fired"] = atomic.LoadUint64(&c.recurringTimerFiredCounter)
+	stats["recurring_timer_invalid_schedule"] = atomic.LoadUint64(&c.recurringTimerInvalidScheduleCounter)
+	stats["recurring_timer_rearm_err"] = atomic.LoadUint64(&c.recurringTimerRearmErrCounter)
+	stats["recurring_timer_scheduled"] = atomic.LoadUint64(&c.recurringTimerScheduledCounter)
+	stats["timer_scan_vb_limit_reached"] = atomic.LoadUint64(&c.timerScanVbLimitCounter)
 
 	for _, vb := range c.getCurrentlyOwnedVbs() {
 		store, found := timers.Fetch(c.producer.GetMetadataPrefix(), int(vb))
@@ -379,26 +440,45 @@ func (c *Consumer) GetLatencyStats() map[string]uint64 {
 	c.statsRWMutex.RLock()
 	defer c.statsRWMutex.RUnlock()
 
-	latencyStats := make(map[string]uint64)
+	return c.latencySinceClear("execution", c.latencyStats)
+}
 
-	for k, v := range c.latencyStats {
-		latencyStats[k] = v
-	}
+// GetTimerLatenessStats returns histogram of seconds by which timers fired past their due time,
+// keyed by lower bound of its buckets
+func (c *Consumer) GetTimerLatenessStats() map[string]uint64 {
+	c.statsRWMutex.RLock()
+	defer c.statsRWMutex.RUnlock()
+
+	return c.timerLateness.Buckets()
+}
+
+// GetTimerMaxLateness returns highest seconds by which timers fired in the last one to two minutes
+// were past their due time
+func (c *Consumer) GetTimerMaxLateness() int64 {
+	c.statsRWMutex.Lock()
+	defer c.statsRWMutex.Unlock()
 
-	return latencyStats
+	c.rotateTimerMaxLateness(time.Now())
+	if c.timerMaxLatenessPrev > c.timerMaxLateness {
+		return c.timerMaxLatenessPrev
+	}
+	return c.timerMaxLateness
 }
 
 func (c *Consumer) GetCurlLatencyStats() map[string]uint64 {
 	c.statsRWMutex.RLock()
 	defer c.statsRWMutex.RUnlock()
 
-	latencyStats := make(map[string]uint64)
+	return c.latencySinceClear("curl", c.curlLatencyStats)
+}
 
-	for k, v := range c.curlLatencyStats {
-		latencyStats[k] = v
-	}
+// GetDcpLatencyStats returns histogram of latency from an event being received from DCP till
+// its handler finished, from cpp world
+func (c *Consumer) GetDcpLatencyStats() map[string]uint64 {
+	c.statsRWMutex.RLock()
+	defer c.statsRWMutex.RUnlock()
 
-	return latencyStats
+	return c.latencySinceClear("dcp", c.dcpLatencyStats)
 }
 
 // GetExecutionStats returns OnUpdate/OnDelete success/failure stats for event handlers from cpp world
@@ -424,6 +504,7 @@ func (c *Consumer) GetFailureStats() map[string]interface{} {
 	for k, v := range c.failureStats {
 		failureStats[k] = v
 	}
+	failureStats["timer_context_too_large_count"] = atomic.LoadUint64(&c.timerContextTooLargeCounter)
 
 	return failureStats
 }
@@ -711,6 +792,13 @@ func (c *Consumer) VbSeqnoStats() map[int]map[string]interface{} {
 			seqnoStats[vb]["seq_no_after_close_stream"] = c.vbProcessingStats.getVbStat(uint16(vb), "seq_no_after_close_stream")
 			seqnoStats[vb]["timestamp"] = c.vbProcessingStats.getVbStat(uint16(vb), "timestamp")
 			seqnoStats[vb]["worker_name"] = c.vbProcessingStats.getVbStat(uint16(vb), "worker_name")
+
+			if status := c.vbProcessingStats.getVbStat(uint16(vb), "reprocess_status").(string); status != "" {
+				seqnoStats[vb]["reprocess_end_seq_no"] = c.vbProcessingStats.getVbStat(uint16(vb), "reprocess_end_seq_no")
+				seqnoStats[vb]["reprocess_start_seq_no"] = c.vbProcessingStats.getVbStat(uint16(vb), "reprocess_start_seq_no")
+				seqnoStats[vb]["reprocess_status"] = status
+				seqnoStats[vb]["reprocess_timestamp"] = c.vbProcessingStats.getVbStat(uint16(vb), "reprocess_timestamp")
+			}
 		}
 	}
 
@@ -777,11 +865,61 @@ func (c *Consumer) UpdateWorkerQueueMemCap(quota int64) {
 	c.workerQueueMemCap = (quota / divisor) * 1024 * 1024
 	c.aggDCPFeedMemCap = (quota / divisor) * 1024 * 1024
 
+	if c.app.UsingTimer {
+		c.fireTimerQueue.SetMaxSize(c.timerQueueMemCap)
+		c.createTimerQueue.SetMaxSize(c.timerQueueMemCap)
+
+		c.timerStorageMetaChsRWMutex.RLock()
+		for _, queue := range c.timerStorageQueues {
+			if queue != nil {
+				queue.SetMaxSize(c.timerQueueMemCap / uint64(c.timerStorageRoutineCount))
+			}
+		}
+		c.timerStorageMetaChsRWMutex.RUnlock()
+	}
+
 	logging.Infof("%s [%s:%s:%d] Updated memory quota: %d MB previous worker quota: %d MB dcp feed quota: %d MB",
 		logPrefix, c.workerName, c.tcpPort, c.Pid(), c.workerQueueMemCap/(1024*1024),
 		prevWorkerMemCap/(1024*1024), prevDCPFeedMemCap/(1024*1024))
 }
 
+// GetMemoryUsage returns memory held by DCP feed, timer and eventing-consumer queues
+func (c *Consumer) GetMemoryUsage() *common.MemoryUsage {
+	usage := &common.MemoryUsage{
+		DcpFeed: atomic.LoadInt64(&c.aggDCPFeedMem),
+	}
+
+	if c.cppQueueSizes != nil {
+		usage.WorkerQueue = c.cppQueueSizes.AggQueueMemory
+	}
+
+	timerQueues := c.fireTimerQueue.Size() + c.createTimerQueue.Size()
+	c.timerStorageMetaChsRWMutex.RLock()
+	for _, queue := range c.timerStorageQueues {
+		if queue != nil {
+			timerQueues += queue.Size()
+		}
+	}
+	c.timerStorageMetaChsRWMutex.RUnlock()
+	usage.TimerQueues = int64(timerQueues)
+
+	return usage
+}
+
+// SetMemoryThrottle slows down DCP feed while node memory usage is over quota
+func (c *Consumer) SetMemoryThrottle(throttle bool) {
+	logPrefix := "Consumer::SetMemoryThrottle"
+
+	var val int32
+	if throttle {
+		val = 1
+	}
+
+	if prev := atomic.SwapInt32(&c.memoryThrottled, val); prev != val {
+		logging.Infof("%s [%s:%s:%d] Memory throttle: %t", logPrefix, c.workerName, c.tcpPort, c.Pid(), throttle)
+	}
+}
+
 // ResetBootstrapDone to unset bootstrap flag
 func (c *Consumer) ResetBootstrapDone() {
 	logPrefix := "Consumer::ResetBootstrapDone"
@@ -791,6 +929,238 @@ func (c *Consumer) ResetBootstrapDone() {
 	logging.Infof("%s [%s:%s:%d] Updated ResetBootstrapDone flag to: %t", logPrefix, c.workerName, c.tcpPort, c.Pid(), c.resetBootstrapDone)
 }
 
+// RollbackHistory returns recent rollbacks requested by DCP producer for vbuckets owned by consumer
+func (c *Consumer) RollbackHistory() []*common.RollbackEntry {
+	c.rollbackHistoryRWMutex.RLock()
+	defer c.rollbackHistoryRWMutex.RUnlock()
+
+	history := make([]*common.RollbackEntry, len(c.rollbackHistory))
+	copy(history, c.rollbackHistory)
+	return history
+}
+
+// Reprocess closes dcp streams for requested vbuckets owned by the consumer. Once C++ worker
+// acknowledges the stream end, checkpoint is rewritten to requested seq no and stream is
+// requested again from there, while rest of the vbuckets continue to be processed
+func (c *Consumer) Reprocess(req *common.ReprocessRequest) {
+	logPrefix := "Consumer::Reprocess"
+
+	vbs := make([]uint16, 0)
+	reqVbs := req.Vbuckets
+	if len(reqVbs) == 0 {
+		reqVbs = c.getCurrentlyOwnedVbs()
+	}
+
+	for _, vb := range reqVbs {
+		if int(vb) < c.numVbuckets && c.checkIfVbAlreadyOwnedByCurrConsumer(vb) {
+			vbs = append(vbs, vb)
+		}
+	}
+
+	if len(vbs) == 0 {
+		return
+	}
+
+	startSeqNos, err := c.reprocessStartSeqNos(req, vbs)
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] Failed to resolve seq nos to reprocess vbs: %s from, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), util.Condense(vbs), err)
+		return
+	}
+
+	for _, vb := range vbs {
+		c.RLock()
+		dcpFeed, ok := c.vbDcpFeedMap[vb]
+		c.RUnlock()
+		if !ok {
+			logging.Errorf("%s [%s:%s:%d] vb: %d No dcp feed found, skipping reprocess",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb)
+			continue
+		}
+
+		startSeqNo, ok := startSeqNos[vb]
+		if !ok {
+			logging.Errorf("%s [%s:%s:%d] vb: %d No seq no resolved, skipping reprocess",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb)
+			continue
+		}
+
+		c.reprocessRWMutex.Lock()
+		c.reprocessSeqNos[vb] = startSeqNo
+		c.reprocessRWMutex.Unlock()
+
+		lastReadSeqNo := c.vbProcessingStats.getVbStat(vb, "last_read_seq_no").(uint64)
+		c.vbProcessingStats.updateVbStat(vb, "reprocess_end_seq_no", lastReadSeqNo)
+		c.vbProcessingStats.updateVbStat(vb, "reprocess_start_seq_no", startSeqNo)
+		c.vbProcessingStats.updateVbStat(vb, "reprocess_status", reprocessRequested)
+		c.vbProcessingStats.updateVbStat(vb, "reprocess_timestamp", time.Now().Format(time.RFC3339))
+
+		logging.Infof("%s [%s:%s:%d] vb: %d Issuing dcp close stream to reprocess from seq no: %d till: %d",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, startSeqNo, lastReadSeqNo)
+
+		c.dcpCloseStreamCounter++
+		err = dcpFeed.DcpCloseStream(vb, vb)
+		if err != nil {
+			c.dcpCloseStreamErrCounter++
+			logging.Errorf("%s [%s:%s:%d] vb: %d Failed to close dcp stream for reprocess, err: %v",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
+
+			c.reprocessRWMutex.Lock()
+			delete(c.reprocessSeqNos, vb)
+			c.reprocessRWMutex.Unlock()
+
+			c.vbProcessingStats.updateVbStat(vb, "reprocess_status", "")
+		}
+	}
+}
+
+// Seq nos to replay vbs from. Timestamp boundary is resolved the same way as from_timestamp
+// feed boundary, for the requested vbuckets alone
+func (c *Consumer) reprocessStartSeqNos(req *common.ReprocessRequest, vbs []uint16) (map[uint16]uint64, error) {
+	startSeqNos := make(map[uint16]uint64, len(vbs))
+
+	if req.Boundary != common.DcpFromTimestamp {
+		for _, vb := range vbs {
+			startSeqNos[vb] = req.StartSeqNo
+		}
+		return startSeqNos, nil
+	}
+
+	ts, err := time.Parse(time.RFC3339, req.BoundaryTimestamp)
+	if err != nil {
+		return nil, err
+	}
+
+	var allFlogs couchbase.FailoverLog
+	err = util.Retry(util.NewFixedBackoff(clusterOpRetryInterval), c.retryCount, getFailoverLogOpCallback, c, &allFlogs)
+	if err != nil {
+		return nil, err
+	}
+
+	flogs := make(couchbase.FailoverLog)
+	for _, vb := range vbs {
+		if flog, ok := allFlogs[vb]; ok {
+			flogs[vb] = flog
+		}
+	}
+
+	vbSeqnos, err := util.BucketSeqnos(c.producer.NsServerHostPort(), "default", c.bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	err = util.Retry(util.NewFixedBackoff(clusterOpRetryInterval), c.retryCount, resolveTimestampBoundaryCallback,
+		c, uint64(ts.UnixNano()), flogs, vbSeqnos, &startSeqNos)
+	if err != nil {
+		return nil, err
+	}
+
+	return startSeqNos, nil
+}
+
+// ListTimers returns pending timers of vbuckets owned by the consumer, matching filter
+func (c *Consumer) ListTimers(filter *common.TimerFilter) ([]*common.PendingTimer, error) {
+	logPrefix := "Consumer::ListTimers"
+
+	pending := make([]*common.PendingTimer, 0)
+
+	for _, vb := range c.getCurrentlyOwnedVbs() {
+		if filter.Vbucket >= 0 && int(vb) != filter.Vbucket {
+			continue
+		}
+
+		store, found := timers.Fetch(c.producer.GetMetadataPrefix(), int(vb))
+		if !found {
+			atomic.AddUint64(&c.metastoreNotFoundErrCounter, 1)
+			continue
+		}
+
+		vbTimers, err := c.listVbTimers(store, vb, filter)
+		if err != nil {
+			logging.Errorf("%s [%s:%s:%d] vb: %d Failed to list timers, err: %v",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
+			atomic.AddUint64(&c.metastoreScanErrCounter, 1)
+			return nil, err
+		}
+
+		pending = append(pending, vbTimers...)
+	}
+
+	return pending, nil
+}
+
+// CancelTimer cancels timers created with callback and reference in vbuckets owned by the consumer,
+// reporting if any such timer was pending
+func (c *Consumer) CancelTimer(callback, reference string) (bool, error) {
+	logPrefix := "Consumer::CancelTimer"
+
+	ref := callback + ":" + reference
+	cancelled := false
+
+	for _, vb := range c.getCurrentlyOwnedVbs() {
+		store, found := timers.Fetch(c.producer.GetMetadataPrefix(), int(vb))
+		if !found {
+			atomic.AddUint64(&c.metastoreNotFoundErrCounter, 1)
+			continue
+		}
+
+		entry, err := store.Lookup(ref)
+		if err != nil {
+			logging.Errorf("%s [%s:%s:%d] vb: %d Failed to look up timer, err: %v",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
+			return cancelled, err
+		}
+
+		if entry == nil {
+			continue
+		}
+
+		err = store.Cancel(ref)
+		if err != nil {
+			logging.Errorf("%s [%s:%s:%d] vb: %d Failed to cancel timer, err: %v",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
+			return cancelled, err
+		}
+
+		logging.Infof("%s [%s:%s:%d] vb: %d Cancelled timer due at: %d callback: %s reference: %ru",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, entry.AlarmDue, callback, reference)
+		cancelled = true
+	}
+
+	return cancelled, nil
+}
+
+// CheckTimers checks consistency of timer stores of vbuckets owned by the consumer, repairing
+// them if asked to
+func (c *Consumer) CheckTimers(repair bool) []*common.TimerStoreReport {
+	logPrefix := "Consumer::CheckTimers"
+
+	if !c.usingTimer {
+		return make([]*common.TimerStoreReport, 0)
+	}
+
+	stores := make([]*timers.TimerStore, 0)
+	for _, vb := range c.getCurrentlyOwnedVbs() {
+		store, found := timers.Fetch(c.producer.GetMetadataPrefix(), int(vb))
+		if !found {
+			atomic.AddUint64(&c.metastoreNotFoundErrCounter, 1)
+			continue
+		}
+		stores = append(stores, store)
+	}
+
+	reports := timers.CheckStores(stores, repair)
+	for _, report := range reports {
+		if !report.Consistent() {
+			logging.Infof("%s [%s:%s:%d] vb: %d Timer store inconsistent, orphan alarms: %d orphan contexts: %d rows outside span: %d span mismatch: %t orphans removed: %d err: %v",
+				logPrefix, c.workerName, c.tcpPort, c.Pid(), report.Vbucket, len(report.OrphanAlarms), len(report.OrphanContexts),
+				len(report.RowsOutsideSpan), report.SpanMismatch, report.OrphansRemoved, report.Error)
+		}
+	}
+
+	return reports
+}
+
 func (c *Consumer) RemoveSupervisorToken() error {
 	logPrefix := "Consumer::RemoveSupervisorToken"
 	logging.Infof("%s [%s:%s:%d] Removing supervisor token",
@@ -11,6 +11,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/couchbase/eventing/common"
 	mcd "github.com/couchbase/eventing/dcp/transport"
 	"github.com/couchbase/eventing/dcp/transport/client"
 	"github.com/couchbase/eventing/logging"
@@ -265,6 +266,28 @@ func (c *Consumer) sendGetLatencyStats(sendToDebugger bool) {
 	c.sendMessage(m)
 }
 
+func (c *Consumer) sendGetDcpLatencyStats(sendToDebugger bool) {
+	header, hBuilder := c.makeHeader(v8WorkerEvent, v8WorkerDcpLatencyStats, 0, "")
+
+	c.msgProcessedRWMutex.Lock()
+	if _, ok := c.v8WorkerMessagesProcessed["dcp_latency_stats"]; !ok {
+		c.v8WorkerMessagesProcessed["dcp_latency_stats"] = 0
+	}
+	c.v8WorkerMessagesProcessed["dcp_latency_stats"]++
+	c.msgProcessedRWMutex.Unlock()
+
+	m := &msgToTransmit{
+		msg: &message{
+			Header: header,
+		},
+		sendToDebugger: sendToDebugger,
+		prioritize:     true,
+		headerBuilder:  hBuilder,
+	}
+
+	c.sendMessage(m)
+}
+
 func (c *Consumer) refershCurlLatencyStats(sendToDebugger bool) {
 	header, hBuilder := c.makeHeader(v8WorkerEvent, v8WorkerCurlLatencyStats, 0, "")
 
@@ -391,14 +414,22 @@ func (c *Consumer) sendDcpEvent(e *memcached.DcpEvent, sendToDebugger bool) {
 
 	partition := int16(util.VbucketByKey(e.Key, cppWorkerPartitionCount))
 
+	var trace *common.EventTrace
+	var traceID string
+	if !sendToDebugger {
+		if trace = c.startTrace(e); trace != nil {
+			traceID = trace.TraceID
+		}
+	}
+
 	var dcpHeader []byte
 	var hBuilder *flatbuffers.Builder
 	if e.Opcode == mcd.DCP_MUTATION {
-		dcpHeader, hBuilder = c.makeDcpMutationHeader(partition, string(metadata))
+		dcpHeader, hBuilder = c.makeDcpMutationHeader(partition, string(metadata), e.Ctime, traceID)
 	}
 
 	if e.Opcode == mcd.DCP_DELETION {
-		dcpHeader, hBuilder = c.makeDcpDeletionHeader(partition, string(metadata))
+		dcpHeader, hBuilder = c.makeDcpDeletionHeader(partition, string(metadata), e.Ctime, traceID)
 	}
 
 	dcpPayload, pBuilder := c.makeDcpPayload(e.Key, e.Value)
@@ -412,9 +443,54 @@ func (c *Consumer) sendDcpEvent(e *memcached.DcpEvent, sendToDebugger bool) {
 		prioritize:     false,
 		headerBuilder:  hBuilder,
 		payloadBuilder: pBuilder,
+		trace:          trace,
+	}
+
+	c.sendMessage(msg)
+}
+
+func (c *Consumer) sendRollbackEvent(vb uint16, oldSeqNo, newSeqNo, vbuuid uint64) {
+	logPrefix := "Consumer::sendRollbackEvent"
+
+	data := rollbackMetadata{
+		NewSeqNo: newSeqNo,
+		OldSeqNo: oldSeqNo,
+		Vbucket:  vb,
+		VbUUID:   vbuuid,
+	}
+
+	metadata, err := json.Marshal(&data)
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] vb: %d failed to marshal rollback metadata",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), vb)
+		return
+	}
+
+	c.msgProcessedRWMutex.Lock()
+	if _, ok := c.v8WorkerMessagesProcessed["rollback_event"]; !ok {
+		c.v8WorkerMessagesProcessed["rollback_event"] = 0
+	}
+	c.v8WorkerMessagesProcessed["rollback_event"]++
+	c.msgProcessedRWMutex.Unlock()
+
+	// Payload is sent empty as C++ side parses it for all DCP events
+	rollbackHeader, hBuilder := c.makeDcpRollbackHeader(int16(vb), string(metadata))
+	rollbackPayload, pBuilder := c.makeDcpPayload(nil, nil)
+
+	msg := &msgToTransmit{
+		msg: &message{
+			Header:  rollbackHeader,
+			Payload: rollbackPayload,
+		},
+		sendToDebugger: false,
+		prioritize:     false,
+		headerBuilder:  hBuilder,
+		payloadBuilder: pBuilder,
 	}
 
 	c.sendMessage(msg)
+	logging.Infof("%s [%s:%s:%d] vb: %d oldSeqNo: %d newSeqNo: %d sending rollback event to C++",
+		logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, oldSeqNo, newSeqNo)
 }
 
 func (c *Consumer) sendVbFilterData(vb uint16, seqNo uint64, skipAck bool) {
@@ -523,8 +599,10 @@ func (c *Consumer) sendMessageLoop() {
 						return
 					}
 
+					writeTs := time.Now().UnixNano()
 					_, err := c.sendMsgBuffer.WriteTo(c.conn)
 					if err != nil {
+						writeTs = 0
 						logging.Errorf("%s [%s:%s:%d] stoppingConsumer: %t write to downstream socket failed, err: %v",
 							logPrefix, c.workerName, c.tcpPort, c.Pid(), c.stoppingConsumer, err)
 
@@ -539,6 +617,7 @@ func (c *Consumer) sendMessageLoop() {
 
 					// Reset the sendMessage buffer and message counter
 					c.sendMsgBuffer.Reset()
+					c.markTracesWritten(writeTs)
 					c.aggMessagesSentCounter += c.sendMsgCounter
 					c.sendMsgCounter = 0
 				}()
@@ -602,14 +681,19 @@ func (c *Consumer) sendMessage(m *msgToTransmit) error {
 	}
 
 	c.sendMsgCounter++
+	if m.trace != nil {
+		c.bufferedTraces = append(c.bufferedTraces, m.trace)
+	}
 
 	if c.sendMsgCounter >= uint64(c.socketWriteBatchSize) || m.prioritize || m.sendToDebugger {
 		c.connMutex.Lock()
 		defer c.connMutex.Unlock()
 
+		var writeTs int64
 		if !m.sendToDebugger && c.conn != nil {
 			c.conn.SetWriteDeadline(time.Now().Add(c.socketTimeout))
 
+			writeTs = time.Now().UnixNano()
 			_, err := c.sendMsgBuffer.WriteTo(c.conn)
 			if err != nil {
 				logging.Errorf("%s [%s:%s:%d] stoppingConsumer: %t write to downstream socket failed, err: %v",
@@ -636,6 +720,7 @@ func (c *Consumer) sendMessage(m *msgToTransmit) error {
 		// Reset the sendMessage buffer and message counter
 		c.aggMessagesSentCounter += c.sendMsgCounter
 		c.sendMsgBuffer.Reset()
+		c.markTracesWritten(writeTs)
 		c.sendMsgCounter = 0
 	}
 
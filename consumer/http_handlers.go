@@ -152,6 +152,7 @@ func (c *Consumer) dcpEventsRemainingToProcess() error {
 
 	vbsTohandle := c.vbsToHandle()
 	if len(vbsTohandle) <= 0 {
+		c.sampleVbLag(nil, nil)
 		return nil
 	}
 
@@ -166,6 +167,7 @@ func (c *Consumer) dcpEventsRemainingToProcess() error {
 		c.dcpEventsRemaining = 0
 		return err
 	}
+	c.sampleVbLag(vbsTohandle, seqNos)
 
 	var eventsProcessed, totalEvents uint64
 
@@ -0,0 +1,112 @@
+package consumer
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+)
+
+// Processed seq nos of vbuckets are sampled every lagSampleInterval, and throughput is worked
+// out over lagRateWindow, long enough to span a few progress reports from eventing-consumer
+const (
+	lagSampleInterval = 10 * time.Second
+	lagRateWindow     = 5 * time.Minute
+)
+
+type seqNoSample struct {
+	seqNo uint64
+	ts    time.Time
+}
+
+type vbLag struct {
+	highSeqNo uint64
+	samples   []*seqNoSample // Oldest first
+}
+
+// Records high seq nos of vbuckets the consumer handles, as fetched from KV, and samples
+// their processed seq nos
+func (c *Consumer) sampleVbLag(vbs []uint16, highSeqNos []uint64) {
+	now := time.Now()
+
+	c.vbLagRWMutex.Lock()
+	defer c.vbLagRWMutex.Unlock()
+
+	lags := make(map[uint16]*vbLag, len(vbs))
+	for _, vb := range vbs {
+		if int(vb) >= len(highSeqNos) {
+			continue
+		}
+
+		lag, ok := c.vbLags[vb]
+		if !ok {
+			lag = &vbLag{}
+		}
+		lag.highSeqNo = highSeqNos[vb]
+		lags[vb] = lag
+
+		seqNo := c.vbProcessingStats.getVbStat(vb, "last_processed_seq_no").(uint64)
+		if n := len(lag.samples); n > 0 {
+			if seqNo < lag.samples[n-1].seqNo {
+				// Rolled back or reprocessed, earlier samples no longer tell throughput
+				lag.samples = nil
+			} else if now.Sub(lag.samples[n-1].ts) < lagSampleInterval {
+				continue
+			}
+		}
+
+		lag.samples = append(lag.samples, &seqNoSample{seqNo: seqNo, ts: now})
+		for len(lag.samples) > 2 && now.Sub(lag.samples[1].ts) >= lagRateWindow {
+			lag.samples = lag.samples[1:]
+		}
+	}
+	c.vbLags = lags
+}
+
+// VbLag reports how far behind vbuckets handled by the consumer are
+func (c *Consumer) VbLag() []*common.VbLag {
+	now := time.Now()
+	node := c.HostPortAddr()
+
+	c.vbLagRWMutex.RLock()
+	defer c.vbLagRWMutex.RUnlock()
+
+	lags := make([]*common.VbLag, 0, len(c.vbLags))
+	for vb, lag := range c.vbLags {
+		seqNo := c.vbProcessingStats.getVbStat(vb, "last_processed_seq_no").(uint64)
+
+		l := &common.VbLag{
+			Vbucket:        vb,
+			Node:           node,
+			Worker:         c.ConsumerName(),
+			StreamStatus:   c.vbProcessingStats.getVbStat(vb, "dcp_stream_status").(string),
+			HighSeqNo:      lag.highSeqNo,
+			ProcessedSeqNo: seqNo,
+		}
+		if lag.highSeqNo > seqNo {
+			l.Backlog = lag.highSeqNo - seqNo
+		}
+
+		if len(lag.samples) > 0 {
+			first := lag.samples[0]
+			if elapsed := now.Sub(first.ts).Seconds(); elapsed > 0 && seqNo >= first.seqNo {
+				l.Throughput = float64(seqNo-first.seqNo) / elapsed
+			}
+		}
+
+		if l.Backlog == 0 {
+			eta := int64(0)
+			l.EtaSecs = &eta
+		} else if l.Throughput > 0 {
+			eta := int64(math.Ceil(float64(l.Backlog) / l.Throughput))
+			l.EtaSecs = &eta
+		}
+		lags = append(lags, l)
+	}
+
+	sort.Slice(lags, func(i, j int) bool {
+		return lags[i].Vbucket < lags[j].Vbucket
+	})
+	return lags
+}
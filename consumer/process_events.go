@@ -66,6 +66,17 @@ func (c *Consumer) processEvents() {
 				c.filterVbEventsRWMutex.RUnlock()
 
 				c.vbProcessingStats.updateVbStat(e.VBucket, "last_read_seq_no", e.Seqno)
+
+				if c.isQuarantinedEvent(e) {
+					c.skippedQuarantinedCounter++
+					continue
+				}
+
+				if c.isPastBackfillJob(e) {
+					c.skippedBackfillJobCounter++
+					continue
+				}
+
 				logging.Tracef("%s [%s:%s:%d] Got DCP_MUTATION for key: %ru datatype: %v",
 					logPrefix, c.workerName, c.tcpPort, c.Pid(), string(e.Key), e.Datatype)
 
@@ -103,6 +114,17 @@ func (c *Consumer) processEvents() {
 				c.filterVbEventsRWMutex.RUnlock()
 
 				c.vbProcessingStats.updateVbStat(e.VBucket, "last_read_seq_no", e.Seqno)
+
+				if c.isQuarantinedEvent(e) {
+					c.skippedQuarantinedCounter++
+					continue
+				}
+
+				if c.isPastBackfillJob(e) {
+					c.skippedBackfillJobCounter++
+					continue
+				}
+
 				switch e.Datatype {
 				case dcpDatatypeJSONXattr:
 					xattrLen := binary.BigEndian.Uint32(e.Value[0:4])
@@ -167,7 +189,7 @@ func (c *Consumer) processEvents() {
 					}
 
 					if c.usingTimer {
-						err := timers.Create(c.producer.GetMetadataPrefix(), int(e.VBucket), connStr, c.producer.MetadataBucket())
+						err := timers.Create(c.producer.GetMetadataPrefix(), int(e.VBucket), connStr, c.producer.MetadataBucket(), c.timerResolution)
 						if err == common.ErrRetryTimeout {
 							logging.Infof("%s [%s:%s:%d] Exiting due to timeout", logPrefix, c.workerName, c.tcpPort, c.Pid())
 							return
@@ -192,6 +214,8 @@ func (c *Consumer) processEvents() {
 						return
 					}
 
+					c.addQuarantinedEvents(e.VBucket, vbBlob.QuarantinedSeqNos)
+
 					vbuuid, seqNo, err := e.FailoverLog.Latest()
 					if err != nil {
 						logging.Errorf("%s [%s:%s:%d] vb: %d STREAMREQ Inserting entry: %#v to vbFlogChan."+
@@ -380,9 +404,26 @@ func (c *Consumer) processEvents() {
 			delete(c.filterVbEvents, e.Vbucket)
 			c.filterVbEventsRWMutex.Unlock()
 
+			seqNoProcessed := e.SeqNo
+			if startSeqNo, ok := c.popReprocessSeqNo(e.Vbucket); ok {
+				if startSeqNo < seqNoProcessed {
+					seqNoProcessed = startSeqNo
+				}
+
+				// Events from closed stream still queued up in C++ worker beyond seqNoProcessed
+				// would be replayed anyway, filter only what precedes restarted stream
+				c.sendVbFilterData(e.Vbucket, seqNoProcessed, true)
+
+				c.vbProcessingStats.updateVbStat(e.Vbucket, "reprocess_start_seq_no", seqNoProcessed)
+				c.vbProcessingStats.updateVbStat(e.Vbucket, "reprocess_status", reprocessRestreaming)
+
+				logging.Infof("%s [%s:%s:%d] vb: %d Rewinding checkpoint from seq no: %d to: %d for reprocess",
+					logPrefix, c.workerName, c.tcpPort, c.Pid(), e.Vbucket, e.SeqNo, seqNoProcessed)
+			}
+
 			var vbBlob vbucketKVBlob
 			var cas gocb.Cas
-			c.vbProcessingStats.updateVbStat(e.Vbucket, "last_processed_seq_no", e.SeqNo)
+			c.vbProcessingStats.updateVbStat(e.Vbucket, "last_processed_seq_no", seqNoProcessed)
 
 			err = util.Retry(util.NewFixedBackoff(bucketOpRetryInterval), c.retryCount, getOpCallback,
 				c, c.producer.AddMetadataPrefix(vbKey), &vbBlob, &cas, false)
@@ -391,7 +432,7 @@ func (c *Consumer) processEvents() {
 				return
 			}
 
-			vbBlob.LastSeqNoProcessed = e.SeqNo
+			vbBlob.LastSeqNoProcessed = seqNoProcessed
 			err = c.updateCheckpoint(vbKey, e.Vbucket, &vbBlob)
 			if err == common.ErrRetryTimeout {
 				logging.Errorf("%s [%s:%s:%d] Exiting due to timeout", logPrefix, c.workerName, c.tcpPort, c.Pid())
@@ -528,6 +569,9 @@ func (c *Consumer) startDcp(flogs couchbase.FailoverLog) error {
 	flogVbs := make([]uint16, 0)
 	vbs := make([]uint16, 0)
 
+	// Resolved only once a vbucket needs to be bootstrapped from from_timestamp boundary
+	var timestampSeqNos map[uint16]uint64
+
 	for vb := range flogs {
 		flogVbs = append(flogVbs, vb)
 	}
@@ -635,6 +679,25 @@ func (c *Consumer) startDcp(flogs couchbase.FailoverLog) error {
 				}
 				c.vbProcessingStats.updateVbStat(vb, "start_seq_no", start)
 				c.vbProcessingStats.updateVbStat(vb, "timestamp", time.Now().Format(time.RFC3339))
+
+			case common.DcpFromSeqNos, common.DcpFromTimestamp:
+				err = c.resolveStreamBoundary(flogs, vbSeqnos, &timestampSeqNos)
+				if err == common.ErrRetryTimeout {
+					logging.Errorf("%s [%s:%s:%d] Exiting due to timeout", logPrefix, c.workerName, c.tcpPort, c.Pid())
+					return err
+				}
+
+				start = c.streamBoundarySeqNo(vb, vbSeqnos, timestampSeqNos)
+				logging.Infof("%s [%s:%s:%d] vb: %d boundary: %s start seq no: %d Sending streamRequestInfo size: %d",
+					logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, c.dcpStreamBoundary, start, len(c.reqStreamCh))
+
+				c.reqStreamCh <- &streamRequestInfo{
+					vb:         vb,
+					vbBlob:     &vbBlob,
+					startSeqNo: start,
+				}
+				c.vbProcessingStats.updateVbStat(vb, "start_seq_no", start)
+				c.vbProcessingStats.updateVbStat(vb, "timestamp", time.Now().Format(time.RFC3339))
 			}
 		} else {
 			logging.Infof("%s [%s:%s:%d] vb: %d checkpoint blob prexisted, UUID: %s assigned worker: %s",
@@ -682,6 +745,21 @@ func (c *Consumer) startDcp(flogs couchbase.FailoverLog) error {
 							startSeqNo: vbBlob.LastSeqNoProcessed,
 						}
 						c.vbProcessingStats.updateVbStat(vb, "start_seq_no", vbBlob.LastSeqNoProcessed)
+
+					case common.DcpFromSeqNos, common.DcpFromTimestamp:
+						err = c.resolveStreamBoundary(flogs, vbSeqnos, &timestampSeqNos)
+						if err == common.ErrRetryTimeout {
+							logging.Errorf("%s [%s:%s:%d] Exiting due to timeout", logPrefix, c.workerName, c.tcpPort, c.Pid())
+							return err
+						}
+
+						start = c.streamBoundarySeqNo(vb, vbSeqnos, timestampSeqNos)
+						c.reqStreamCh <- &streamRequestInfo{
+							vb:         vb,
+							vbBlob:     &vbBlob,
+							startSeqNo: start,
+						}
+						c.vbProcessingStats.updateVbStat(vb, "start_seq_no", start)
 					}
 				} else {
 					c.reqStreamCh <- &streamRequestInfo{
@@ -706,6 +784,39 @@ func (c *Consumer) startDcp(flogs couchbase.FailoverLog) error {
 	return nil
 }
 
+// Resolves seq no to bootstrap vbucket stream from, for from_seqnos and from_timestamp
+// feed boundaries. Seq nos, either supplied in the vector or resolved from the timestamp,
+// are capped at current high seq no of the vbucket, as the stream can't start beyond it
+// and vbuckets missing from them are streamed from now
+func (c *Consumer) streamBoundarySeqNo(vb uint16, vbSeqnos []uint64, timestampSeqNos map[uint16]uint64) uint64 {
+	var highSeqNo uint64
+	if int(vb) < len(vbSeqnos) {
+		highSeqNo = vbSeqnos[int(vb)]
+	}
+
+	seqNos := c.dcpStreamBoundarySeqNos
+	if c.dcpStreamBoundary == common.DcpFromTimestamp {
+		seqNos = timestampSeqNos
+	}
+
+	seqNo, ok := seqNos[vb]
+	if !ok || seqNo > highSeqNo {
+		return highSeqNo
+	}
+	return seqNo
+}
+
+// Resolves from_timestamp boundary into seq nos of vbuckets in flogs, unless it's already
+// been resolved or the feed boundary is something else
+func (c *Consumer) resolveStreamBoundary(flogs couchbase.FailoverLog, vbSeqnos []uint64, timestampSeqNos *map[uint16]uint64) error {
+	if c.dcpStreamBoundary != common.DcpFromTimestamp || *timestampSeqNos != nil {
+		return nil
+	}
+
+	return util.Retry(util.NewFixedBackoff(clusterOpRetryInterval), c.retryCount,
+		resolveTimestampBoundaryCallback, c, c.dcpStreamBoundaryTimestamp, flogs, vbSeqnos, timestampSeqNos)
+}
+
 func (c *Consumer) addToAggChan(dcpFeed *couchbase.DcpFeed) {
 	logPrefix := "Consumer::addToAggChan"
 
@@ -745,7 +856,7 @@ func (c *Consumer) addToAggChan(dcpFeed *couchbase.DcpFeed) {
 					return
 				}
 
-				if c.aggDCPFeedMem > c.aggDCPFeedMemCap {
+				if c.aggDCPFeedMem > c.aggDCPFeedMemCap || atomic.LoadInt32(&c.memoryThrottled) == 1 {
 					time.Sleep(10 * time.Millisecond)
 				}
 
@@ -1133,6 +1244,7 @@ func (c *Consumer) handleFailoverLog() {
 					// for stream in later case, unless we maintain another data structure to
 					// maintain that information
 					c.sendVbFilterData(vbFlog.vb, vbFlog.seqNo, true)
+					c.recordRollback(vbFlog.vb, vbBlob.LastSeqNoProcessed, vbFlog.seqNo, vbuuid)
 					vbBlob.VBuuid = vbuuid
 					streamInfo := &streamRequestInfo{
 						vb:         vbFlog.vb,
@@ -1198,6 +1310,74 @@ func (c *Consumer) handleFailoverLog() {
 	}
 }
 
+func (c *Consumer) recordRollback(vb uint16, oldSeqNo, newSeqNo, vbuuid uint64) {
+	logPrefix := "Consumer::recordRollback"
+
+	entry := &common.RollbackEntry{
+		HostPortAddr: c.HostPortAddr(),
+		NewSeqNo:     newSeqNo,
+		OldSeqNo:     oldSeqNo,
+		Timestamp:    time.Now().Format(time.RFC3339),
+		Vbucket:      vb,
+		VbUUID:       vbuuid,
+		WorkerName:   c.workerName,
+	}
+
+	c.rollbackHistoryRWMutex.Lock()
+	c.rollbackHistory = append(c.rollbackHistory, entry)
+	if len(c.rollbackHistory) > rollbackHistorySize {
+		c.rollbackHistory = c.rollbackHistory[len(c.rollbackHistory)-rollbackHistorySize:]
+	}
+	c.rollbackHistoryRWMutex.Unlock()
+
+	atomic.AddUint64(&c.dcpRollbackCounter, 1)
+
+	counter, _ := c.vbProcessingStats.getVbStat(vb, "rollback_counter").(uint64)
+	c.vbProcessingStats.updateVbStat(vb, "rollback_counter", counter+1)
+	c.vbProcessingStats.updateVbStat(vb, "rollback_from_seq_no", oldSeqNo)
+	c.vbProcessingStats.updateVbStat(vb, "rollback_to_seq_no", newSeqNo)
+
+	logging.Infof("%s [%s:%s:%d] vb: %d rolled back from seqNo: %d to seqNo: %d vbuuid: %d",
+		logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, oldSeqNo, newSeqNo, vbuuid)
+
+	c.producer.WriteAppLog(fmt.Sprintf("Rollback requested by DCP for vb: %d from seq_no: %d to seq_no: %d, mutations after seq_no: %d will be redelivered",
+		vb, oldSeqNo, newSeqNo, newSeqNo))
+
+	if c.deliverRollbackEvent {
+		c.sendRollbackEvent(vb, oldSeqNo, newSeqNo, vbuuid)
+	}
+}
+
+func (c *Consumer) popReprocessSeqNo(vb uint16) (uint64, bool) {
+	c.reprocessRWMutex.Lock()
+	defer c.reprocessRWMutex.Unlock()
+
+	seqNo, ok := c.reprocessSeqNos[vb]
+	if ok {
+		delete(c.reprocessSeqNos, vb)
+	}
+	return seqNo, ok
+}
+
+// Marks reprocess of vbucket complete once processing catches up with seq no
+// read at the time of request
+func (c *Consumer) updateReprocessStatus(vb uint16) {
+	logPrefix := "Consumer::updateReprocessStatus"
+
+	if status, ok := c.vbProcessingStats.getVbStat(vb, "reprocess_status").(string); !ok || status != reprocessRestreaming {
+		return
+	}
+
+	endSeqNo := c.vbProcessingStats.getVbStat(vb, "reprocess_end_seq_no").(uint64)
+	lastProcessedSeqNo := c.vbProcessingStats.getVbStat(vb, "last_processed_seq_no").(uint64)
+
+	if lastProcessedSeqNo >= endSeqNo {
+		c.vbProcessingStats.updateVbStat(vb, "reprocess_status", reprocessCompleted)
+		logging.Infof("%s [%s:%s:%d] vb: %d Reprocess completed, last processed seq no: %d",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, lastProcessedSeqNo)
+	}
+}
+
 func (c *Consumer) getCurrentlyOwnedVbs() []uint16 {
 	var vbsOwned []uint16
 
@@ -1222,11 +1402,123 @@ func (c *Consumer) cppWorkerThrPartitionMap() {
 	}
 
 	c.cppThrPartitionMap = util.VbucketDistribution(partitions, c.cppWorkerThrCount)
+
+	c.cppPartitionThrMap = make(map[uint16]int)
+	for thr, thrPartitions := range c.cppThrPartitionMap {
+		for _, partition := range thrPartitions {
+			c.cppPartitionThrMap[partition] = thr
+		}
+	}
+}
+
+// Queues up event sent to eventing-consumer against the cpp worker thread that executes it.
+// A thread executes events of its partitions in the order they're sent, so once events it's
+// done with are dropped off the front, the one at the front is what it's executing
+func (c *Consumer) trackInflightEvent(e *cb.DcpEvent) {
+	thr := c.cppPartitionThrMap[util.VbucketByKey(e.Key, cppWorkerPartitionCount)]
+
+	c.inflightEventsRWMutex.Lock()
+	defer c.inflightEventsRWMutex.Unlock()
+
+	c.inflightEvents[thr] = append(c.dropProcessedEvents(c.inflightEvents[thr]), &common.CrashSuspect{
+		Key:     string(e.Key),
+		SeqNo:   e.Seqno,
+		Vbucket: e.VBucket,
+	})
+}
+
+// Drops events off the front of a thread's queue that eventing-consumer has reported as processed
+func (c *Consumer) dropProcessedEvents(events []*common.CrashSuspect) []*common.CrashSuspect {
+	for len(events) > 0 {
+		lastProcessedSeqNo, _ := c.vbProcessingStats.getVbStat(events[0].Vbucket, "last_processed_seq_no").(uint64)
+		if events[0].SeqNo > lastProcessedSeqNo {
+			break
+		}
+		events[0] = nil
+		events = events[1:]
+	}
+	return events
+}
+
+// Loads events quarantined for crashing eventing-consumer, as recorded in checkpoint blob of vbucket
+func (c *Consumer) addQuarantinedEvents(vb uint16, seqNos []uint64) {
+	for _, seqNo := range seqNos {
+		if _, ok := c.quarantinedEvents[vb]; !ok {
+			c.quarantinedEvents[vb] = make(map[uint64]struct{})
+		}
+		c.quarantinedEvents[vb][seqNo] = struct{}{}
+	}
+}
+
+func (c *Consumer) isQuarantinedEvent(e *cb.DcpEvent) bool {
+	logPrefix := "Consumer::isQuarantinedEvent"
+
+	if _, ok := c.quarantinedEvents[e.VBucket][e.Seqno]; !ok {
+		return false
+	}
+
+	logging.Infof("%s [%s:%s:%d] vb: %d seqNo: %d key: %ru Skipping event quarantined for crashing eventing-consumer",
+		logPrefix, c.workerName, c.tcpPort, c.Pid(), e.VBucket, e.Seqno, string(e.Key))
+	return true
+}
+
+// Events past seq nos recorded when function was deployed as backfill job aren't processed. Vbucket
+// is marked for checkpointing to report it caught up, as the event at the recorded seq no may never
+// be streamed if a later mutation of the same document deduplicated it
+func (c *Consumer) isPastBackfillJob(e *cb.DcpEvent) bool {
+	if int(e.VBucket) >= len(c.backfillJobSeqNos) || e.Seqno <= c.backfillJobSeqNos[e.VBucket] {
+		return false
+	}
+
+	c.backfillJobRWMutex.Lock()
+	c.backfillJobPastVbs[e.VBucket] = struct{}{}
+	c.backfillJobRWMutex.Unlock()
+	return true
+}
+
+// Moves processed seq no of a vbucket that streamed past seq nos of backfill job to the recorded one,
+// once eventing-consumer has processed all events sent before, so that the job sees it caught up
+func (c *Consumer) updateBackfillJobStatus(vb uint16) {
+	logPrefix := "Consumer::updateBackfillJobStatus"
+
+	c.backfillJobRWMutex.RLock()
+	_, past := c.backfillJobPastVbs[vb]
+	c.backfillJobRWMutex.RUnlock()
+
+	if !past || c.hasInflightEvents(vb) {
+		return
+	}
+
+	seqNo := c.backfillJobSeqNos[vb]
+	if lastProcessedSeqNo := c.vbProcessingStats.getVbStat(vb, "last_processed_seq_no").(uint64); lastProcessedSeqNo < seqNo {
+		c.vbProcessingStats.updateVbStat(vb, "last_processed_seq_no", seqNo)
+		logging.Infof("%s [%s:%s:%d] vb: %d Caught up with backfill job, moving last processed seq no from: %d to: %d",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, lastProcessedSeqNo, seqNo)
+	}
+}
+
+// Tells if events of vbucket sent to eventing-consumer are yet to be processed
+func (c *Consumer) hasInflightEvents(vb uint16) bool {
+	lastProcessedSeqNo, _ := c.vbProcessingStats.getVbStat(vb, "last_processed_seq_no").(uint64)
+
+	c.inflightEventsRWMutex.RLock()
+	defer c.inflightEventsRWMutex.RUnlock()
+
+	for _, events := range c.inflightEvents {
+		for _, event := range events {
+			if event.Vbucket == vb && event.SeqNo > lastProcessedSeqNo {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 func (c *Consumer) sendEvent(e *cb.DcpEvent) error {
 	logPrefix := "Consumer::processTrappedEvent"
 
+	c.trackInflightEvent(e)
+
 	if !c.producer.IsTrapEvent() {
 		c.sendDcpEvent(e, false)
 		return nil
@@ -54,12 +54,14 @@ const (
 	v8WorkerCompile
 	v8WorkerLcbExceptions
 	v8WorkerCurlLatencyStats
+	v8WorkerDcpLatencyStats
 )
 
 const (
 	dcpOpcode int8 = iota
 	dcpDeletion
 	dcpMutation
+	dcpRollback
 )
 
 const (
@@ -77,6 +79,7 @@ const (
 	docTimerResponse
 	bucketOpsResponse
 	bucketOpsFilterAck
+	traceResponse
 )
 
 const (
@@ -92,6 +95,7 @@ const (
 	queueSize
 	lcbExceptions
 	curlLatencyStats
+	dcpLatencyStats
 )
 
 const (
@@ -106,6 +110,10 @@ const (
 	bucketOpsFilterAckOpCode int8 = iota
 )
 
+const (
+	traceSpans int8 = iota
+)
+
 type message struct {
 	Header  []byte
 	Payload []byte
@@ -115,16 +123,23 @@ func (c *Consumer) makeTimerEventHeader(partition int16) ([]byte, *flatbuffers.B
 	return c.makeHeader(timerEvent, timer, partition, "")
 }
 
-func (c *Consumer) makeDcpMutationHeader(partition int16, mutationMeta string) ([]byte, *flatbuffers.Builder) {
-	return c.makeDcpHeader(dcpMutation, partition, mutationMeta)
+func (c *Consumer) makeDcpMutationHeader(partition int16, mutationMeta string, dcpTs int64, traceID string) ([]byte, *flatbuffers.Builder) {
+	return c.makeDcpHeader(dcpMutation, partition, mutationMeta, dcpTs, traceID)
+}
+
+func (c *Consumer) makeDcpDeletionHeader(partition int16, deletionMeta string, dcpTs int64, traceID string) ([]byte, *flatbuffers.Builder) {
+	return c.makeDcpHeader(dcpDeletion, partition, deletionMeta, dcpTs, traceID)
 }
 
-func (c *Consumer) makeDcpDeletionHeader(partition int16, deletionMeta string) ([]byte, *flatbuffers.Builder) {
-	return c.makeDcpHeader(dcpDeletion, partition, deletionMeta)
+func (c *Consumer) makeDcpRollbackHeader(partition int16, rollbackMeta string) ([]byte, *flatbuffers.Builder) {
+	return c.makeDcpHeader(dcpRollback, partition, rollbackMeta, 0, "")
 }
 
-func (c *Consumer) makeDcpHeader(opcode int8, partition int16, meta string) ([]byte, *flatbuffers.Builder) {
-	return c.makeHeader(dcpEvent, opcode, partition, meta)
+// dcpTs is when the event was received from DCP, which eventing-consumer measures latency of
+// handler execution from. traceID is set for events sampled for tracing, which eventing-consumer
+// reports back on
+func (c *Consumer) makeDcpHeader(opcode int8, partition int16, meta string, dcpTs int64, traceID string) ([]byte, *flatbuffers.Builder) {
+	return c.makeEventHeader(dcpEvent, opcode, partition, meta, dcpTs, traceID)
 }
 
 func (c *Consumer) filterEventHeader(opcode int8, partition int16, meta string) ([]byte, *flatbuffers.Builder) {
@@ -184,9 +199,17 @@ func (c *Consumer) makeThrMapHeader() ([]byte, *flatbuffers.Builder) {
 }
 
 func (c *Consumer) makeHeader(event int8, opcode int8, partition int16, meta string) (encodedHeader []byte, builder *flatbuffers.Builder) {
+	return c.makeEventHeader(event, opcode, partition, meta, 0, "")
+}
+
+func (c *Consumer) makeEventHeader(event int8, opcode int8, partition int16, meta string, dcpTs int64, traceID string) (encodedHeader []byte, builder *flatbuffers.Builder) {
 	builder = c.getBuilder()
 
 	metadata := builder.CreateString(meta)
+	var trace flatbuffers.UOffsetT
+	if traceID != "" {
+		trace = builder.CreateString(traceID)
+	}
 
 	header.HeaderStart(builder)
 
@@ -194,6 +217,12 @@ func (c *Consumer) makeHeader(event int8, opcode int8, partition int16, meta str
 	header.HeaderAddOpcode(builder, opcode)
 	header.HeaderAddPartition(builder, partition)
 	header.HeaderAddMetadata(builder, metadata)
+	if dcpTs > 0 {
+		header.HeaderAddDcpTs(builder, dcpTs)
+	}
+	if traceID != "" {
+		header.HeaderAddTraceId(builder, trace)
+	}
 
 	headerPos := header.HeaderEnd(builder)
 	builder.Finish(headerPos)
@@ -400,6 +429,16 @@ func (c *Consumer) routeResponse(msgType, opcode int8, msg string) {
 				logging.Errorf("%s [%s:%s:%d] Failed to unmarshal curl latency stats, msg: %v err: %v",
 					logPrefix, c.workerName, c.tcpPort, c.Pid(), msg, err)
 			}
+		case dcpLatencyStats:
+			c.workerRespMainLoopTs.Store(time.Now())
+
+			c.statsRWMutex.Lock()
+			defer c.statsRWMutex.Unlock()
+			err := json.Unmarshal([]byte(msg), &c.dcpLatencyStats)
+			if err != nil {
+				logging.Errorf("%s [%s:%s:%d] Failed to unmarshal dcp latency stats, msg: %v err: %v",
+					logPrefix, c.workerName, c.tcpPort, c.Pid(), msg, err)
+			}
 		case failureStats:
 			c.workerRespMainLoopTs.Store(time.Now())
 
@@ -511,6 +550,8 @@ func (c *Consumer) routeResponse(msgType, opcode int8, msg string) {
 		if ack.SkipAck == 0 {
 			c.filterDataCh <- &ack
 		}
+	case traceResponse:
+		c.completeTrace(msg)
 	default:
 		logging.Infof("%s [%s:%s:%d] Unknown message %s",
 			logPrefix, c.workerName, c.tcpPort, c.Pid(), msg)
@@ -54,6 +54,13 @@ func newVbProcessingStats(appName string, numVbuckets uint16, uuid, workerName s
 		vbsts[i].stats["last_checkpointed_seq_no"] = uint64(0)
 		vbsts[i].stats["last_read_seq_no"] = uint64(0)
 		vbsts[i].stats["node_uuid"] = uuid
+		vbsts[i].stats["reprocess_end_seq_no"] = uint64(0)
+		vbsts[i].stats["reprocess_start_seq_no"] = uint64(0)
+		vbsts[i].stats["reprocess_status"] = ""
+		vbsts[i].stats["reprocess_timestamp"] = ""
+		vbsts[i].stats["rollback_counter"] = uint64(0)
+		vbsts[i].stats["rollback_from_seq_no"] = uint64(0)
+		vbsts[i].stats["rollback_to_seq_no"] = uint64(0)
 		vbsts[i].stats["start_seq_no"] = uint64(0)
 		vbsts[i].stats["seq_no_at_stream_end"] = uint64(0)
 		vbsts[i].stats["seq_no_after_close_stream"] = uint64(0)
@@ -112,6 +119,7 @@ func (c *Consumer) updateWorkerStats() {
 			c.sendGetLatencyStats(false)
 			c.sendGetLcbExceptionStats(false)
 			c.refershCurlLatencyStats(false)
+			c.sendGetDcpLatencyStats(false)
 
 			val := c.workerRespMainLoopTs.Load()
 			if val == nil {
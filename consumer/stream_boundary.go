@@ -0,0 +1,156 @@
+package consumer
+
+import (
+	"fmt"
+	"time"
+
+	couchbase "github.com/couchbase/eventing/dcp"
+	mcd "github.com/couchbase/eventing/dcp/transport"
+	"github.com/couchbase/eventing/logging"
+)
+
+// How long a round of probes waits on vbuckets to respond, before resolution is retried
+const boundaryProbeTimeout = time.Minute
+
+// Binary search state of a vbucket, narrowing down the seq no to stream it from. Mutation
+// right past hi, if any, is known to be on or after the boundary, and so is the answer once
+// lo catches up with hi
+type boundaryProbe struct {
+	lo     uint64
+	hi     uint64
+	vbuuid uint64
+
+	// First mutation past the seq no probed in current round
+	found bool
+	seqNo uint64
+	cas   uint64
+}
+
+// Resolves timestamp(in ns) into seq nos to stream vbuckets in flogs from. Data service doesn't
+// map wall clock time to seq nos, but cas of a mutation is a hybrid logical clock, which only
+// moves forward within a vbucket. So the last seq no before the boundary is binary searched
+// for, between 0 and high seq no of the vbucket, by streaming it from a seq no and reading
+// cas of the first mutation that arrives. All vbuckets are probed together, one round per
+// halving of their ranges, on a feed of their own
+func (c *Consumer) resolveTimestampBoundary(timestamp uint64, flogs couchbase.FailoverLog, vbSeqnos []uint64) (map[uint16]uint64, error) {
+	logPrefix := "Consumer::resolveTimestampBoundary"
+
+	probes := make(map[uint16]*boundaryProbe)
+	for vb, flog := range flogs {
+		vbuuid, _, err := flog.Latest()
+		if err != nil {
+			return nil, fmt.Errorf("vb: %d failed to grab latest failover log, err: %v", vb, err)
+		}
+
+		probe := &boundaryProbe{vbuuid: vbuuid}
+		if int(vb) < len(vbSeqnos) {
+			probe.hi = vbSeqnos[int(vb)]
+		}
+		probes[vb] = probe
+	}
+
+	feedName := couchbase.NewDcpFeedName(c.HostPortAddr() + "_" + c.workerName + "_boundary_probe")
+
+	c.cbBucketRWMutex.Lock()
+	feed, err := c.cbBucket.StartDcpFeedOver(feedName, uint32(0), 0, nil, 0xABCD, c.dcpConfig)
+	c.cbBucketRWMutex.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start dcp feed, err: %v", err)
+	}
+	defer feed.Close()
+
+	rounds := 0
+	for round := uint16(1); ; round++ {
+		pending := make(map[uint16]uint64)
+		for vb, probe := range probes {
+			if probe.lo >= probe.hi {
+				continue
+			}
+
+			mid := probe.lo + (probe.hi-probe.lo)/2
+			probe.found = false
+			err = feed.DcpRequestStream(vb, round, uint32(0), probe.vbuuid, mid, probe.hi, mid, mid)
+			if err != nil {
+				return nil, fmt.Errorf("vb: %d failed to request stream from seq no: %d, err: %v", vb, mid, err)
+			}
+			pending[vb] = mid
+		}
+
+		if len(pending) == 0 {
+			break
+		}
+		rounds++
+
+		if err = awaitBoundaryProbes(feed, round, probes, pending); err != nil {
+			return nil, err
+		}
+
+		for vb, mid := range pending {
+			probe := probes[vb]
+			switch {
+			case !probe.found, probe.cas >= timestamp:
+				probe.hi = mid
+			case probe.seqNo < probe.hi:
+				probe.lo = probe.seqNo
+			default:
+				probe.lo = probe.hi
+			}
+		}
+	}
+
+	seqNos := make(map[uint16]uint64, len(probes))
+	for vb, probe := range probes {
+		seqNos[vb] = probe.lo
+	}
+
+	logging.Infof("%s [%s:%s:%d] Resolved boundary timestamp: %d into seq nos of %d vbuckets in %d rounds",
+		logPrefix, c.workerName, c.tcpPort, c.Pid(), timestamp, len(seqNos), rounds)
+	return seqNos, nil
+}
+
+// Waits on streams requested in a round till each of them ends, closing a stream as soon as
+// its first mutation arrives. Events of streams of earlier rounds are told apart by opaque
+func awaitBoundaryProbes(feed *couchbase.DcpFeed, round uint16, probes map[uint16]*boundaryProbe, pending map[uint16]uint64) error {
+	timer := time.NewTimer(boundaryProbeTimeout)
+	defer timer.Stop()
+
+	ended := make(map[uint16]struct{})
+	for len(ended) < len(pending) {
+		select {
+		case e, ok := <-feed.C:
+			if !ok {
+				return fmt.Errorf("dcp feed closed while probing vbuckets")
+			}
+
+			if _, ok := pending[e.VBucket]; !ok || e.Opaque != round {
+				continue
+			}
+
+			probe := probes[e.VBucket]
+			switch e.Opcode {
+			case mcd.DCP_STREAMREQ:
+				if e.Status != mcd.SUCCESS {
+					return fmt.Errorf("vb: %d stream request failed, status: %v", e.VBucket, e.Status)
+				}
+
+			case mcd.DCP_MUTATION, mcd.DCP_DELETION, mcd.DCP_EXPIRATION:
+				if probe.found {
+					continue
+				}
+				probe.found, probe.seqNo, probe.cas = true, e.Seqno, e.Cas
+
+				if err := feed.DcpCloseStream(e.VBucket, round); err != nil {
+					return fmt.Errorf("vb: %d failed to close stream, err: %v", e.VBucket, err)
+				}
+
+			case mcd.DCP_STREAMEND:
+				ended[e.VBucket] = struct{}{}
+			}
+
+		case <-timer.C:
+			return fmt.Errorf("timed out probing %d vbuckets", len(pending)-len(ended))
+		}
+	}
+
+	return nil
+}
@@ -1,10 +1,15 @@
 package consumer
 
 import (
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"math"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/couchbase/eventing/common"
 	"github.com/couchbase/eventing/logging"
 	"github.com/couchbase/eventing/timers"
 	"github.com/couchbase/eventing/util"
@@ -21,33 +26,108 @@ func (c *Consumer) scanTimers() {
 			return
 
 		default:
-			vbs := c.getCurrentlyOwnedVbs()
-			workerVbMapping := util.VbucketDistribution(vbs, c.executeTimerRoutineCount)
+			startTs := time.Now()
 
-			var wg sync.WaitGroup
-			wg.Add(c.executeTimerRoutineCount)
+			c.executeTimers(c.getCurrentlyOwnedVbs())
 
-			startTs := time.Now()
+			delta := time.Duration(c.timerResolution)*time.Second - time.Since(startTs)
+			if delta > 0 {
+				time.Sleep(delta)
+			}
+		}
+	}
+}
+
+// Fires due timers of owned vbuckets in order of due time across all of them, rather than draining
+// one vbucket after another, favouring vbuckets that fired fewer timers in this scan among timers
+// due together. Stores are opened, and queued timers retired, by execute_timer_routine_count
+// routines in parallel, while timers are queued from one heap of all vbuckets. A vbucket being
+// advanced by a routine holds back timers due after the one it queued last, as its next timer
+// may be due earlier. A timer is retired as soon as it's queued, so that a full fire timer queue
+// ends the scan without leaving queued timers to be fired again. A vbucket fires at most
+// timer_scan_vb_limit timers per scan, leaving the rest for next scan
+func (c *Consumer) executeTimers(vbs []uint16) {
+	workerVbMapping := util.VbucketDistribution(vbs, c.executeTimerRoutineCount)
+	opened := make([]dueTimerHeap, c.executeTimerRoutineCount)
+
+	var wg sync.WaitGroup
+	wg.Add(c.executeTimerRoutineCount)
 
-			for i := 0; i < c.executeTimerRoutineCount; i++ {
-				go c.executeTimers(workerVbMapping[i], &wg)
+	for i := 0; i < c.executeTimerRoutineCount; i++ {
+		go func(i int) {
+			defer wg.Done()
+			opened[i] = c.openDueTimers(workerVbMapping[i])
+		}(i)
+	}
+
+	wg.Wait()
+
+	due := make(dueTimerHeap, 0)
+	for _, vbTimers := range opened {
+		due = append(due, vbTimers...)
+	}
+	heap.Init(&due)
+
+	toAdvance := make(chan *dueTimer, c.executeTimerRoutineCount)
+	advanced := make(chan *dueTimer, c.executeTimerRoutineCount)
+	defer close(toAdvance)
+
+	for i := 0; i < c.executeTimerRoutineCount; i++ {
+		go func() {
+			for t := range toAdvance {
+				c.retireTimer(t)
+				c.nextDueTimer(t)
+				advanced <- t
 			}
+		}()
+	}
 
-			wg.Wait()
+	// Due time of the timer each vbucket being advanced queued last
+	advancing := make(map[*dueTimer]int64)
+	queueFull := false
 
-			delta := timers.Resolution - int64(time.Now().Sub(startTs).Seconds())
-			if delta > 0 {
-				time.Sleep(time.Duration(delta) * time.Second)
+	for {
+		for !queueFull && due.Len() > 0 && len(advancing) < c.executeTimerRoutineCount &&
+			due[0].entry.AlarmDue <= earliestDue(advancing) {
+
+			t := heap.Pop(&due).(*dueTimer)
+			if !c.queueTimer(t) {
+				queueFull = true
+				break
 			}
+
+			advancing[t] = t.entry.AlarmDue
+			toAdvance <- t
+		}
+
+		if len(advancing) == 0 {
+			return
+		}
+
+		t := <-advanced
+		delete(advancing, t)
+		if t.entry != nil {
+			heap.Push(&due, t)
 		}
 	}
 }
 
-func (c *Consumer) executeTimers(vbs []uint16, wg *sync.WaitGroup) {
-	logPrefix := "Consumer::executeTimers"
+// Returns earliest of due times, or max int64 if there are none
+func earliestDue(dues map[*dueTimer]int64) int64 {
+	earliest := int64(math.MaxInt64)
+	for _, due := range dues {
+		if due < earliest {
+			earliest = due
+		}
+	}
+	return earliest
+}
 
-	defer wg.Done()
+// Looks up first due timer of each vbucket, leaving out vbuckets without due timers
+func (c *Consumer) openDueTimers(vbs []uint16) dueTimerHeap {
+	logPrefix := "Consumer::openDueTimers"
 
+	due := make(dueTimerHeap, 0)
 	for _, vb := range vbs {
 		if !c.checkIfCurrentConsumerShouldOwnVb(vb) {
 			continue
@@ -72,50 +152,198 @@ func (c *Consumer) executeTimers(vbs []uint16, wg *sync.WaitGroup) {
 			logging.Tracef("%s [%s:%s:%d] vb: %d no timers to fire",
 				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb)
 			continue
-		} else {
-			c.executeTimersImpl(store, iterator, vb)
+		}
+
+		t := &dueTimer{iterator: iterator, store: store, vb: vb}
+		c.nextDueTimer(t)
+		if t.entry != nil {
+			due = append(due, t)
 		}
 	}
+
+	return due
 }
 
-func (c *Consumer) executeTimersImpl(store *timers.TimerStore, iterator *timers.TimerIter, vb uint16) {
-	logPrefix := "Consumer::executeTimersImpl"
+// Moves to next due timer of a vbucket, leaving entry nil once there are none or the vbucket
+// has fired timer_scan_vb_limit timers in this scan
+func (c *Consumer) nextDueTimer(t *dueTimer) {
+	logPrefix := "Consumer::nextDueTimer"
 
-	for entry, err := iterator.ScanNext(); entry != nil; entry, err = iterator.ScanNext() {
-		if err != nil {
-			logging.Errorf("%s [%s:%s:%d] vb: %d unable to get timer entry, err: %v",
-				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
-			atomic.AddUint64(&c.metastoreScanErrCounter, 1)
-			continue
-		}
+	t.entry = nil
+	if c.timerScanVbLimit > 0 && t.fired >= c.timerScanVbLimit {
+		logging.Debugf("%s [%s:%s:%d] vb: %d fired %d timers, leaving rest for next scan",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), t.vb, t.fired)
+		atomic.AddUint64(&c.timerScanVbLimitCounter, 1)
+		return
+	}
+
+	// Rest of the vbucket is left for next scan on an error, rather than walking its span
+	// erroring out on every row while metadata bucket is unreachable
+	entry, err := t.iterator.ScanNext()
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] vb: %d unable to get timer entry, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), t.vb, err)
+		atomic.AddUint64(&c.metastoreScanErrCounter, 1)
+		return
+	}
+	if entry != nil {
 		atomic.AddUint64(&c.metastoreScanCounter, 1)
+	}
+	t.entry = entry
+}
 
-		e := entry.Context.(map[string]interface{})
-		timer := &timerContext{
-			Callback:  e["callback"].(string),
-			Context:   e["context"].(string),
-			reference: entry.ContextRecord.AlarmRef,
-			Vb:        uint64(e["vb"].(float64)),
+// Queues a due timer to be fired. Returns false if fire timer queue is full, which ends the scan
+func (c *Consumer) queueTimer(t *dueTimer) bool {
+	logPrefix := "Consumer::queueTimer"
+
+	e := t.entry.Context.(map[string]interface{})
+	timer := &timerContext{
+		Callback:  e["callback"].(string),
+		Context:   e["context"].(string),
+		reference: t.entry.ContextRecord.AlarmRef,
+		Vb:        uint64(e["vb"].(float64)),
+	}
+
+	if err := c.fireTimerQueue.Push(timer); err != nil {
+		logging.Errorf("%s [%s:%s:%d] Failed to write to fireTimerQueue, size: %d, quota: %d err : %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), timer.Size(), c.timerQueueMemCap, err)
+		return false
+	}
+
+	t.fired++
+	c.recordTimerLateness(t.entry)
+	return true
+}
+
+// Deletes a queued timer from its store, or re-arms it if it's recurring
+func (c *Consumer) retireTimer(t *dueTimer) {
+	logPrefix := "Consumer::retireTimer"
+
+	e := t.entry.Context.(map[string]interface{})
+	if recurrence := recurrenceFromContext(e); recurrence != nil {
+		atomic.AddUint64(&c.recurringTimerFiredCounter, 1)
+
+		next := &timerContext{
+			Callback:   e["callback"].(string),
+			Context:    e["context"].(string),
+			Recurrence: recurrence,
+			Vb:         uint64(e["vb"].(float64)),
 		}
+		next.Reference, _ = e["reference"].(string)
 
-		if err = c.fireTimerQueue.Push(timer); err != nil {
-			logging.Errorf("%s [%s:%s:%d] Failed to write to fireTimerQueue, size: %d, quota: %d err : %v",
-				logPrefix, c.workerName, c.tcpPort, c.Pid(), timer.Size(), c.timerQueueMemCap, err)
+		if c.rearmTimer(t.store, t.entry, next, t.vb) {
 			return
 		}
+	}
 
-		// TODO: Implement ack channel
-		err = store.Delete(entry)
-		if err != nil {
-			logging.Errorf("%s [%s:%s:%d] vb: %d unable to delete timer entry, err: %v",
-				logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
-			atomic.AddUint64(&c.metastoreDeleteErrCounter, 1)
-		} else {
-			atomic.AddUint64(&c.metastoreDeleteCounter, 1)
-		}
+	// TODO: Implement ack channel
+	err := t.store.Delete(t.entry)
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] vb: %d unable to delete timer entry, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), t.vb, err)
+		atomic.AddUint64(&c.metastoreDeleteErrCounter, 1)
+	} else {
+		atomic.AddUint64(&c.metastoreDeleteCounter, 1)
 	}
 }
 
+// Re-arms a fired recurring timer for its next occurrence under the same reference, reporting
+// if the fired occurrence was taken care of. Timers that completed their occurrences are left
+// to be deleted like one-shot timers, while ones cancelled meanwhile aren't re-armed
+func (c *Consumer) rearmTimer(store *timers.TimerStore, entry *timers.TimerEntry, timer *timerContext, vb uint16) bool {
+	logPrefix := "Consumer::rearmTimer"
+
+	due, ok, err := timer.Recurrence.Advance(time.Now().Unix())
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] vb: %d unable to compute next occurrence of recurring timer, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
+		atomic.AddUint64(&c.recurringTimerRearmErrCounter, 1)
+		return false
+	}
+
+	if !ok {
+		logging.Tracef("%s [%s:%s:%d] vb: %d recurring timer completed %d occurrences",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, timer.Recurrence.Occurrence+1)
+		atomic.AddUint64(&c.recurringTimerCompletedCounter, 1)
+		return false
+	}
+
+	rearmed, err := store.Rearm(entry, due, timer)
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] vb: %d unable to rearm recurring timer, err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), vb, err)
+		atomic.AddUint64(&c.recurringTimerRearmErrCounter, 1)
+		return false
+	}
+
+	if rearmed {
+		atomic.AddUint64(&c.recurringTimerScheduledCounter, 1)
+	}
+	return true
+}
+
+// Captures how late a timer fired, in seconds, relative to the due time it was created with.
+// Timers stored before requested due time was persisted are measured against their alarm
+func (c *Consumer) recordTimerLateness(entry *timers.TimerEntry) {
+	now := time.Now()
+
+	due := entry.RequestedDue
+	if due == 0 {
+		due = entry.AlarmDue
+	}
+
+	lateness := now.Unix() - due
+	if lateness < 0 {
+		lateness = 0
+	}
+
+	c.statsRWMutex.Lock()
+	defer c.statsRWMutex.Unlock()
+	c.timerLateness.Record(lateness)
+
+	c.rotateTimerMaxLateness(now)
+	if lateness > c.timerMaxLateness {
+		c.timerMaxLateness = lateness
+	}
+}
+
+// Moves highest lateness to the previous minute's slot once a minute has passed, and clears both
+// if more than that did. Lateness reported is highest of the two, so it lasts beyond scans that
+// fire nothing but drops back within two minutes of a backlog getting cleared. Caller holds
+// statsRWMutex
+func (c *Consumer) rotateTimerMaxLateness(now time.Time) {
+	minute := now.Unix() / 60
+	switch minute {
+	case c.timerLatenessMinute:
+		return
+	case c.timerLatenessMinute + 1:
+		c.timerMaxLatenessPrev = c.timerMaxLateness
+	default:
+		c.timerMaxLatenessPrev = 0
+	}
+	c.timerMaxLateness = 0
+	c.timerLatenessMinute = minute
+}
+
+// Recurrence is persisted as part of timer context, which is read back as a generic map
+func recurrenceFromContext(context map[string]interface{}) *timers.Recurrence {
+	val, ok := context["recurrence"]
+	if !ok || val == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil
+	}
+
+	recurrence := &timers.Recurrence{}
+	if err = json.Unmarshal(data, recurrence); err != nil {
+		return nil
+	}
+	return recurrence
+}
+
 func (c *Consumer) routeTimers() {
 	logPrefix := "Consumer::routeTimers"
 
@@ -186,12 +414,36 @@ func (c *Consumer) storeTimers(index int, timerQueue *util.BoundedQueue) {
 			}
 
 			context := &timerContext{
-				Callback: timer.Callback,
-				Context:  timer.Context,
-				Vb:       timer.Vb,
+				Callback:  timer.Callback,
+				Context:   timer.Context,
+				Reference: timer.Reference,
+				Vb:        timer.Vb,
+			}
+
+			if timer.Recurrence != nil {
+				if err = timer.Recurrence.Validate(c.timerResolution); err != nil {
+					logging.Errorf("%s [%s:%s:%d] vb: %d seq: %d invalid schedule for recurring timer, err: %v",
+						logPrefix, c.workerName, c.tcpPort, c.Pid(), timer.Vb, timer.SeqNum, err)
+					atomic.AddUint64(&c.recurringTimerInvalidScheduleCounter, 1)
+					c.producer.WriteAppLog(fmt.Sprintf("Not creating recurring timer with callback %s, invalid schedule: %v",
+						timer.Callback, err))
+					continue
+				}
+
+				timer.Recurrence.Occurrence = 0
+				timer.Recurrence.ScheduledDue = timer.Epoch
+				context.Recurrence = timer.Recurrence
 			}
 
-			err = store.Set(timer.Epoch, timer.Callback+":"+timer.Reference, context)
+			err = store.SetWithLimit(timer.Epoch, timer.Callback+":"+timer.Reference, context, c.timerContextLimit(context))
+			if tooLarge, ok := err.(*timers.ContextTooLargeError); ok {
+				logging.Errorf("%s [%s:%s:%d] vb: %d seq: %d not storing timer, err: %v",
+					logPrefix, c.workerName, c.tcpPort, c.Pid(), timer.Vb, timer.SeqNum, err)
+				atomic.AddUint64(&c.timerContextTooLargeCounter, 1)
+				c.producer.WriteAppLog(fmt.Sprintf("Not creating timer with callback %s and reference %s, context is %d bytes after compression, more than timer_context_size of %d bytes",
+					timer.Callback, timer.Reference, tooLarge.Compacted, atomic.LoadInt64(&c.timerContextSize)))
+				continue
+			}
 			if err != nil {
 				logging.Errorf("%s [%s:%s:%d] vb: %d seq: %d failed to store",
 					logPrefix, c.workerName, c.tcpPort, c.Pid(), timer.Vb, timer.SeqNum)
@@ -199,6 +451,131 @@ func (c *Consumer) storeTimers(index int, timerQueue *util.BoundedQueue) {
 				continue
 			}
 			atomic.AddUint64(&c.metastoreSetCounter, 1)
+
+			if timer.Recurrence != nil {
+				atomic.AddUint64(&c.recurringTimerScheduledCounter, 1)
+			}
 		}
 	}
 }
+
+// Size limit of stored context of a timer. timer_context_size applies to context passed by
+// handler, so the rest of stored context, along with escaping of handler's context, is
+// allowed on top of it. Contexts within timer_context_size hence fit even if incompressible
+func (c *Consumer) timerContextLimit(context *timerContext) int64 {
+	data, err := json.Marshal(context)
+	if err != nil {
+		return 0
+	}
+	return atomic.LoadInt64(&c.timerContextSize) + int64(len(data)-len(context.Context))
+}
+
+// Lists pending timers of a vbucket in due order without firing them. Timers created
+// before reference was persisted in their context can only be found by looking up
+// callback and reference together
+func (c *Consumer) listVbTimers(store *timers.TimerStore, vb uint16, filter *common.TimerFilter) ([]*common.PendingTimer, error) {
+	vbTimers := make([]*common.PendingTimer, 0)
+
+	if filter.Callback != "" && filter.Reference != "" {
+		entry, err := store.Lookup(filter.Callback + ":" + filter.Reference)
+		if err != nil || entry == nil {
+			return vbTimers, err
+		}
+
+		timer := newPendingTimer(vb, entry)
+		timer.Reference = filter.Reference
+		if timerMatchesFilter(timer, filter) {
+			vbTimers = append(vbTimers, timer)
+		}
+		return vbTimers, nil
+	}
+
+	var iterator *timers.TimerIter
+	if after := filter.After; after == nil {
+		iterator = store.ScanRange(filter.From, filter.To)
+	} else {
+		// Timers due at the same time as the one resumed after come before it in
+		// vbuckets preceding its vbucket and after it in the ones following
+		seq := after.Seq
+		switch {
+		case vb < after.Vbucket:
+			seq = math.MaxInt64
+		case vb > after.Vbucket:
+			seq = 0
+		}
+		iterator = store.ScanRangeAfter(after.Due, seq, filter.To)
+	}
+
+	for {
+		entry, err := iterator.ScanNext()
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			break
+		}
+
+		timer := newPendingTimer(vb, entry)
+		if !timerMatchesFilter(timer, filter) {
+			continue
+		}
+
+		vbTimers = append(vbTimers, timer)
+		if filter.Limit > 0 && len(vbTimers) >= filter.Limit {
+			break
+		}
+	}
+
+	return vbTimers, nil
+}
+
+func newPendingTimer(vb uint16, entry *timers.TimerEntry) *common.PendingTimer {
+	timer := &common.PendingTimer{
+		AlarmKey: entry.ContextRecord.AlarmRef,
+		Due:      entry.AlarmDue,
+		Seq:      entry.Seq(),
+		Vbucket:  vb,
+	}
+
+	if context, ok := entry.Context.(map[string]interface{}); ok {
+		timer.Callback, _ = context["callback"].(string)
+		timer.Context, _ = context["context"].(string)
+		timer.Reference, _ = context["reference"].(string)
+	}
+
+	return timer
+}
+
+func timerMatchesFilter(timer *common.PendingTimer, filter *common.TimerFilter) bool {
+	if filter.Vbucket >= 0 && int(timer.Vbucket) != filter.Vbucket {
+		return false
+	}
+
+	if filter.Callback != "" && timer.Callback != filter.Callback {
+		return false
+	}
+
+	if filter.Reference != "" && timer.Reference != filter.Reference {
+		return false
+	}
+
+	if filter.From != 0 && timer.Due < filter.From {
+		return false
+	}
+
+	if filter.To != 0 && timer.Due > filter.To {
+		return false
+	}
+
+	return filter.After == nil || timerAfterCursor(timer, filter.After)
+}
+
+func timerAfterCursor(timer *common.PendingTimer, after *common.TimerCursor) bool {
+	if timer.Due != after.Due {
+		return timer.Due > after.Due
+	}
+	if timer.Vbucket != after.Vbucket {
+		return timer.Vbucket > after.Vbucket
+	}
+	return timer.Seq > after.Seq
+}
@@ -0,0 +1,112 @@
+package consumer
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	mcd "github.com/couchbase/eventing/dcp/transport"
+	"github.com/couchbase/eventing/dcp/transport/client"
+	"github.com/couchbase/eventing/logging"
+)
+
+const (
+	// Traces awaiting report from eventing-consumer, beyond which events aren't sampled
+	maxPendingTraces = 1000
+
+	// Pending traces are given up on after, as events filtered out by eventing-consumer or in
+	// flight when it restarts are never reported on
+	pendingTraceTimeout = time.Minute
+)
+
+// Samples event for tracing as per trace_sample_rate, recording when it was received from DCP
+// and when it's being sent to eventing-consumer. Returns nil if event isn't sampled
+func (c *Consumer) startTrace(e *memcached.DcpEvent) *common.EventTrace {
+	rate := c.producer.TraceSampleRate()
+	if rate <= 0 || c.traceRand.Float64() >= rate {
+		return nil
+	}
+
+	traceID := fmt.Sprintf("%016x%016x", c.traceRand.Uint64(), c.traceRand.Uint64())
+
+	opcode := "mutation"
+	if e.Opcode == mcd.DCP_DELETION {
+		opcode = "deletion"
+	}
+
+	now := time.Now().UnixNano()
+	trace := &common.EventTrace{
+		TraceID: traceID,
+		Worker:  c.workerName,
+		Opcode:  opcode,
+		Vbucket: e.VBucket,
+		SeqNo:   e.Seqno,
+		DcpTs:   e.Ctime,
+		SendTs:  now,
+	}
+
+	c.pendingTracesRWMutex.Lock()
+	defer c.pendingTracesRWMutex.Unlock()
+
+	if len(c.pendingTraces) >= maxPendingTraces {
+		cutoff := now - int64(pendingTraceTimeout)
+		for id, pending := range c.pendingTraces {
+			if pending.SendTs < cutoff {
+				delete(c.pendingTraces, id)
+			}
+		}
+		if len(c.pendingTraces) >= maxPendingTraces {
+			return nil
+		}
+	}
+
+	c.pendingTraces[traceID] = trace
+	return trace
+}
+
+// Records traces in sendMsgBuffer as written to socket at writeTs, 0 if buffer was dropped or
+// went to debugger instead. Expects sendMsgBufferRWMutex to be held
+func (c *Consumer) markTracesWritten(writeTs int64) {
+	if len(c.bufferedTraces) == 0 {
+		return
+	}
+
+	if writeTs > 0 {
+		c.pendingTracesRWMutex.Lock()
+		for _, trace := range c.bufferedTraces {
+			// Reported on already if eventing-consumer was done before write returned
+			if _, ok := c.pendingTraces[trace.TraceID]; ok {
+				trace.WriteTs = writeTs
+			}
+		}
+		c.pendingTracesRWMutex.Unlock()
+	}
+	c.bufferedTraces = nil
+}
+
+// Completes trace of an event with report from eventing-consumer and exports it
+func (c *Consumer) completeTrace(msg string) {
+	logPrefix := "Consumer::completeTrace"
+
+	var report traceReport
+	err := json.Unmarshal([]byte(msg), &report)
+	if err != nil {
+		logging.Errorf("%s [%s:%s:%d] Failed to unmarshal trace report, msg: %v err: %v",
+			logPrefix, c.workerName, c.tcpPort, c.Pid(), msg, err)
+		return
+	}
+
+	c.pendingTracesRWMutex.Lock()
+	trace, ok := c.pendingTraces[report.TraceID]
+	delete(c.pendingTraces, report.TraceID)
+	c.pendingTracesRWMutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	trace.DequeueTs = report.DequeueTs
+	trace.DoneTs = report.DoneTs
+	c.producer.ExportTrace(trace)
+}
@@ -3,6 +3,7 @@ package consumer
 import (
 	"fmt"
 	"hash/crc32"
+	"math/rand"
 	"net"
 	"runtime/debug"
 	"sort"
@@ -49,9 +50,12 @@ func NewConsumer(hConfig *common.HandlerConfig, pConfig *common.ProcessConfig, r
 		dcpConfig:                       dcpConfig,
 		dcpFeedVbMap:                    make(map[*couchbase.DcpFeed][]uint16),
 		dcpStreamBoundary:               hConfig.StreamBoundary,
+		dcpStreamBoundarySeqNos:         hConfig.StreamBoundarySeqNos,
+		dcpStreamBoundaryTimestamp:      uint64(hConfig.StreamBoundaryTimestamp),
 		diagDir:                         pConfig.DiagDir,
 		fireTimerQueue:                  util.NewBoundedQueue(hConfig.TimerQueueSize, hConfig.TimerQueueMemCap),
 		debuggerPort:                    pConfig.DebuggerPort,
+		deliverRollbackEvent:            hConfig.DeliverRollbackEvent,
 		eventingAdminPort:               pConfig.EventingPort,
 		eventingSSLPort:                 pConfig.EventingSSLPort,
 		eventingDir:                     pConfig.EventingDir,
@@ -71,6 +75,8 @@ func NewConsumer(hConfig *common.HandlerConfig, pConfig *common.ProcessConfig, r
 		index:                           index,
 		ipcType:                         pConfig.IPCType,
 		inflightDcpStreams:              make(map[uint16]struct{}),
+		inflightEvents:                  make(map[int][]*common.CrashSuspect),
+		inflightEventsRWMutex:           &sync.RWMutex{},
 		inflightDcpStreamsRWMutex:       &sync.RWMutex{},
 		hostDcpFeedRWMutex:              &sync.RWMutex{},
 		kvHostDcpFeedMap:                make(map[string]*couchbase.DcpFeed),
@@ -81,10 +87,21 @@ func NewConsumer(hConfig *common.HandlerConfig, pConfig *common.ProcessConfig, r
 		numVbuckets:                     numVbuckets,
 		opsTimestamp:                    time.Now(),
 		createTimerQueue:                util.NewBoundedQueue(hConfig.TimerQueueSize, hConfig.TimerQueueMemCap),
+		pendingTraces:                   make(map[string]*common.EventTrace),
+		pendingTracesRWMutex:            &sync.RWMutex{},
+		traceRand:                       rand.New(rand.NewSource(time.Now().UnixNano())),
 		producer:                        p,
+		quarantinedEvents:               p.QuarantinedEvents(),
+		backfillJobSeqNos:               p.BackfillJobSeqNos(),
+		backfillJobPastVbs:              make(map[uint16]struct{}),
+		backfillJobRWMutex:              &sync.RWMutex{},
+		reprocessSeqNos:                 make(map[uint16]uint64),
+		reprocessRWMutex:                &sync.RWMutex{},
 		reqStreamCh:                     make(chan *streamRequestInfo, numVbuckets*10),
 		restartVbDcpStreamTicker:        time.NewTicker(restartVbDcpStreamTickInterval),
 		retryCount:                      retryCount,
+		rollbackHistory:                 make([]*common.RollbackEntry, 0),
+		rollbackHistoryRWMutex:          &sync.RWMutex{},
 		sendMsgBufferRWMutex:            &sync.RWMutex{},
 		sendMsgCounter:                  0,
 		signalBootstrapFinishCh:         make(chan struct{}, 1),
@@ -104,8 +121,11 @@ func NewConsumer(hConfig *common.HandlerConfig, pConfig *common.ProcessConfig, r
 		superSup:                        s,
 		tcpPort:                         pConfig.SockIdentifier,
 		timerContextSize:                hConfig.TimerContextSize,
+		timerLateness:                   util.NewHistogram(),
 		timerQueueSize:                  hConfig.TimerQueueSize,
 		timerQueueMemCap:                hConfig.TimerQueueMemCap,
+		timerResolution:                 hConfig.TimerResolution,
+		timerScanVbLimit:                hConfig.TimerScanVbLimit,
 		timerStorageChanSize:            hConfig.TimerStorageChanSize,
 		timerStorageMetaChsRWMutex:      &sync.RWMutex{},
 		timerStorageRoutineCount:        hConfig.TimerStorageRoutineCount,
@@ -119,6 +139,8 @@ func NewConsumer(hConfig *common.HandlerConfig, pConfig *common.ProcessConfig, r
 		vbFlogChan:                      make(chan *vbFlogEntry, 1024),
 		vbnos:                           vbnos,
 		vbDcpEventsRemaining:            make(map[int]int64),
+		vbLags:                          make(map[uint16]*vbLag),
+		vbLagRWMutex:                    &sync.RWMutex{},
 		vbEventingNodeAssignMap:         vbEventingNodeAssignMap,
 		vbEventingNodeAssignRWMutex:     &sync.RWMutex{},
 		vbOwnershipGiveUpRoutineCount:   rConfig.VBOwnershipGiveUpRoutineCount,
@@ -335,7 +357,7 @@ func (c *Consumer) HandleV8Worker() error {
 	payload, pBuilder := c.makeV8InitPayload(c.app.AppName, c.debuggerPort, currHost,
 		c.eventingDir, c.eventingAdminPort, c.eventingSSLPort, c.getKvNodes()[0],
 		c.producer.CfgData(), c.lcbInstCapacity, c.executionTimeout,
-		int(c.checkpointInterval.Nanoseconds()/(1000*1000)), false, c.timerContextSize)
+		int(c.checkpointInterval.Nanoseconds()/(1000*1000)), false, atomic.LoadInt64(&c.timerContextSize))
 
 	c.sendInitV8Worker(payload, false, pBuilder)
 
@@ -0,0 +1,288 @@
+package logging
+
+import (
+	"encoding/json"
+	"path"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Components log levels can be set for, named after packages logging from them
+var Components = []string{"consumer", "dcp", "producer", "servicemanager", "supervisor", "suptree", "timers", "util"}
+
+// Function a hand built log line is about, from worker name as in "[worker_fn_0:port:pid]" and
+// DCP feed names, or from context of producer as in "[fn:3]"
+var (
+	workerNameRegex      = regexp.MustCompile(`worker_([\w-]+)_\d+(?:[:\]_\s]|$)`)
+	producerContextRegex = regexp.MustCompile(`^\S+ \[([\w-]+):\d+\] `)
+)
+
+// LevelOverride is log level for a component, a function, or a component of a function, taking
+// precedence over process wide level. Most specific override applies
+type LevelOverride struct {
+	Component string `json:"component,omitempty"`
+	Function  string `json:"function,omitempty"`
+	Level     string `json:"level"`
+}
+
+type levelKey struct {
+	component string
+	function  string
+}
+
+type levelOverrides struct {
+	levels     map[levelKey]LogLevel
+	min        LogLevel
+	max        LogLevel
+	byFunction bool
+
+	// Override applying to a caller, for a function if overridden by function, as resolved
+	// earlier. Lives as long as these overrides do
+	resolved sync.Map // callerKey => resolvedLevel
+}
+
+type callerKey struct {
+	pc       uintptr
+	function string
+}
+
+type resolvedLevel struct {
+	level      LogLevel
+	overridden bool
+}
+
+var (
+	overrides     atomic.Value // *levelOverrides, nil when there are none
+	overridesLock sync.Mutex
+	callerComps   sync.Map // pc => component
+)
+
+func GetLogLevel() LogLevel {
+	return baselevel
+}
+
+// SetLogLevelFor overrides log level of a component, a function, or both. Empty component or
+// function matches any
+func SetLogLevelFor(component, function string, level LogLevel) {
+	updateOverrides(func(levels map[levelKey]LogLevel) {
+		levels[levelKey{component, function}] = level
+	})
+}
+
+// ResetLogLevelFor drops override set for a component, a function, or both
+func ResetLogLevelFor(component, function string) {
+	updateOverrides(func(levels map[levelKey]LogLevel) {
+		delete(levels, levelKey{component, function})
+	})
+}
+
+// SetLogLevelOverrides replaces overrides currently set with given list
+func SetLogLevelOverrides(list []LevelOverride) {
+	updateOverrides(func(levels map[levelKey]LogLevel) {
+		for key := range levels {
+			delete(levels, key)
+		}
+		for _, o := range list {
+			levels[levelKey{o.Component, o.Function}] = Level(o.Level)
+		}
+	})
+}
+
+// MergeLevelOverride sets override in list, in place of one for same component and function,
+// or drops it from list if its level is empty
+func MergeLevelOverride(list []LevelOverride, override LevelOverride) []LevelOverride {
+	merged := make([]LevelOverride, 0, len(list)+1)
+	for _, o := range list {
+		if o.Component != override.Component || o.Function != override.Function {
+			merged = append(merged, o)
+		}
+	}
+	if override.Level != "" {
+		merged = append(merged, override)
+	}
+	return merged
+}
+
+// LevelOverridesFrom reads list of overrides back from a value decoded out of JSON, as
+// kept in global config
+func LevelOverridesFrom(value interface{}) ([]LevelOverride, error) {
+	list := make([]LevelOverride, 0)
+	if value == nil {
+		return list, nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &list)
+	return list, err
+}
+
+// LogLevelOverrides lists overrides currently set
+func LogLevelOverrides() []LevelOverride {
+	list := make([]LevelOverride, 0)
+	if o := loadOverrides(); o != nil {
+		for key, level := range o.levels {
+			list = append(list, LevelOverride{Component: key.component, Function: key.function, Level: level.String()})
+		}
+	}
+	return list
+}
+
+func loadOverrides() *levelOverrides {
+	o, _ := overrides.Load().(*levelOverrides)
+	return o
+}
+
+func updateOverrides(update func(levels map[levelKey]LogLevel)) {
+	overridesLock.Lock()
+	defer overridesLock.Unlock()
+
+	levels := make(map[levelKey]LogLevel)
+	if o := loadOverrides(); o != nil {
+		for key, level := range o.levels {
+			levels[key] = level
+		}
+	}
+	update(levels)
+
+	if len(levels) == 0 {
+		overrides.Store((*levelOverrides)(nil))
+		return
+	}
+
+	o := &levelOverrides{levels: levels, min: Trace, max: Silent}
+	for key, level := range levels {
+		if level < o.min {
+			o.min = level
+		}
+		if level > o.max {
+			o.max = level
+		}
+		if key.function != "" {
+			o.byFunction = true
+		}
+	}
+	overrides.Store(o)
+}
+
+// Decides whether a line at given level is logged, if that doesn't depend on who logs it or
+// what about. Caller and message are looked into by shouldLog only if decided is false
+func levelDecided(at LogLevel) (decided, ok bool) {
+	o := loadOverrides()
+	if o == nil {
+		return true, baselevel >= at
+	}
+	if at <= o.min && at <= baselevel {
+		return true, true
+	}
+	if at > o.max && at > baselevel {
+		return true, false
+	}
+	return false, false
+}
+
+// Decides whether a line at given level is logged, going by overrides that apply to its caller.
+// Function is worked out, from fields or else from formatted message, only if overridden for
+// some function. Overrides that apply are resolved once per caller and function
+func shouldLog(at LogLevel, fields []field, msg string) bool {
+	o := loadOverrides()
+	if o == nil {
+		return baselevel >= at
+	}
+
+	function := ""
+	if o.byFunction {
+		function = fieldFunction(fields)
+		if function == "" {
+			function = functionOf(msg)
+		}
+	}
+
+	pc, component := caller()
+	key := callerKey{pc, function}
+	resolved, ok := o.resolved.Load(key)
+	if !ok {
+		resolved = o.resolve(component, function)
+		o.resolved.Store(key, resolved)
+	}
+
+	if r := resolved.(resolvedLevel); r.overridden {
+		return r.level >= at
+	}
+	return baselevel >= at
+}
+
+func (o *levelOverrides) resolve(component, function string) resolvedLevel {
+	r := resolvedLevel{}
+	if l, ok := o.levels[levelKey{component, ""}]; ok {
+		r = resolvedLevel{l, true}
+	}
+
+	if function != "" {
+		if l, ok := o.levels[levelKey{"", function}]; ok {
+			r = resolvedLevel{l, true}
+		}
+		if l, ok := o.levels[levelKey{component, function}]; ok {
+			r = resolvedLevel{l, true}
+		}
+	}
+	return r
+}
+
+// Code logging and its component, named after its package, found by walking out of this package
+func caller() (uintptr, string) {
+	pcs := make([]uintptr, 8)
+	n := runtime.Callers(3, pcs)
+	for _, pc := range pcs[:n] {
+		if comp, ok := callerComps.Load(pc); ok {
+			return pc, comp.(string)
+		}
+
+		fn := runtime.FuncForPC(pc)
+		if fn == nil {
+			continue
+		}
+		file, _ := fn.FileLine(pc)
+		dir := path.Dir(file)
+		if path.Base(dir) == "logging" {
+			continue
+		}
+
+		comp := path.Base(dir)
+		switch {
+		case strings.Contains(file, "/dcp/"):
+			comp = "dcp"
+		case comp == "service_manager":
+			comp = "servicemanager"
+		}
+		callerComps.Store(pc, comp)
+		return pc, comp
+	}
+	return 0, ""
+}
+
+func fieldFunction(fields []field) string {
+	for _, f := range fields {
+		if f.key == FieldFunction {
+			if function, ok := f.value.(string); ok {
+				return function
+			}
+		}
+	}
+	return ""
+}
+
+func functionOf(msg string) string {
+	if match := workerNameRegex.FindStringSubmatch(msg); match != nil {
+		return match[1]
+	}
+	if match := producerContextRegex.FindStringSubmatch(msg); match != nil {
+		return match[1]
+	}
+	return ""
+}
@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFunctionOf(t *testing.T) {
+	tests := []struct {
+		msg      string
+		function string
+	}{
+		{msg: "Consumer::processEvents [worker_credit_score_0:39117:4123] vb: 7 stream started", function: "credit_score"},
+		{msg: "DCP feed eventing:worker_fn-2_3_xyz closed", function: "fn-2"},
+		{msg: "Producer::Serve [credit_score:3] started consumers", function: "credit_score"},
+		{msg: "ServiceMgr::getStats request from 10.1.1.1", function: ""},
+	}
+
+	for _, test := range tests {
+		if function := functionOf(test.msg); function != test.function {
+			t.Errorf("Expected function %q in %q, got %q", test.function, test.msg, function)
+		}
+	}
+}
+
+func TestResolveOverrides(t *testing.T) {
+	o := &levelOverrides{levels: map[levelKey]LogLevel{
+		{"consumer", ""}:   Debug,
+		{"", "fn"}:         Trace,
+		{"consumer", "fn"}: Error,
+	}}
+
+	tests := []struct {
+		component string
+		function  string
+		resolved  resolvedLevel
+	}{
+		{component: "consumer", function: "", resolved: resolvedLevel{Debug, true}},
+		{component: "consumer", function: "other", resolved: resolvedLevel{Debug, true}},
+		{component: "producer", function: "fn", resolved: resolvedLevel{Trace, true}},
+		{component: "consumer", function: "fn", resolved: resolvedLevel{Error, true}},
+		{component: "producer", function: "", resolved: resolvedLevel{}},
+	}
+
+	for _, test := range tests {
+		if resolved := o.resolve(test.component, test.function); resolved != test.resolved {
+			t.Errorf("Component: %q function: %q expected %+v, got %+v",
+				test.component, test.function, test.resolved, resolved)
+		}
+	}
+}
+
+func TestLevelDecided(t *testing.T) {
+	prevLevel := baselevel
+	SetLogLevel(Info)
+	defer SetLogLevel(prevLevel)
+
+	if decided, ok := levelDecided(Debug); !decided || ok {
+		t.Errorf("Expected Debug to be turned down without overrides, got decided: %t ok: %t", decided, ok)
+	}
+
+	SetLogLevelFor("", "fn", Debug)
+	defer ResetLogLevelFor("", "fn")
+
+	if decided, ok := levelDecided(Error); !decided || !ok {
+		t.Errorf("Expected Error to be logged regardless of overrides, got decided: %t ok: %t", decided, ok)
+	}
+	if decided, _ := levelDecided(Debug); decided {
+		t.Errorf("Expected Debug to depend on override")
+	}
+	if decided, ok := levelDecided(Trace); !decided || ok {
+		t.Errorf("Expected Trace to be turned down, above every override, got decided: %t ok: %t", decided, ok)
+	}
+}
+
+func TestFunctionOverride(t *testing.T) {
+	SetLogLevelFor("", "fn", Debug)
+	lines := captureLog(TextFormat, func() {
+		WithComponent("Consumer::scan").With(FieldFunction, "fn").Debugf("fn by field")
+		WithComponent("Consumer::scan").With(FieldFunction, "other").Debugf("other by field")
+		Debugf("Consumer::scan [worker_fn_0:39117:4123] fn by message")
+		Debugf("Consumer::scan [worker_other_0:39117:4123] other by message")
+	})
+	ResetLogLevelFor("", "fn")
+
+	if len(lines) != 2 || !strings.HasSuffix(lines[0], "fn by field") || !strings.HasSuffix(lines[1], "fn by message") {
+		t.Errorf("Expected only lines of fn to be logged, got %q", lines)
+	}
+
+	// Resolution cached for overrides that were reset mustn't apply any more
+	lines = captureLog(TextFormat, func() {
+		WithComponent("Consumer::scan").With(FieldFunction, "fn").Debugf("fn by field")
+	})
+	if len(lines) != 0 {
+		t.Errorf("Expected nothing to be logged once override is reset, got %q", lines)
+	}
+}
+
+func TestLevelOverridesFromConfig(t *testing.T) {
+	var value interface{}
+	err := json.Unmarshal([]byte(`[{"component": "timers", "level": "DEBUG"}, {"function": "fn", "level": "TRACE"}]`), &value)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal config value, err: %v", err)
+	}
+
+	list, err := LevelOverridesFrom(value)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("Expected 2 overrides, got %+v err: %v", list, err)
+	}
+
+	list = MergeLevelOverride(list, LevelOverride{Component: "timers", Level: "ERROR"})
+	list = MergeLevelOverride(list, LevelOverride{Function: "fn"})
+	if len(list) != 1 || list[0] != (LevelOverride{Component: "timers", Level: "ERROR"}) {
+		t.Errorf("Expected timers override to be replaced and fn override dropped, got %+v", list)
+	}
+
+	SetLogLevelFor("consumer", "", Debug)
+	SetLogLevelOverrides(list)
+	defer SetLogLevelOverrides(nil)
+
+	overrides := LogLevelOverrides()
+	if len(overrides) != 1 || overrides[0].Component != "timers" || Level(overrides[0].Level) != Error {
+		t.Errorf("Expected overrides to be replaced by timers override, got %+v", overrides)
+	}
+}
@@ -96,19 +96,35 @@ func Level(s string) LogLevel {
 var baselevel LogLevel
 var target *l.Logger
 var noredact bool
+var format int32 // LogFormat, accessed atomically as it is set while logging
 
 func init() {
 	target = l.New(os.Stdout, "", 0)
 	baselevel = Info
 	noredact = os.Getenv("CB_EVENTING_NOREDACT") == "true"
+	format = int32(Format(os.Getenv("CB_EVENTING_LOG_FORMAT")))
 }
 
-func printf(at LogLevel, format string, v ...interface{}) {
-	if baselevel >= at {
-		format := RedactFormat(format)
-		ts := time.Now().Format("2006-01-02T15:04:05.000-07:00")
-		target.Printf(ts+" ["+at.String()+"] "+format, v...)
+func printf(at LogLevel, fmtStr string, v ...interface{}) {
+	decided, ok := levelDecided(at)
+	if decided && !ok {
+		return
 	}
+
+	msg := fmt.Sprintf(RedactFormat(fmtStr), v...)
+	if !decided && !shouldLog(at, nil, msg) {
+		return
+	}
+
+	if logFormat() == JSONFormat {
+		printJSON(at, nil, msg)
+		return
+	}
+	target.Print(timestamp() + " [" + at.String() + "] " + msg)
+}
+
+func timestamp() string {
+	return time.Now().Format("2006-01-02T15:04:05.000-07:00")
 }
 
 func RedactFormat(format string) string {
@@ -151,6 +167,9 @@ func Tracef(format string, v ...interface{}) {
 }
 
 func IsEnabled(at LogLevel) bool {
+	if o := loadOverrides(); o != nil && at <= o.max {
+		return true
+	}
 	return baselevel >= at
 }
 
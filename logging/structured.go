@@ -0,0 +1,240 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+type LogFormat int
+
+const (
+	TextFormat LogFormat = iota
+	JSONFormat
+)
+
+// Names of contextual fields common across components
+const (
+	FieldComponent = "component"
+	FieldFunction  = "function"
+	FieldNode      = "node"
+	FieldPid       = "pid"
+	FieldVb        = "vb"
+	FieldWorker    = "worker"
+)
+
+// Log lines built by hand start with "Type::method [context] ", which JSON output splits into fields
+var logPrefixRegex = regexp.MustCompile(`(?s)^(\w+::\w+) (?:\[([^\]]*)\] )?(.*)$`)
+
+func (f LogFormat) String() string {
+	switch f {
+	case JSONFormat:
+		return "json"
+	default:
+		return "text"
+	}
+}
+
+func Format(s string) LogFormat {
+	switch strings.ToLower(s) {
+	case "json":
+		return JSONFormat
+	default:
+		return TextFormat
+	}
+}
+
+func SetLogFormat(to LogFormat) {
+	atomic.StoreInt32(&format, int32(to))
+}
+
+func logFormat() LogFormat {
+	return LogFormat(atomic.LoadInt32(&format))
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// Entry logs with contextual fields attached, rendered as key=value pairs after component in
+// text format and as keys of their own in JSON format
+type Entry struct {
+	fields []field
+}
+
+func With(key string, value interface{}) *Entry {
+	return (&Entry{}).With(key, value)
+}
+
+func WithComponent(component string) *Entry {
+	return With(FieldComponent, component)
+}
+
+// With returns a new entry with field added, leaving this one as is so it can be shared
+func (e *Entry) With(key string, value interface{}) *Entry {
+	fields := make([]field, len(e.fields), len(e.fields)+1)
+	copy(fields, e.fields)
+	return &Entry{fields: append(fields, field{key, value})}
+}
+
+// WithUserData adds a field holding user data, tagged for redaction as %ru does in formats
+func (e *Entry) WithUserData(key string, value interface{}) *Entry {
+	return e.With(key, redacted{userDataKind, value})
+}
+
+// WithMetaData adds a field holding metadata, as %rm does in formats
+func (e *Entry) WithMetaData(key string, value interface{}) *Entry {
+	return e.With(key, redacted{metaDataKind, value})
+}
+
+// WithSystemData adds a field holding system data, as %rs does in formats
+func (e *Entry) WithSystemData(key string, value interface{}) *Entry {
+	return e.With(key, redacted{systemDataKind, value})
+}
+
+func (e *Entry) Warnf(format string, v ...interface{}) {
+	e.printf(Warn, format, v...)
+}
+
+func (e *Entry) Errorf(format string, v ...interface{}) {
+	e.printf(Error, format, v...)
+}
+
+func (e *Entry) Fatalf(format string, v ...interface{}) {
+	e.printf(Fatal, format, v...)
+}
+
+func (e *Entry) Infof(format string, v ...interface{}) {
+	e.printf(Info, format, v...)
+}
+
+func (e *Entry) Verbosef(format string, v ...interface{}) {
+	e.printf(Verbose, format, v...)
+}
+
+func (e *Entry) Debugf(format string, v ...interface{}) {
+	e.printf(Debug, format, v...)
+}
+
+func (e *Entry) Tracef(format string, v ...interface{}) {
+	e.printf(Trace, format, v...)
+}
+
+func (e *Entry) StackTrace() string {
+	return StackTrace()
+}
+
+func (e *Entry) printf(at LogLevel, fmtStr string, v ...interface{}) {
+	decided, ok := levelDecided(at)
+	if decided && !ok {
+		return
+	}
+
+	msg := fmt.Sprintf(RedactFormat(fmtStr), v...)
+	if !decided && !shouldLog(at, e.fields, msg) {
+		return
+	}
+
+	if logFormat() == JSONFormat {
+		printJSON(at, e.fields, msg)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(timestamp() + " [" + at.String() + "] ")
+
+	pairs := make([]string, 0, len(e.fields))
+	for _, f := range e.fields {
+		if f.key == FieldComponent {
+			buf.WriteString(fmt.Sprint(f.value) + " ")
+			continue
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=%v", f.key, f.value))
+	}
+	if len(pairs) > 0 {
+		buf.WriteString("[" + strings.Join(pairs, " ") + "] ")
+	}
+
+	buf.WriteString(msg)
+	target.Print(buf.String())
+}
+
+// Writes a log line as JSON object, with fields in the order they were added
+func printJSON(at LogLevel, fields []field, msg string) {
+	if len(fields) == 0 {
+		if match := logPrefixRegex.FindStringSubmatch(msg); match != nil {
+			fields = []field{{FieldComponent, match[1]}}
+			if match[2] != "" {
+				fields = append(fields, field{"context", match[2]})
+			}
+			msg = match[3]
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"ts":` + jsonValue(timestamp()) + `,"level":` + jsonValue(at.String()))
+	for _, f := range fields {
+		buf.WriteString("," + jsonValue(f.key) + ":" + jsonValue(f.value))
+	}
+	buf.WriteString(`,"msg":` + jsonValue(msg) + "}")
+	target.Print(buf.String())
+}
+
+func jsonValue(value interface{}) string {
+	data, err := marshal(value)
+	if err != nil {
+		data, _ = marshal(fmt.Sprint(value))
+	}
+	return string(data)
+}
+
+// Marshals without escaping <, > and &, so redaction tags stay readable to log redaction
+func marshal(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+type redactKind int
+
+const (
+	userDataKind redactKind = iota
+	metaDataKind
+	systemDataKind
+)
+
+// Field value tagged by kind of data it holds. Like RedactFormat, only user data is wrapped in
+// tags for now, metadata and system data are logged as is
+type redacted struct {
+	kind  redactKind
+	value interface{}
+}
+
+func (r redacted) tag() string {
+	if r.kind == userDataKind && !noredact {
+		return "ud"
+	}
+	return ""
+}
+
+func (r redacted) String() string {
+	if tag := r.tag(); tag != "" {
+		return fmt.Sprintf("<%s>%v</%s>", tag, r.value, tag)
+	}
+	return fmt.Sprint(r.value)
+}
+
+func (r redacted) MarshalJSON() ([]byte, error) {
+	if r.tag() == "" {
+		return marshal(r.value)
+	}
+	return marshal(r.String())
+}
@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	l "log"
+	"strings"
+	"testing"
+)
+
+// Captures log lines written while fn runs, at Info level in given format
+func captureLog(as LogFormat, fn func()) []string {
+	var buf bytes.Buffer
+	prevTarget, prevLevel, prevFormat := target, baselevel, logFormat()
+	target = l.New(&buf, "", 0)
+	SetLogLevel(Info)
+	SetLogFormat(as)
+	defer func() {
+		target = prevTarget
+		SetLogLevel(prevLevel)
+		SetLogFormat(prevFormat)
+	}()
+
+	fn()
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines
+}
+
+func TestEntryTextFormat(t *testing.T) {
+	lines := captureLog(TextFormat, func() {
+		WithComponent("Consumer::scan").With(FieldFunction, "fn").With(FieldVb, 7).Infof("fired %d timers", 3)
+	})
+
+	if len(lines) != 1 {
+		t.Fatalf("Expected one line, got %q", lines)
+	}
+	suffix := " [Info] Consumer::scan [function=fn vb=7] fired 3 timers"
+	if !strings.HasSuffix(lines[0], suffix) {
+		t.Errorf("Expected line ending with %q, got %q", suffix, lines[0])
+	}
+}
+
+func TestEntryJSONFormat(t *testing.T) {
+	lines := captureLog(JSONFormat, func() {
+		WithComponent("Consumer::scan").With(FieldVb, 7).Errorf("unable to get store")
+	})
+
+	if len(lines) != 1 {
+		t.Fatalf("Expected one line, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], `{"ts":`) || !strings.Contains(lines[0], `"level":"Error","component":"Consumer::scan","vb":7,"msg":`) {
+		t.Errorf("Fields out of order in %s", lines[0])
+	}
+
+	line := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("Failed to unmarshal %s, err: %v", lines[0], err)
+	}
+	if line["msg"] != "unable to get store" {
+		t.Errorf("Expected msg to be set, got %v", line)
+	}
+}
+
+func TestJSONFormatSplitsLogPrefix(t *testing.T) {
+	lines := captureLog(JSONFormat, func() {
+		Infof("Producer::Serve [fn:3] started %d consumers", 2)
+	})
+
+	line := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatalf("Failed to unmarshal %s, err: %v", lines[0], err)
+	}
+	if line[FieldComponent] != "Producer::Serve" || line["context"] != "fn:3" || line["msg"] != "started 2 consumers" {
+		t.Errorf("Expected log prefix split into fields, got %v", line)
+	}
+}
+
+func TestEntryRedaction(t *testing.T) {
+	entry := WithComponent("Consumer::run").
+		WithUserData("doc", "ssn_1").
+		WithMetaData("bucket", "cards").
+		WithSystemData("host", "10.1.1.1")
+
+	lines := captureLog(TextFormat, func() { entry.Infof("processed") })
+	if !strings.Contains(lines[0], "[doc=<ud>ssn_1</ud> bucket=cards host=10.1.1.1]") {
+		t.Errorf("Expected only user data tagged, got %q", lines[0])
+	}
+
+	lines = captureLog(JSONFormat, func() { entry.Infof("processed") })
+	if !strings.Contains(lines[0], `"doc":"<ud>ssn_1</ud>","bucket":"cards","host":"10.1.1.1"`) {
+		t.Errorf("Expected only user data tagged, got %s", lines[0])
+	}
+
+	noredact = true
+	defer func() { noredact = false }()
+
+	lines = captureLog(TextFormat, func() { entry.Infof("processed") })
+	if !strings.Contains(lines[0], "[doc=ssn_1 bucket=cards host=10.1.1.1]") {
+		t.Errorf("Expected nothing tagged with redaction off, got %q", lines[0])
+	}
+}
+
+func TestEntryLevel(t *testing.T) {
+	lines := captureLog(TextFormat, func() {
+		WithComponent("Consumer::scan").Debugf("not logged")
+		WithComponent("Consumer::scan").Warnf("logged")
+	})
+
+	if len(lines) != 1 || !strings.HasSuffix(lines[0], "logged") {
+		t.Errorf("Expected only warning to be logged, got %q", lines)
+	}
+}
+
+func TestWithLeavesEntryAsIs(t *testing.T) {
+	base := WithComponent("Consumer::scan")
+	base.With(FieldVb, 1)
+	base.With(FieldVb, 2)
+
+	if len(base.fields) != 1 {
+		t.Errorf("Expected base entry to keep one field, got %v", base.fields)
+	}
+}
@@ -0,0 +1,298 @@
+package producer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/util"
+)
+
+const (
+	appLogSinkQueueSize     = 10000
+	appLogSinkBatchSize     = 100
+	appLogSinkFlushInterval = time.Second
+	appLogSinkRetries       = 3
+	appLogSinkTimeout       = 10 * time.Second
+)
+
+// Syslog facility application log is sent under, user-level messages
+const syslogFacilityUser = 1
+
+// Name of this node records forwarded carry
+var appLogHostname, _ = os.Hostname()
+
+type appLogRecord struct {
+	Function  string    `json:"function"`
+	Level     string    `json:"level"`
+	Message   string    `json:"msg"`
+	Node      string    `json:"node"`
+	Timestamp time.Time `json:"ts"`
+}
+
+// appLogSink forwards application log of a function to a collector, besides its file. Records
+// are queued up to a bound and dropped when the collector can't keep up, so that a slow or
+// unreachable collector never holds up the handler
+type appLogSink struct {
+	rawURL    string
+	name      string // URL without password, reported in stats
+	queue     chan *appLogRecord
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	batchSize int
+	send      func(batch []*appLogRecord) (int, error)
+	close     func()
+
+	dropped uint64
+	failed  uint64
+	sent    uint64
+}
+
+// Parses URL of a sink or trace collector, leaving URL out of error as it may carry password
+func parseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if uerr, ok := err.(*url.Error); ok {
+		return nil, uerr.Err
+	}
+	return u, err
+}
+
+// Opens a sink for URLs of form syslog+unix:///dev/log, syslog+udp://host:514 or
+// http(s)://host/path, the latter receiving batches of records as JSON arrays
+func newAppLogSink(rawURL, appName string) (*appLogSink, error) {
+	u, err := parseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	sink := &appLogSink{
+		rawURL:    rawURL,
+		queue:     make(chan *appLogRecord, appLogSinkQueueSize),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+		batchSize: appLogSinkBatchSize,
+		close:     func() {},
+	}
+
+	switch u.Scheme {
+	case "syslog+unix", "syslog+udp":
+		w := &syslogWriter{hostname: appLogHostname, tag: "eventing-" + appName}
+		if u.Scheme == "syslog+unix" {
+			w.network, w.addr, w.local = "unixgram", u.Path, true
+		} else {
+			w.network, w.addr = "udp", u.Host
+		}
+		sink.batchSize = 1
+		sink.send = w.send
+		sink.close = w.close
+
+	case "http", "https":
+		h := &httpSink{url: rawURL, client: &http.Client{Timeout: appLogSinkTimeout}, stopCh: sink.stopCh}
+		sink.send = h.send
+
+	default:
+		return nil, fmt.Errorf("unsupported app log sink: %s", u.Scheme)
+	}
+
+	sink.name = util.URLWithoutPassword(u)
+
+	go sink.run()
+	return sink, nil
+}
+
+func (s *appLogSink) write(record *appLogRecord) {
+	select {
+	case s.queue <- record:
+	default:
+		atomic.AddUint64(&s.dropped, 1)
+	}
+}
+
+func (s *appLogSink) run() {
+	logPrefix := "appLogSink::run"
+	defer close(s.doneCh)
+
+	ticker := time.NewTicker(appLogSinkFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*appLogRecord, 0, s.batchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		sent, err := s.send(batch)
+		atomic.AddUint64(&s.sent, uint64(sent))
+		if err != nil {
+			atomic.AddUint64(&s.failed, uint64(len(batch)-sent))
+			logging.Debugf("%s Sink: %s failed to send %d records, err: %v", logPrefix, s.name, len(batch)-sent, err)
+		}
+		batch = make([]*appLogRecord, 0, s.batchSize)
+	}
+
+	for {
+		select {
+		case record := <-s.queue:
+			batch = append(batch, record)
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+
+		case <-s.stopCh:
+			for {
+				select {
+				case record := <-s.queue:
+					batch = append(batch, record)
+					continue
+				default:
+				}
+				break
+			}
+			flush()
+			s.close()
+			return
+		}
+	}
+}
+
+// Stops sink once records queued so far are sent, or given up on
+func (s *appLogSink) stop() {
+	close(s.stopCh)
+	<-s.doneCh
+}
+
+func (s *appLogSink) stats() *common.AppLogSinkStats {
+	return &common.AppLogSinkStats{
+		Dropped: atomic.LoadUint64(&s.dropped),
+		Failed:  atomic.LoadUint64(&s.failed),
+		Sent:    atomic.LoadUint64(&s.sent),
+	}
+}
+
+// syslogWriter sends records to syslog in the format log/syslog does, which isn't available
+// on all platforms. Connection is made again if it breaks
+type syslogWriter struct {
+	network  string
+	addr     string
+	local    bool
+	hostname string
+	tag      string
+	conn     net.Conn
+}
+
+func (w *syslogWriter) send(batch []*appLogRecord) (int, error) {
+	for i, record := range batch {
+		if err := w.write(record); err != nil {
+			return i, err
+		}
+	}
+	return len(batch), nil
+}
+
+func (w *syslogWriter) write(record *appLogRecord) error {
+	var msg string
+	priority := syslogFacilityUser*8 + syslogSeverity(record.Level)
+	if w.local {
+		msg = fmt.Sprintf("<%d>%s %s[%d]: %s", priority, record.Timestamp.Format(time.Stamp),
+			w.tag, os.Getpid(), record.Message)
+	} else {
+		msg = fmt.Sprintf("<%d>%s %s %s[%d]: %s", priority, record.Timestamp.Format(time.RFC3339),
+			w.hostname, w.tag, os.Getpid(), record.Message)
+	}
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if w.conn, err = net.DialTimeout(w.network, w.addr, appLogSinkTimeout); err != nil {
+				w.conn = nil
+				continue
+			}
+		}
+		w.conn.SetWriteDeadline(time.Now().Add(appLogSinkTimeout))
+		if _, err = w.conn.Write([]byte(msg)); err == nil {
+			return nil
+		}
+		w.close()
+	}
+	return err
+}
+
+func (w *syslogWriter) close() {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+func syslogSeverity(level string) int {
+	switch strings.ToUpper(level) {
+	case "ERROR":
+		return 3
+	case "WARN", "WARNING":
+		return 4
+	case "DEBUG", "TRACE":
+		return 7
+	default:
+		return 6
+	}
+}
+
+// httpSink POSTs batches of records, retrying with backoff before giving up on a batch
+type httpSink struct {
+	url    string
+	client *http.Client
+	stopCh chan struct{}
+}
+
+func (h *httpSink) send(batch []*appLogRecord) (int, error) {
+	payload, err := json.Marshal(batch)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = h.post(payload); err != nil {
+		return 0, err
+	}
+	return len(batch), nil
+}
+
+func (h *httpSink) post(payload []byte) (err error) {
+	backoff := time.Second
+	for attempt := 0; attempt < appLogSinkRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-h.stopCh:
+				return err
+			}
+		}
+
+		var res *http.Response
+		res, err = h.client.Post(h.url, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			continue
+		}
+		io.Copy(ioutil.Discard, res.Body)
+		res.Body.Close()
+
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("collector responded with status: %s", res.Status)
+	}
+	return err
+}
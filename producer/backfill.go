@@ -0,0 +1,133 @@
+package producer
+
+import (
+	"fmt"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/timers"
+	"github.com/couchbase/eventing/util"
+)
+
+// Records high seq nos of source bucket at the time of deployment, backfill job
+// is considered caught up once checkpointed seq nos for all vbuckets reach them.
+// If blob was already written, either by another eventing node or before a
+// rebalance, the persisted seq nos are reused
+func (p *Producer) initBackfillJob() error {
+	logPrefix := "Producer::initBackfillJob"
+
+	blob := &backfillJobBlob{}
+	err := util.Retry(util.NewFixedBackoff(bucketOpRetryInterval), &p.retryCount, initBackfillJobCallback, p, blob)
+	if err == common.ErrRetryTimeout {
+		logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+		return err
+	}
+
+	p.backfillJob = blob
+
+	p.backfillRWMutex.Lock()
+	p.backfillStatus.Completed = blob.Completed
+	p.backfillStatus.StartTimestamp = blob.StartTimestamp
+	p.backfillRWMutex.Unlock()
+
+	logging.Infof("%s [%s:%d] Backfill job started at: %s completed: %t seq nos: %v",
+		logPrefix, p.appName, p.LenRunningConsumers(), blob.StartTimestamp, blob.Completed, blob.SeqNos)
+	return nil
+}
+
+func (p *Producer) updateBackfillJobStatus() error {
+	logPrefix := "Producer::updateBackfillJobStatus"
+
+	if !p.handlerConfig.BackfillJob || p.backfillJob == nil {
+		return nil
+	}
+
+	var target, processed uint64
+
+	p.seqsNoProcessedRWMutex.RLock()
+	for vb, seqNo := range p.backfillJob.SeqNos {
+		target += seqNo
+
+		seqNoProcessed := uint64(0)
+		if val, ok := p.seqsNoProcessed[vb]; ok && val > 0 {
+			seqNoProcessed = uint64(val)
+		}
+
+		if seqNoProcessed > seqNo {
+			seqNoProcessed = seqNo
+		}
+		processed += seqNoProcessed
+	}
+	p.seqsNoProcessedRWMutex.RUnlock()
+
+	progress := float64(100)
+	if target > 0 {
+		progress = float64(processed) * 100 / float64(target)
+	}
+
+	caughtUp := processed == target
+	drainingTimers := false
+
+	if caughtUp && p.handlerConfig.BackfillDrainTimers && p.app.UsingTimer {
+		drained, err := p.timersDrained()
+		if err == common.ErrRetryTimeout {
+			logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+			return err
+		}
+		drainingTimers = !drained
+	}
+
+	p.backfillRWMutex.Lock()
+	p.backfillStatus.Progress = progress
+	p.backfillStatus.DrainingTimers = drainingTimers
+	completed := p.backfillStatus.Completed
+	p.backfillRWMutex.Unlock()
+
+	if !caughtUp || drainingTimers || completed {
+		return nil
+	}
+
+	// All eventing nodes observe the job catching up, only the one that gets
+	// to flip the flag in metadata bucket triggers undeploy
+	var markedComplete bool
+	err := util.Retry(util.NewFixedBackoff(bucketOpRetryInterval), &p.retryCount, completeBackfillJobCallback, p, &markedComplete)
+	if err == common.ErrRetryTimeout {
+		logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+		return err
+	}
+
+	p.backfillRWMutex.Lock()
+	p.backfillStatus.Completed = true
+	p.backfillRWMutex.Unlock()
+
+	if markedComplete {
+		logging.Infof("%s [%s:%d] Backfill job caught up with seq nos recorded at: %s, undeploying",
+			logPrefix, p.appName, p.LenRunningConsumers(), p.backfillJob.StartTimestamp)
+		p.WriteAppLog(fmt.Sprintf("Backfill job started at %s completed, undeploying function", p.backfillJob.StartTimestamp))
+
+		go p.superSup.CompleteBackfillJob(p.appName)
+	}
+
+	return nil
+}
+
+func (p *Producer) timersDrained() (bool, error) {
+	logPrefix := "Producer::timersDrained"
+
+	for vb := 0; vb < p.numVbuckets; vb++ {
+		var span timers.Span
+		var found bool
+
+		err := util.Retry(util.NewFixedBackoff(bucketOpRetryInterval), &p.retryCount, getTimerSpanCallback, p, vb, &span, &found)
+		if err == common.ErrRetryTimeout {
+			logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+			return false, err
+		}
+
+		if found && !span.Drained() {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -3,10 +3,13 @@ package producer
 import (
 	"fmt"
 	"net"
+	"sort"
+	"time"
 
 	"github.com/couchbase/eventing/common"
 	"github.com/couchbase/eventing/dcp"
 	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/timers"
 	"github.com/couchbase/eventing/util"
 	"github.com/couchbase/gocb"
 )
@@ -291,6 +294,231 @@ var deleteOpCallback = func(args ...interface{}) error {
 	return err
 }
 
+var quarantineEventCallback = func(args ...interface{}) error {
+	logPrefix := "Producer::quarantineEventCallback"
+
+	p := args[0].(*Producer)
+	vbKey := args[1].(common.Key)
+	seqNo := args[2].(uint64)
+
+	if p.isTerminateRunning {
+		return nil
+	}
+
+	if p.metadataBucketHandle == nil {
+		logging.Errorf("%s [%s:%d] Bucket handle not initialized",
+			logPrefix, p.appName, p.LenRunningConsumers())
+		return nil
+	}
+
+	var blob struct {
+		QuarantinedSeqNos []uint64 `json:"quarantined_seq_nos"`
+	}
+
+	cas, err := p.metadataBucketHandle.Get(vbKey.Raw(), &blob)
+	if gocb.IsKeyNotFoundError(err) || err == gocb.ErrShutdown {
+		return nil
+	}
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Key: %ru failed to read checkpoint blob, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), vbKey.Raw(), err)
+		return err
+	}
+
+	for _, quarantinedSeqNo := range blob.QuarantinedSeqNos {
+		if quarantinedSeqNo == seqNo {
+			return nil
+		}
+	}
+
+	// Compared against cas, as eventing-consumer owning the vbucket checkpoints it concurrently
+	_, err = p.metadataBucketHandle.MutateIn(vbKey.Raw(), cas, uint32(0)).
+		UpsertEx("quarantined_seq_nos", append(blob.QuarantinedSeqNos, seqNo), gocb.SubdocFlagCreatePath).
+		Execute()
+	if err == gocb.ErrShutdown {
+		return nil
+	}
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Key: %ru failed to record quarantined seq no: %d, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), vbKey.Raw(), seqNo, err)
+	}
+	return err
+}
+
+var initBackfillJobCallback = func(args ...interface{}) error {
+	logPrefix := "Producer::initBackfillJobCallback"
+
+	p := args[0].(*Producer)
+	blob := args[1].(*backfillJobBlob)
+
+	if p.metadataBucketHandle == nil {
+		logging.Errorf("%s [%s:%d] Bucket handle not initialized",
+			logPrefix, p.appName, p.LenRunningConsumers())
+		return nil
+	}
+
+	key := p.AddMetadataPrefix(p.appName + "::" + backfillJobKey).Raw()
+
+	_, err := p.metadataBucketHandle.Get(key, blob)
+	if err == nil || err == gocb.ErrShutdown {
+		return nil
+	}
+
+	if !gocb.IsKeyNotFoundError(err) {
+		logging.Errorf("%s [%s:%d] Failed to read backfill job blob, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+		return err
+	}
+
+	seqNos, err := util.BucketSeqnos(p.NsServerHostPort(), "default", p.handlerConfig.SourceBucket)
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to fetch high seq nos for bucket: %s, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), p.handlerConfig.SourceBucket, err)
+		return err
+	}
+
+	blob.SeqNos = seqNos
+	blob.StartTimestamp = time.Now().Format(time.RFC3339)
+
+	// Another eventing node might have recorded the seq nos in the meantime,
+	// in which case its copy wins
+	_, err = p.metadataBucketHandle.Insert(key, blob, 0)
+	if gocb.IsKeyExistsError(err) {
+		_, err = p.metadataBucketHandle.Get(key, blob)
+	}
+
+	if err == gocb.ErrShutdown {
+		return nil
+	} else if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to write backfill job blob, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+	}
+	return err
+}
+
+var completeBackfillJobCallback = func(args ...interface{}) error {
+	logPrefix := "Producer::completeBackfillJobCallback"
+
+	p := args[0].(*Producer)
+	markedComplete := args[1].(*bool)
+
+	*markedComplete = false
+
+	if p.metadataBucketHandle == nil {
+		logging.Errorf("%s [%s:%d] Bucket handle not initialized",
+			logPrefix, p.appName, p.LenRunningConsumers())
+		return nil
+	}
+
+	key := p.AddMetadataPrefix(p.appName + "::" + backfillJobKey).Raw()
+
+	var blob backfillJobBlob
+	cas, err := p.metadataBucketHandle.Get(key, &blob)
+	if gocb.IsKeyNotFoundError(err) || err == gocb.ErrShutdown {
+		return nil
+	} else if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to read backfill job blob, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+		return err
+	}
+
+	if blob.Completed {
+		return nil
+	}
+
+	blob.Completed = true
+	_, err = p.metadataBucketHandle.Replace(key, &blob, cas, 0)
+	if err == gocb.ErrShutdown {
+		return nil
+	} else if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to mark backfill job as complete, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+		return err
+	}
+
+	*markedComplete = true
+	return nil
+}
+
+var completeTimerCarryOverCallback = func(args ...interface{}) error {
+	logPrefix := "Producer::completeTimerCarryOverCallback"
+
+	p := args[0].(*Producer)
+	vbs := args[1].([]uint16)
+
+	if p.metadataBucketHandle == nil {
+		logging.Errorf("%s [%s:%d] Bucket handle not initialized",
+			logPrefix, p.appName, p.LenRunningConsumers())
+		return nil
+	}
+
+	key := p.timerCarryOverKey().Raw()
+
+	var blob timerCarryOverBlob
+	cas, err := p.metadataBucketHandle.Get(key, &blob)
+	if gocb.IsKeyNotFoundError(err) || err == gocb.ErrShutdown {
+		return nil
+	} else if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to read timer carry over blob, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+		return err
+	}
+
+	carried := make(map[uint16]struct{})
+	for _, vb := range blob.CarriedVbs {
+		carried[vb] = struct{}{}
+	}
+	for _, vb := range vbs {
+		carried[vb] = struct{}{}
+	}
+
+	// Eventing node carrying over the last of vbuckets removes the blob, so that timers
+	// aren't looked for under the old prefix again
+	if len(carried) >= p.numVbuckets {
+		_, err = p.metadataBucketHandle.Remove(key, cas)
+	} else {
+		blob.CarriedVbs = make([]uint16, 0, len(carried))
+		for vb := range carried {
+			blob.CarriedVbs = append(blob.CarriedVbs, vb)
+		}
+		sort.Sort(util.Uint16Slice(blob.CarriedVbs))
+		_, err = p.metadataBucketHandle.Replace(key, &blob, cas, 0)
+	}
+
+	if gocb.IsKeyNotFoundError(err) || err == gocb.ErrShutdown {
+		return nil
+	} else if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to record vbuckets carried over, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+		return err
+	}
+	return nil
+}
+
+var getTimerSpanCallback = func(args ...interface{}) error {
+	logPrefix := "Producer::getTimerSpanCallback"
+
+	p := args[0].(*Producer)
+	vb := args[1].(int)
+	span := args[2].(*timers.Span)
+	found := args[3].(*bool)
+
+	key := timers.SpanLocator(p.GetMetadataPrefix(), vb)
+
+	_, err := p.metadataBucketHandle.Get(key, span)
+	if gocb.IsKeyNotFoundError(err) || err == gocb.ErrShutdown {
+		*found = false
+		return nil
+	} else if err != nil {
+		logging.Errorf("%s [%s:%d] vb: %d failed to read timer span, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), vb, err)
+		return err
+	}
+
+	*found = true
+	return nil
+}
+
 var checkIfQueuesAreDrained = func(args ...interface{}) error {
 	p := args[0].(*Producer)
 
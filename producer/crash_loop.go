@@ -0,0 +1,109 @@
+package producer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/util"
+)
+
+// Records crash of an eventing-consumer. Events its worker threads were executing when it
+// crashed are blamed for it and once the same event has crashed eventing-consumer
+// crash_loop_threshold times, it's either skipped or function is failed as per
+// crash_loop_action, unless it's left to respawn the worker as before. Crashes without
+// any event being executed are counted per worker over crash_loop_window
+func (p *Producer) recordWorkerCrash(c common.EventingConsumer) {
+	logPrefix := "Producer::recordWorkerCrash"
+
+	workerName := c.ConsumerName()
+	suspects := c.CrashSuspects()
+	threshold := p.handlerConfig.CrashLoopThreshold
+	action := p.handlerConfig.CrashLoopAction
+
+	now := time.Now()
+	entry := &common.CrashEntry{
+		Suspects:   suspects,
+		Timestamp:  now.Format(time.RFC3339),
+		WorkerName: workerName,
+	}
+
+	p.crashRWMutex.Lock()
+	defer p.crashRWMutex.Unlock()
+
+	p.workerCrashCounter++
+
+	window := time.Duration(p.handlerConfig.CrashLoopWindow) * time.Second
+	timestamps := []time.Time{now}
+	for _, ts := range p.workerCrashTimestamps[workerName] {
+		if now.Sub(ts) <= window {
+			timestamps = append(timestamps, ts)
+		}
+	}
+	p.workerCrashTimestamps[workerName] = timestamps
+
+	var quarantined []*common.CrashSuspect
+	for _, suspect := range suspects {
+		key := fmt.Sprintf("%d:%d", suspect.Vbucket, suspect.SeqNo)
+		p.eventCrashCounts[key]++
+		suspect.Count = p.eventCrashCounts[key]
+
+		if threshold > 0 && suspect.Count >= threshold {
+			quarantined = append(quarantined, suspect)
+		}
+	}
+
+	switch {
+	case threshold == 0 || p.crashLoopFailed || action == crashLoopActionRespawn:
+
+	case action == crashLoopActionFail && (len(quarantined) > 0 || len(timestamps) >= threshold):
+		entry.Action = crashActionFailed
+		p.crashLoopFailed = true
+
+		logging.Errorf("%s [%s:%d] Worker: %s crashed %d times in last %v, failing function",
+			logPrefix, p.appName, p.LenRunningConsumers(), workerName, len(timestamps), window)
+		p.WriteAppLog(fmt.Sprintf("Worker %s crashed %d times in last %v, undeploying function", workerName, len(timestamps), window))
+
+		go p.superSup.FailFunction(p.appName)
+
+	case len(quarantined) > 0:
+		entry.Action = crashActionSkipped
+
+		for _, suspect := range quarantined {
+			if _, ok := p.quarantinedEvents[suspect.Vbucket]; !ok {
+				p.quarantinedEvents[suspect.Vbucket] = make(map[uint64]struct{})
+			}
+			p.quarantinedEvents[suspect.Vbucket][suspect.SeqNo] = struct{}{}
+			go p.persistQuarantinedEvent(suspect.Vbucket, suspect.SeqNo)
+			delete(p.eventCrashCounts, fmt.Sprintf("%d:%d", suspect.Vbucket, suspect.SeqNo))
+
+			logging.Errorf("%s [%s:%d] Worker: %s vb: %d seqNo: %d key: %ru crashed worker %d times, skipping it",
+				logPrefix, p.appName, p.LenRunningConsumers(), workerName, suspect.Vbucket, suspect.SeqNo, suspect.Key, suspect.Count)
+			p.WriteAppLog(fmt.Sprintf("Skipping mutation of key %s in vbucket %d at seq no %d as it crashed worker %d times",
+				suspect.Key, suspect.Vbucket, suspect.SeqNo, suspect.Count))
+		}
+	}
+
+	p.crashHistory = append(p.crashHistory, entry)
+	if len(p.crashHistory) > crashHistorySize {
+		p.crashHistory = p.crashHistory[len(p.crashHistory)-crashHistorySize:]
+	}
+
+	logging.Infof("%s [%s:%d] Worker: %s crashed, crashes in window: %d suspects: %d action: %q",
+		logPrefix, p.appName, p.LenRunningConsumers(), workerName, len(timestamps), len(suspects), entry.Action)
+}
+
+// Records quarantined event in checkpoint blob of its vbucket, so that it continues to be
+// skipped by whichever eventing-consumer owns the vbucket next, across restarts
+func (p *Producer) persistQuarantinedEvent(vb uint16, seqNo uint64) {
+	logPrefix := "Producer::persistQuarantinedEvent"
+
+	vbKey := p.AddMetadataPrefix(fmt.Sprintf("%s::vb::%d", p.appName, vb))
+
+	err := util.Retry(util.NewFixedBackoff(bucketOpRetryInterval), &p.retryCount, quarantineEventCallback, p, vbKey, seqNo)
+	if err == common.ErrRetryTimeout {
+		logging.Errorf("%s [%s:%d] vb: %d seqNo: %d Exiting due to timeout",
+			logPrefix, p.appName, p.LenRunningConsumers(), vb, seqNo)
+	}
+}
@@ -32,6 +32,19 @@ const (
 	// for instantiating V8 Debugger instance
 	startDebuggerFlag    = "startDebugger"
 	debuggerInstanceAddr = "debuggerInstAddr"
+
+	// KV blob suffix capturing seq nos a backfill job has to process
+	backfillJobKey = "backfill_job"
+
+	// Crashes of eventing-consumers retained for status API
+	crashHistorySize = 256
+
+	crashLoopActionFail    = "fail"
+	crashLoopActionRespawn = "respawn"
+
+	// Actions recorded against a crash once crash loop threshold is reached
+	crashActionFailed  = "failed"
+	crashActionSkipped = "skipped"
 )
 
 type appStatus uint16
@@ -40,6 +53,22 @@ const (
 	appUndeployed appStatus = iota
 )
 
+// Persisted in metadata bucket so that backfill target survives rebalance and
+// remains same across all eventing nodes
+type backfillJobBlob struct {
+	Completed      bool     `json:"completed"`
+	SeqNos         []uint64 `json:"seq_nos"`
+	StartTimestamp string   `json:"start_timestamp"`
+}
+
+// Persisted in metadata bucket when a function carrying over timers is undeployed, to locate
+// its timers on next deploy even if its metadata prefix changes
+type timerCarryOverBlob struct {
+	CarriedVbs          []uint16 `json:"carried_vbs,omitempty"`
+	Prefix              string   `json:"prefix"`
+	UndeployedTimestamp string   `json:"undeployed_timestamp"`
+}
+
 type startDebugBlob struct {
 	StartDebug bool `json:"start_debug"`
 }
@@ -49,8 +78,18 @@ type Producer struct {
 	appName                string
 	app                    *common.AppConfig
 	auth                   string
+	backfillJob            *backfillJobBlob
+	backfillStatus         *common.BackfillJobStatus // Access controlled by backfillRWMutex
+	backfillRWMutex        *sync.RWMutex
 	cfgData                string
 	cleanupTimers          bool
+	crashHistory           []*common.CrashEntry // Access controlled by crashRWMutex
+	crashLoopFailed        bool                 // Access controlled by crashRWMutex
+	crashRWMutex           *sync.RWMutex
+	eventCrashCounts       map[string]int                 // Access controlled by crashRWMutex
+	quarantinedEvents      map[uint16]map[uint64]struct{} // Access controlled by crashRWMutex
+	workerCrashTimestamps  map[string][]time.Time         // Access controlled by crashRWMutex
+	workerCrashCounter     uint64
 	handleV8ConsumerMutex  *sync.Mutex // controls access to Producer.handleV8Consumer
 	isBootstrapping        bool
 	isPlannerRunning       bool
@@ -71,6 +110,9 @@ type Producer struct {
 	stopChClosed           bool
 	stopProducerCh         chan struct{}
 	superSup               common.EventingSuperSup
+	timerCheckMutex        *sync.Mutex // Allows one timer consistency check at a time
+	timersCarriedOver      uint64
+	timersMissingCallback  uint64
 	trapEvent              bool
 	debuggerToken          string
 	uuid                   string
@@ -87,9 +129,27 @@ type Producer struct {
 	appLogPath     string
 	appLogMaxSize  int64
 	appLogMaxFiles int64
+	appLogMaxAge   int64
+	appLogCompress bool
+	appLogInterval string
 	appLogRotation bool
 	appLogWriter   io.WriteCloser
 
+	// Sinks application log is forwarded to, as per app_log_sinks setting of function if set,
+	// or else of global config. Nil appLogSinkURLs means function doesn't set it
+	appLogSinkURLs        []string
+	defaultAppLogSinkURLs []string
+	appLogSinks           []*appLogSink
+	appLogSinksRWMutex    *sync.RWMutex
+
+	// Events are traced at traceSampleRate, float64 bits accessed atomically, and their traces
+	// exported to traceExportURL or else into file at tracePath
+	traceSampleRate uint64
+	traceExportURL  string
+	tracePath       string
+	tracer          *eventTracer
+	tracerRWMutex   *sync.RWMutex
+
 	// Chan used to signal if Eventing.Producer has finished bootstrap
 	// i.e. started up all it's child routines
 	bootstrapFinishCh chan struct{}
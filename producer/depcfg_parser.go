@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/couchbase/eventing/common"
 	"github.com/couchbase/eventing/gen/flatbuf/cfg"
 	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/timers"
 	"github.com/couchbase/eventing/util"
 )
 
@@ -84,6 +86,12 @@ func (p *Producer) parseDepcfg() error {
 
 	// Handler related configurations
 
+	if val, ok := settings["carry_over_timers"]; ok {
+		p.handlerConfig.CarryOverTimers = val.(bool)
+	} else {
+		p.handlerConfig.CarryOverTimers = false
+	}
+
 	if val, ok := settings["checkpoint_interval"]; ok {
 		p.handlerConfig.CheckpointInterval = int(val.(float64))
 	} else {
@@ -96,18 +104,79 @@ func (p *Producer) parseDepcfg() error {
 		p.handlerConfig.CleanupTimers = false
 	}
 
+	if val, ok := settings["backfill_job"]; ok {
+		p.handlerConfig.BackfillJob = val.(bool)
+	} else {
+		p.handlerConfig.BackfillJob = false
+	}
+
+	if val, ok := settings["backfill_drain_timers"]; ok {
+		p.handlerConfig.BackfillDrainTimers = val.(bool)
+	} else {
+		p.handlerConfig.BackfillDrainTimers = false
+	}
+
 	if val, ok := settings["cpp_worker_thread_count"]; ok {
 		p.handlerConfig.CPPWorkerThrCount = int(val.(float64))
 	} else {
 		p.handlerConfig.CPPWorkerThrCount = 2
 	}
 
+	if val, ok := settings["crash_loop_action"]; ok {
+		p.handlerConfig.CrashLoopAction = val.(string)
+	} else {
+		p.handlerConfig.CrashLoopAction = "respawn"
+	}
+
+	if val, ok := settings["crash_loop_threshold"]; ok {
+		p.handlerConfig.CrashLoopThreshold = int(val.(float64))
+	} else {
+		p.handlerConfig.CrashLoopThreshold = 5
+	}
+
+	if val, ok := settings["crash_loop_window"]; ok {
+		p.handlerConfig.CrashLoopWindow = int(val.(float64))
+	} else {
+		p.handlerConfig.CrashLoopWindow = 300
+	}
+
+	if val, ok := settings["deliver_rollback_event"]; ok {
+		p.handlerConfig.DeliverRollbackEvent = val.(bool)
+	} else {
+		p.handlerConfig.DeliverRollbackEvent = false
+	}
+
 	if val, ok := settings["dcp_stream_boundary"]; ok {
 		p.handlerConfig.StreamBoundary = common.DcpStreamBoundary(val.(string))
 	} else {
 		p.handlerConfig.StreamBoundary = common.DcpStreamBoundary("everything")
 	}
 
+	p.handlerConfig.StreamBoundarySeqNos = make(map[uint16]uint64)
+	if val, ok := settings["dcp_stream_boundary_seqnos"]; ok {
+		for vb, seqNo := range val.(map[string]interface{}) {
+			vbNo, err := strconv.Atoi(vb)
+			if err != nil {
+				logging.Errorf("%s [%s] Skipping invalid vb: %s in dcp_stream_boundary_seqnos, err: %v",
+					logPrefix, p.appName, vb, err)
+				continue
+			}
+			p.handlerConfig.StreamBoundarySeqNos[uint16(vbNo)] = uint64(seqNo.(float64))
+		}
+	}
+
+	if val, ok := settings["dcp_stream_boundary_timestamp"]; ok {
+		ts, err := time.Parse(time.RFC3339, val.(string))
+		if err != nil {
+			logging.Errorf("%s [%s] Failed to parse dcp_stream_boundary_timestamp: %s, err: %v",
+				logPrefix, p.appName, val.(string), err)
+		} else {
+			p.handlerConfig.StreamBoundaryTimestamp = ts.UnixNano()
+		}
+	} else {
+		p.handlerConfig.StreamBoundaryTimestamp = 0
+	}
+
 	if val, ok := settings["deadline_timeout"]; ok {
 		p.handlerConfig.SocketTimeout = int(val.(float64))
 	} else {
@@ -227,12 +296,36 @@ func (p *Producer) parseDepcfg() error {
 		p.handlerConfig.ExecuteTimerRoutineCount = 3
 	}
 
+	if val, ok := settings["timer_check_interval"]; ok {
+		p.handlerConfig.TimerCheckInterval = int(val.(float64))
+	} else {
+		p.handlerConfig.TimerCheckInterval = 0
+	}
+
+	if val, ok := settings["timer_check_repair"]; ok {
+		p.handlerConfig.TimerCheckRepair = val.(bool)
+	} else {
+		p.handlerConfig.TimerCheckRepair = false
+	}
+
 	if val, ok := settings["timer_context_size"]; ok {
 		p.handlerConfig.TimerContextSize = int64(val.(float64))
 	} else {
 		p.handlerConfig.TimerContextSize = 1024
 	}
 
+	if val, ok := settings["timer_resolution"]; ok {
+		p.handlerConfig.TimerResolution = int64(val.(float64))
+	} else {
+		p.handlerConfig.TimerResolution = timers.Resolution
+	}
+
+	if val, ok := settings["timer_scan_vb_limit"]; ok {
+		p.handlerConfig.TimerScanVbLimit = int(val.(float64))
+	} else {
+		p.handlerConfig.TimerScanVbLimit = 500
+	}
+
 	if val, ok := settings["timer_storage_routine_count"]; ok {
 		p.handlerConfig.TimerStorageRoutineCount = int(val.(float64))
 	} else {
@@ -290,9 +383,11 @@ func (p *Producer) parseDepcfg() error {
 	if val, ok := settings["app_log_dir"]; ok {
 		os.MkdirAll(val.(string), 0755)
 		p.appLogPath = fmt.Sprintf("%s/%s", val.(string), p.appName)
+		p.tracePath = fmt.Sprintf("%s/%s_trace.json", val.(string), p.appName)
 	} else {
 		os.MkdirAll(p.processConfig.EventingDir, 0755)
 		p.appLogPath = fmt.Sprintf("%s/%s.log", p.processConfig.EventingDir, p.appName)
+		p.tracePath = fmt.Sprintf("%s/%s_trace.json", p.processConfig.EventingDir, p.appName)
 	}
 
 	if val, ok := settings["app_log_max_size"]; ok {
@@ -307,6 +402,42 @@ func (p *Producer) parseDepcfg() error {
 		p.appLogMaxFiles = int64(10)
 	}
 
+	if val, ok := settings["app_log_compress"]; ok {
+		p.appLogCompress = val.(bool)
+	} else {
+		p.appLogCompress = false
+	}
+
+	if val, ok := settings["app_log_max_age"]; ok {
+		p.appLogMaxAge = int64(val.(float64))
+	} else {
+		p.appLogMaxAge = 0
+	}
+
+	if val, ok := settings["app_log_rotation_interval"]; ok {
+		p.appLogInterval = val.(string)
+	} else {
+		p.appLogInterval = appLogRotateNone
+	}
+
+	if val, ok := settings["app_log_sinks"]; ok {
+		p.appLogSinkURLs = util.ToStringArray(val)
+	} else {
+		p.appLogSinkURLs = nil
+	}
+
+	if val, ok := settings["trace_sample_rate"]; ok {
+		p.setTraceSampleRate(val.(float64))
+	} else {
+		p.setTraceSampleRate(0)
+	}
+
+	if val, ok := settings["trace_export_url"]; ok {
+		p.traceExportURL = val.(string)
+	} else {
+		p.traceExportURL = ""
+	}
+
 	if val, ok := settings["enable_applog_rotation"]; ok {
 		p.appLogRotation = val.(bool)
 	} else {
@@ -0,0 +1,364 @@
+package producer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/util"
+)
+
+const (
+	traceQueueSize     = 10000
+	traceBatchSize     = 100
+	traceFlushInterval = time.Second
+)
+
+// OTLP span kind of spans exported, as they're all within eventing
+const otlpSpanKindInternal = 1
+
+// traceSpan is time an event spent in one stage of its way to its handler being done
+type traceSpan struct {
+	Name       string `json:"name"`
+	Start      int64  `json:"start"`
+	End        int64  `json:"end"`
+	DurationUs int64  `json:"duration_us"`
+}
+
+type traceRecord struct {
+	*common.EventTrace
+	Spans []*traceSpan `json:"spans"`
+}
+
+// eventTracer exports traces of sampled events of a function, either into a file alongside
+// its log, rotated as per its app log settings, or to an OTLP/HTTP collector. Like app log
+// sinks, traces are dropped when exporting falls behind rather than holding up events
+type eventTracer struct {
+	exportURL string
+	name      string // URL without password, or path of file traces are exported into
+	queue     chan *common.EventTrace
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+	export    func(batch []*common.EventTrace) error
+	close     func()
+
+	dropped  uint64
+	exported uint64
+	failed   uint64
+}
+
+// Opens a tracer exporting into file at path if exportURL is empty, or else to an OTLP/HTTP
+// collector at exportURL, e.g. http://collector:4318/v1/traces
+func newEventTracer(appName, exportURL, path string, settings *appLogSettings) (*eventTracer, error) {
+	t := &eventTracer{
+		exportURL: exportURL,
+		queue:     make(chan *common.EventTrace, traceQueueSize),
+		stopCh:    make(chan struct{}),
+		doneCh:    make(chan struct{}),
+		close:     func() {},
+	}
+
+	if exportURL == "" {
+		w, err := openAppLog(path, 0600, settings)
+		if err != nil {
+			return nil, err
+		}
+		t.name = path
+		t.export = func(batch []*common.EventTrace) error {
+			return writeTraces(w, batch)
+		}
+		t.close = func() { w.Close() }
+	} else {
+		u, err := parseURL(exportURL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("unsupported trace export URL: %s", u.Scheme)
+		}
+
+		h := &httpSink{url: exportURL, client: &http.Client{Timeout: appLogSinkTimeout}, stopCh: t.stopCh}
+		t.export = func(batch []*common.EventTrace) error {
+			payload, err := json.Marshal(otlpTraces(appName, batch))
+			if err != nil {
+				return err
+			}
+			return h.post(payload)
+		}
+
+		t.name = util.URLWithoutPassword(u)
+	}
+
+	go t.run()
+	return t, nil
+}
+
+func (t *eventTracer) write(trace *common.EventTrace) {
+	select {
+	case t.queue <- trace:
+	default:
+		atomic.AddUint64(&t.dropped, 1)
+	}
+}
+
+func (t *eventTracer) run() {
+	logPrefix := "eventTracer::run"
+	defer close(t.doneCh)
+
+	ticker := time.NewTicker(traceFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*common.EventTrace, 0, traceBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.export(batch); err != nil {
+			atomic.AddUint64(&t.failed, uint64(len(batch)))
+			logging.Debugf("%s Exporter: %s failed to export %d traces, err: %v", logPrefix, t.name, len(batch), err)
+		} else {
+			atomic.AddUint64(&t.exported, uint64(len(batch)))
+		}
+		batch = make([]*common.EventTrace, 0, traceBatchSize)
+	}
+
+	for {
+		select {
+		case trace := <-t.queue:
+			batch = append(batch, trace)
+			if len(batch) >= traceBatchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+
+		case <-t.stopCh:
+			for {
+				select {
+				case trace := <-t.queue:
+					batch = append(batch, trace)
+					continue
+				default:
+				}
+				break
+			}
+			flush()
+			t.close()
+			return
+		}
+	}
+}
+
+// Stops tracer once traces queued so far are exported, or given up on
+func (t *eventTracer) stop() {
+	close(t.stopCh)
+	<-t.doneCh
+}
+
+// Writes traces as JSON, one per line
+func writeTraces(w io.Writer, batch []*common.EventTrace) error {
+	for _, trace := range batch {
+		data, err := json.Marshal(&traceRecord{EventTrace: trace, Spans: traceSpans(trace)})
+		if err != nil {
+			return err
+		}
+		if _, err = w.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Splits trace into time spent waiting in aggDCPFeed, batched in sendMsgBuffer, queued in
+// eventing-consumer and in the handler, leaving out stages not recorded
+func traceSpans(trace *common.EventTrace) []*traceSpan {
+	stages := []traceSpan{
+		{Name: "dcp_feed", Start: trace.DcpTs, End: trace.SendTs},
+		{Name: "send_batch", Start: trace.SendTs, End: trace.WriteTs},
+		{Name: "worker_queue", Start: trace.WriteTs, End: trace.DequeueTs},
+		{Name: "handler", Start: trace.DequeueTs, End: trace.DoneTs},
+	}
+
+	spans := make([]*traceSpan, 0, len(stages))
+	for i := range stages {
+		span := &stages[i]
+		if span.Start <= 0 || span.End < span.Start {
+			continue
+		}
+		span.DurationUs = (span.End - span.Start) / 1000
+		spans = append(spans, span)
+	}
+	return spans
+}
+
+type otlpTracesRequest struct {
+	ResourceSpans []*otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   *otlpResource     `json:"resource"`
+	ScopeSpans []*otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []*otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope *otlpScope  `json:"scope"`
+	Spans []*otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+// Span as OTLP/HTTP JSON encodes it, IDs hex encoded and 64 bit integers as strings
+type otlpSpan struct {
+	TraceID           string           `json:"traceId"`
+	SpanID            string           `json:"spanId"`
+	ParentSpanID      string           `json:"parentSpanId,omitempty"`
+	Name              string           `json:"name"`
+	Kind              int              `json:"kind"`
+	StartTimeUnixNano string           `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string           `json:"endTimeUnixNano"`
+	Attributes        []*otlpAttribute `json:"attributes,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue string `json:"stringValue,omitempty"`
+	IntValue    string `json:"intValue,omitempty"`
+}
+
+func otlpString(key, value string) *otlpAttribute {
+	return &otlpAttribute{Key: key, Value: otlpAnyValue{StringValue: value}}
+}
+
+func otlpInt(key string, value int64) *otlpAttribute {
+	return &otlpAttribute{Key: key, Value: otlpAnyValue{IntValue: strconv.FormatInt(value, 10)}}
+}
+
+// Builds OTLP request for traces, each being a root span covering the event from DCP till
+// its handler is done, with a child span per stage
+func otlpTraces(appName string, batch []*common.EventTrace) *otlpTracesRequest {
+	spans := make([]*otlpSpan, 0, len(batch)*5)
+	for _, trace := range batch {
+		rootID, err := util.RandomHexID(8)
+		if err != nil {
+			continue
+		}
+
+		spans = append(spans, &otlpSpan{
+			TraceID:           trace.TraceID,
+			SpanID:            rootID,
+			Name:              "eventing." + trace.Opcode,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(trace.DcpTs, 10),
+			EndTimeUnixNano:   strconv.FormatInt(trace.DoneTs, 10),
+			Attributes: []*otlpAttribute{
+				otlpString("eventing.worker", trace.Worker),
+				otlpInt("eventing.vb", int64(trace.Vbucket)),
+				otlpString("eventing.seq_no", strconv.FormatUint(trace.SeqNo, 10)),
+			},
+		})
+
+		for _, span := range traceSpans(trace) {
+			spanID, err := util.RandomHexID(8)
+			if err != nil {
+				continue
+			}
+			spans = append(spans, &otlpSpan{
+				TraceID:           trace.TraceID,
+				SpanID:            spanID,
+				ParentSpanID:      rootID,
+				Name:              span.Name,
+				Kind:              otlpSpanKindInternal,
+				StartTimeUnixNano: strconv.FormatInt(span.Start, 10),
+				EndTimeUnixNano:   strconv.FormatInt(span.End, 10),
+			})
+		}
+	}
+
+	return &otlpTracesRequest{
+		ResourceSpans: []*otlpResourceSpans{
+			{
+				Resource: &otlpResource{
+					Attributes: []*otlpAttribute{
+						otlpString("service.name", "eventing"),
+						otlpString("host.name", appLogHostname),
+						otlpString("eventing.function", appName),
+					},
+				},
+				ScopeSpans: []*otlpScopeSpans{
+					{
+						Scope: &otlpScope{Name: "eventing"},
+						Spans: spans,
+					},
+				},
+			},
+		},
+	}
+}
+
+func (p *Producer) setTraceSampleRate(rate float64) {
+	atomic.StoreUint64(&p.traceSampleRate, math.Float64bits(rate))
+}
+
+// Opens tracer when events are sampled, opening it afresh if export URL changed, and stops it
+// when they no longer are
+func (p *Producer) updateTracer() {
+	logPrefix := "Producer::updateTracer"
+
+	p.tracerRWMutex.Lock()
+	old := p.tracer
+	sampled := math.Float64frombits(atomic.LoadUint64(&p.traceSampleRate)) > 0
+	if old != nil && sampled && old.exportURL == p.traceExportURL {
+		p.tracerRWMutex.Unlock()
+		return
+	}
+
+	p.tracer = nil
+	if sampled {
+		tracer, err := newEventTracer(p.appName, p.traceExportURL, p.tracePath, p.appLogSettings())
+		if err != nil {
+			logging.Errorf("%s [%s:%d] Failed to open tracer exporting to: %rs, err: %v",
+				logPrefix, p.appName, p.LenRunningConsumers(), util.RedactURL(p.traceExportURL), err)
+		} else {
+			logging.Infof("%s [%s:%d] Exporting traces to: %rs",
+				logPrefix, p.appName, p.LenRunningConsumers(), tracer.name)
+			p.tracer = tracer
+		}
+	}
+	p.tracerRWMutex.Unlock()
+
+	if old != nil {
+		old.stop()
+		logging.Infof("%s [%s:%d] Stopped exporting traces to: %rs, exported: %d dropped: %d failed: %d",
+			logPrefix, p.appName, p.LenRunningConsumers(), old.name, atomic.LoadUint64(&old.exported),
+			atomic.LoadUint64(&old.dropped), atomic.LoadUint64(&old.failed))
+	}
+}
+
+func (p *Producer) stopTracer() {
+	p.tracerRWMutex.Lock()
+	tracer := p.tracer
+	p.tracer = nil
+	p.tracerRWMutex.Unlock()
+
+	if tracer != nil {
+		tracer.stop()
+	}
+}
@@ -0,0 +1,135 @@
+package producer
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/couchbase/eventing/common"
+)
+
+func TestTraceSpans(t *testing.T) {
+	trace := &common.EventTrace{DcpTs: 1000, SendTs: 5000, WriteTs: 9000, DequeueTs: 10000, DoneTs: 30000}
+
+	spans := traceSpans(trace)
+	expected := []*traceSpan{
+		{Name: "dcp_feed", Start: 1000, End: 5000, DurationUs: 4},
+		{Name: "send_batch", Start: 5000, End: 9000, DurationUs: 4},
+		{Name: "worker_queue", Start: 9000, End: 10000, DurationUs: 1},
+		{Name: "handler", Start: 10000, End: 30000, DurationUs: 20},
+	}
+	if !reflect.DeepEqual(spans, expected) {
+		t.Errorf("Expected spans %+v, got %+v", expected, spans)
+	}
+
+	// Buffer dropped before write, so time between send and dequeue isn't known
+	trace.WriteTs = 0
+	var names []string
+	for _, span := range traceSpans(trace) {
+		names = append(names, span.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"dcp_feed", "handler"}) {
+		t.Errorf("Expected stages around write to be left out, got %v", names)
+	}
+
+	// Clock going back is no span either
+	trace.DoneTs = 9000
+	if spans := traceSpans(trace); len(spans) != 1 || spans[0].Name != "dcp_feed" {
+		t.Errorf("Expected only dcp_feed span, got %+v", spans)
+	}
+}
+
+func TestWriteTraces(t *testing.T) {
+	var buf bytes.Buffer
+	batch := []*common.EventTrace{
+		{TraceID: "a", DcpTs: 1000, SendTs: 2000},
+		{TraceID: "b", DcpTs: 1000, DequeueTs: 3000, DoneTs: 4000},
+	}
+	if err := writeTraces(&buf, batch); err != nil {
+		t.Fatalf("Failed to write traces, err: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a line per trace, got %q", lines)
+	}
+
+	record := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatalf("Failed to unmarshal %s, err: %v", lines[1], err)
+	}
+	spans, _ := record["spans"].([]interface{})
+	if record["trace_id"] != "b" || len(spans) != 1 {
+		t.Errorf("Expected trace b with a handler span, got %s", lines[1])
+	}
+}
+
+func TestOtlpTraces(t *testing.T) {
+	batch := []*common.EventTrace{
+		{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", Worker: "worker_fn_0", Opcode: "mutation", Vbucket: 512,
+			SeqNo: 1841, DcpTs: 1000, SendTs: 2000, WriteTs: 3000, DequeueTs: 4000, DoneTs: 9000},
+		{TraceID: "00f067aa0ba902b7a3ce929d0e0e4736", Worker: "worker_fn_1", Opcode: "deletion", Vbucket: 7,
+			SeqNo: 3, DcpTs: 1000, SendTs: 2000, DoneTs: 5000},
+	}
+
+	req := otlpTraces("fn", batch)
+	if len(req.ResourceSpans) != 1 || len(req.ResourceSpans[0].ScopeSpans) != 1 {
+		t.Fatalf("Expected one resource with one scope, got %+v", req.ResourceSpans)
+	}
+
+	resource := req.ResourceSpans[0].Resource
+	if attr := resource.Attributes[2]; attr.Key != "eventing.function" || attr.Value.StringValue != "fn" {
+		t.Errorf("Expected resource to carry function, got %+v", resource.Attributes)
+	}
+
+	// Root span per trace followed by its stages, of which only dcp_feed of second is known
+	spans := req.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 7 {
+		t.Fatalf("Expected 7 spans, got %d", len(spans))
+	}
+
+	root := spans[0]
+	if root.TraceID != batch[0].TraceID || root.ParentSpanID != "" || root.Name != "eventing.mutation" ||
+		root.StartTimeUnixNano != "1000" || root.EndTimeUnixNano != "9000" || len(root.SpanID) != 16 {
+		t.Errorf("Unexpected root span %+v", *root)
+	}
+	attributes := map[string]otlpAnyValue{}
+	for _, attr := range root.Attributes {
+		attributes[attr.Key] = attr.Value
+	}
+	expected := map[string]otlpAnyValue{
+		"eventing.worker": {StringValue: "worker_fn_0"},
+		"eventing.vb":     {IntValue: "512"},
+		"eventing.seq_no": {StringValue: "1841"},
+	}
+	if !reflect.DeepEqual(attributes, expected) {
+		t.Errorf("Expected root span attributes %v, got %v", expected, attributes)
+	}
+
+	for _, span := range spans[1:5] {
+		if span.TraceID != root.TraceID || span.ParentSpanID != root.SpanID || span.SpanID == root.SpanID {
+			t.Errorf("Expected %s to be child of root span of first trace, got %+v", span.Name, *span)
+		}
+	}
+	if handler := spans[4]; handler.Name != "handler" || handler.StartTimeUnixNano != "4000" || handler.EndTimeUnixNano != "9000" {
+		t.Errorf("Expected handler span from 4000 to 9000, got %+v", *handler)
+	}
+
+	if second := spans[5]; second.Name != "eventing.deletion" || second.ParentSpanID != "" {
+		t.Errorf("Expected root span of second trace, got %+v", *second)
+	}
+	if dcp := spans[6]; dcp.Name != "dcp_feed" || dcp.ParentSpanID != spans[5].SpanID {
+		t.Errorf("Expected dcp_feed span of second trace, got %+v", *dcp)
+	}
+
+	// IDs and 64 bit integers are strings, as OTLP/HTTP JSON encodes them
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Failed to marshal request, err: %v", err)
+	}
+	if !strings.Contains(string(data), `"startTimeUnixNano":"1000"`) || !strings.Contains(string(data), `"intValue":"512"`) {
+		t.Errorf("Expected integers encoded as strings, got %s", data)
+	}
+}
@@ -2,6 +2,7 @@ package producer
 
 import (
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"sort"
@@ -37,6 +38,31 @@ func (p *Producer) ClearEventStats() {
 	}
 }
 
+// CancelTimer cancels timers created with callback and reference on local eventing node
+func (p *Producer) CancelTimer(callback, reference string) (bool, error) {
+	cancelled := false
+
+	for _, c := range p.getConsumers() {
+		found, err := c.CancelTimer(callback, reference)
+		if err != nil {
+			return cancelled, err
+		}
+		cancelled = cancelled || found
+	}
+
+	return cancelled, nil
+}
+
+// CrashHistory returns recent crashes of eventing-consumers along with action taken for them
+func (p *Producer) CrashHistory() []*common.CrashEntry {
+	p.crashRWMutex.RLock()
+	defer p.crashRWMutex.RUnlock()
+
+	crashHistory := make([]*common.CrashEntry, len(p.crashHistory))
+	copy(crashHistory, p.crashHistory)
+	return crashHistory
+}
+
 // GetLatencyStats returns latency stats for event handlers from from cpp world
 func (p *Producer) GetLatencyStats() map[string]uint64 {
 	latencyStats := make(map[string]uint64)
@@ -68,6 +94,44 @@ func (p *Producer) GetCurlLatencyStats() map[string]uint64 {
 	return latencyStats
 }
 
+// GetDcpLatencyStats returns histogram of latency from DCP till handler execution, aggregated
+// from Eventing.Consumer instances
+func (p *Producer) GetDcpLatencyStats() map[string]uint64 {
+	latencyStats := make(map[string]uint64)
+
+	for _, c := range p.getConsumers() {
+		for k, v := range c.GetDcpLatencyStats() {
+			latencyStats[k] += v
+		}
+	}
+	return latencyStats
+}
+
+// GetTimerLatenessStats returns timer lateness histogram aggregated from Eventing.Consumer instances
+func (p *Producer) GetTimerLatenessStats() map[string]uint64 {
+	latenessStats := make(map[string]uint64)
+
+	for _, c := range p.getConsumers() {
+		for k, v := range c.GetTimerLatenessStats() {
+			latenessStats[k] += v
+		}
+	}
+	return latenessStats
+}
+
+// GetTimerMaxLateness returns highest lateness of timers fired by Eventing.Consumer instances
+// in the last one to two minutes
+func (p *Producer) GetTimerMaxLateness() int64 {
+	var maxLateness int64
+
+	for _, c := range p.getConsumers() {
+		if lateness := c.GetTimerMaxLateness(); lateness > maxLateness {
+			maxLateness = lateness
+		}
+	}
+	return maxLateness
+}
+
 // GetExecutionStats returns execution stats aggregated from Eventing.Consumer instances
 func (p *Producer) GetExecutionStats() map[string]interface{} {
 	executionStats := make(map[string]interface{})
@@ -111,6 +175,15 @@ func (p *Producer) GetFailureStats() map[string]interface{} {
 		}
 	}
 
+	p.crashRWMutex.RLock()
+	failureStats["worker_crash_counter"] = float64(p.workerCrashCounter)
+	quarantinedEventCount := 0
+	for _, seqNos := range p.quarantinedEvents {
+		quarantinedEventCount += len(seqNos)
+	}
+	failureStats["quarantined_event_counter"] = float64(quarantinedEventCount)
+	p.crashRWMutex.RUnlock()
+
 	return failureStats
 }
 
@@ -330,6 +403,20 @@ func (p *Producer) VbDcpEventsRemainingToProcess() map[int]int64 {
 	return vbDcpEventsRemaining
 }
 
+// VbLag returns how far behind vbuckets handled by running consumers are
+func (p *Producer) VbLag() []*common.VbLag {
+	lags := make([]*common.VbLag, 0)
+
+	for _, c := range p.getConsumers() {
+		lags = append(lags, c.VbLag()...)
+	}
+
+	sort.Slice(lags, func(i, j int) bool {
+		return lags[i].Vbucket < lags[j].Vbucket
+	})
+	return lags
+}
+
 // GetEventingConsumerPids returns map of Eventing.Consumer worker name and it's os pid
 func (p *Producer) GetEventingConsumerPids() map[string]int {
 	workerPidMapping := make(map[string]int)
@@ -366,8 +453,27 @@ func (p *Producer) KillAllConsumers() {
 
 // WriteAppLog dumps the application specific log message to configured file
 func (p *Producer) WriteAppLog(log string) {
-	ts := time.Now().Format("2006-01-02T15:04:05.000-07:00")
+	now := time.Now()
+	ts := now.Format(util.AppLogTimeLayout)
 	fmt.Fprintf(p.appLogWriter, "%s [INFO] %s\n", ts, log)
+
+	p.appLogSinksRWMutex.RLock()
+	defer p.appLogSinksRWMutex.RUnlock()
+
+	if len(p.appLogSinks) == 0 {
+		return
+	}
+
+	record := &appLogRecord{
+		Function:  p.appName,
+		Level:     "INFO",
+		Message:   log,
+		Node:      appLogHostname,
+		Timestamp: now,
+	}
+	for _, sink := range p.appLogSinks {
+		sink.write(record)
+	}
 }
 
 // InternalVbDistributionStats returns internal state of vbucket ownership distribution on local eventing node
@@ -455,6 +561,40 @@ func (p *Producer) vbDistributionStats() error {
 	return nil
 }
 
+// ListTimers returns earliest pending timers matching filter from vbuckets owned by local eventing node
+func (p *Producer) ListTimers(filter *common.TimerFilter) ([]*common.PendingTimer, error) {
+	pending := make([]*common.PendingTimer, 0)
+
+	for _, c := range p.getConsumers() {
+		timers, err := c.ListTimers(filter)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, timers...)
+	}
+
+	util.SortPendingTimers(pending)
+	if filter.Limit > 0 && len(pending) > filter.Limit {
+		pending = pending[:filter.Limit]
+	}
+	return pending, nil
+}
+
+// QuarantinedEvents returns per vbucket seq nos of events skipped for crashing eventing-consumers
+func (p *Producer) QuarantinedEvents() map[uint16]map[uint64]struct{} {
+	p.crashRWMutex.RLock()
+	defer p.crashRWMutex.RUnlock()
+
+	quarantinedEvents := make(map[uint16]map[uint64]struct{})
+	for vb, seqNos := range p.quarantinedEvents {
+		quarantinedEvents[vb] = make(map[uint64]struct{})
+		for seqNo := range seqNos {
+			quarantinedEvents[vb][seqNo] = struct{}{}
+		}
+	}
+	return quarantinedEvents
+}
+
 // PlannerStats returns vbucket distribution as per planner running on local eventing
 // node for a given app
 func (p *Producer) PlannerStats() []*common.PlannerNodeVbMapping {
@@ -546,6 +686,8 @@ func (p *Producer) CleanupMetadataBucket(skipCheckpointBlobs bool) error {
 		return nil
 	}
 
+	p.recordTimerCarryOver()
+
 	// Distribute vbuckets to cleanup based on planner
 	err := p.vbEventingNodeAssign()
 	if err != nil {
@@ -675,6 +817,10 @@ func (p *Producer) cleanupMetadataImpl(id int, vbsToCleanup []uint16, undeployWG
 							continue
 						}
 
+						if p.carryingOverTimers() && strings.HasPrefix(docID, prefix+":tm:") {
+							continue
+						}
+
 						err = util.Retry(util.NewFixedBackoff(bucketOpRetryInterval), &p.retryCount, deleteOpCallback, p, docID)
 						if err == common.ErrRetryTimeout {
 							logging.Errorf("%s [%s:%d:id_%d] Exiting due to timeout",
@@ -817,6 +963,50 @@ func (p *Producer) UpdateMemoryQuota(quota int64) {
 	}
 }
 
+// GetMemoryUsage returns memory held in queues, aggregated from Eventing.Consumer instances
+func (p *Producer) GetMemoryUsage() *common.MemoryUsage {
+	usage := &common.MemoryUsage{}
+
+	for _, c := range p.getConsumers() {
+		cUsage := c.GetMemoryUsage()
+		usage.DcpFeed += cUsage.DcpFeed
+		usage.TimerQueues += cUsage.TimerQueues
+		usage.WorkerQueue += cUsage.WorkerQueue
+	}
+	return usage
+}
+
+// SetMemoryThrottle notifies Eventing.Consumer instances to slow down or resume DCP feed
+func (p *Producer) SetMemoryThrottle(throttle bool) {
+	for _, c := range p.getConsumers() {
+		c.SetMemoryThrottle(throttle)
+	}
+}
+
+// SetDefaultAppLogSinks sets sinks from global config, which application log is forwarded to
+// unless function sets its own
+func (p *Producer) SetDefaultAppLogSinks(sinks []string) {
+	p.appLogSinksRWMutex.Lock()
+	p.defaultAppLogSinkURLs = sinks
+	p.appLogSinksRWMutex.Unlock()
+
+	if p.appLogWriter != nil {
+		p.updateAppLogSinks()
+	}
+}
+
+// GetAppLogSinkStats returns counts of application log records forwarded to each sink
+func (p *Producer) GetAppLogSinkStats() map[string]*common.AppLogSinkStats {
+	p.appLogSinksRWMutex.RLock()
+	defer p.appLogSinksRWMutex.RUnlock()
+
+	stats := make(map[string]*common.AppLogSinkStats)
+	for _, sink := range p.appLogSinks {
+		stats[sink.name] = sink.stats()
+	}
+	return stats
+}
+
 // TimerDebugStats captures timer related stats to assist in debugging mismtaches during rebalance
 func (p *Producer) TimerDebugStats() map[int]map[string]interface{} {
 	aggStats := make(map[int]map[string]interface{})
@@ -935,6 +1125,70 @@ func (p *Producer) RemoveConsumerToken(workerName string) {
 	}
 }
 
+// BackfillJobStatus returns progress of function deployed as one-shot backfill job
+func (p *Producer) BackfillJobStatus() *common.BackfillJobStatus {
+	if !p.handlerConfig.BackfillJob {
+		return nil
+	}
+
+	p.backfillRWMutex.RLock()
+	defer p.backfillRWMutex.RUnlock()
+
+	status := *p.backfillStatus
+	return &status
+}
+
+// BackfillJobSeqNos returns per vbucket seq nos recorded when function was deployed as backfill job
+func (p *Producer) BackfillJobSeqNos() []uint64 {
+	if !p.handlerConfig.BackfillJob || p.backfillJob == nil {
+		return nil
+	}
+
+	seqNos := make([]uint64, len(p.backfillJob.SeqNos))
+	copy(seqNos, p.backfillJob.SeqNos)
+	return seqNos
+}
+
+// RollbackHistory returns recent rollbacks requested by DCP producer across all running consumers
+func (p *Producer) RollbackHistory() []*common.RollbackEntry {
+	history := make([]*common.RollbackEntry, 0)
+
+	for _, c := range p.getConsumers() {
+		history = append(history, c.RollbackHistory()...)
+	}
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp < history[j].Timestamp
+	})
+	return history
+}
+
+// Reprocess passes on request to replay vbuckets to all running consumers, each of them
+// acts only on vbuckets it currently owns. Consumers are notified in parallel, as resolving
+// a timestamp boundary into seq nos takes a while
+func (p *Producer) Reprocess(req *common.ReprocessRequest) {
+	logPrefix := "Producer::Reprocess"
+
+	logging.Infof("%s [%s:%d] Reprocess requested from boundary: %s seq no: %d timestamp: %s vbs len: %d dump: %s",
+		logPrefix, p.appName, p.LenRunningConsumers(), req.Boundary, req.StartSeqNo, req.BoundaryTimestamp,
+		len(req.Vbuckets), util.Condense(req.Vbuckets))
+
+	vbs := "all"
+	if len(req.Vbuckets) > 0 {
+		vbs = util.Condense(req.Vbuckets)
+	}
+
+	from := fmt.Sprintf("seq no: %d", req.StartSeqNo)
+	if req.Boundary == common.DcpFromTimestamp {
+		from = fmt.Sprintf("timestamp: %s", req.BoundaryTimestamp)
+	}
+	p.WriteAppLog(fmt.Sprintf("Reprocessing vbuckets: %s from %s", vbs, from))
+
+	for _, c := range p.getConsumers() {
+		go c.Reprocess(req)
+	}
+}
+
 func (p *Producer) stopAndDeleteConsumer(c common.EventingConsumer) {
 	p.tokenRWMutex.RLock()
 	token := p.consumerSupervisorTokenMap[c]
@@ -1021,6 +1275,8 @@ func (p *Producer) GetMetaStoreStats() map[string]uint64 {
 		}
 	}
 
+	metaStats["timers_carried_over"] = atomic.LoadUint64(&p.timersCarriedOver)
+	metaStats["timers_missing_callback"] = atomic.LoadUint64(&p.timersMissingCallback)
 	return metaStats
 }
 
@@ -1099,3 +1355,19 @@ func (p *Producer) SpanBlobDump() map[string]interface{} {
 func (p *Producer) DcpFeedBoundary() string {
 	return string(p.handlerConfig.StreamBoundary)
 }
+
+// TraceSampleRate returns fraction of events to be traced, as per trace_sample_rate of function
+func (p *Producer) TraceSampleRate() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&p.traceSampleRate))
+}
+
+// ExportTrace queues up trace of a sampled event for exporting
+func (p *Producer) ExportTrace(trace *common.EventTrace) {
+	p.tracerRWMutex.RLock()
+	defer p.tracerRWMutex.RUnlock()
+
+	if p.tracer != nil {
+		trace.Function = p.appName
+		p.tracer.write(trace)
+	}
+}
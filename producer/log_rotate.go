@@ -2,6 +2,7 @@ package producer
 
 import (
 	"bufio"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"math"
@@ -23,15 +24,35 @@ type filePtr struct {
 	lock sync.Mutex
 }
 
+// Periods application log can be rotated at, besides on reaching its max size
+const (
+	appLogRotateNone   = "none"
+	appLogRotateHourly = "hourly"
+	appLogRotateDaily  = "daily"
+)
+
+// How often rotated files are checked for having grown older than max age
+const appLogAgeCheckInterval = time.Minute
+
+// appLogSettings governs rotation of application log. Zero maxAge keeps rotated files
+// regardless of their age
+type appLogSettings struct {
+	compress bool
+	interval string
+	maxAge   time.Duration
+	maxFiles int64
+	maxSize  int64
+}
+
 type appLogCloser struct {
 	path      string
 	filePtr   unsafe.Pointer //Stores file pointer
 	perm      os.FileMode
-	maxSize   int64
-	maxFiles  int64
+	settings  unsafe.Pointer //Stores *appLogSettings, swapped on update
 	size      int64
 	lowIndex  int64
 	highIndex int64
+	openedAt  time.Time
 	exitCh    chan struct{}
 }
 
@@ -69,13 +90,20 @@ func (wc *appLogCloser) Flush() {
 	fptr.lock.Unlock()
 }
 
+func (wc *appLogCloser) getSettings() *appLogSettings {
+	return (*appLogSettings)(atomic.LoadPointer(&wc.settings))
+}
+
 func (wc *appLogCloser) manageLogFiles() {
 	logPrefix := "manageLogFiles:" + wc.path
-	if err := os.Rename(wc.path, fmt.Sprintf("%s.%d", wc.path, wc.highIndex+1)); err != nil {
+	settings := wc.getSettings()
+	rotated := fmt.Sprintf("%s.%d", wc.path, wc.highIndex+1)
+	if err := os.Rename(wc.path, rotated); err != nil {
 		logging.Errorf("%s: File Rename() failed err: %v", logPrefix, err)
 		return
 	}
 	wc.highIndex++
+	wc.openedAt = time.Now()
 	fp, err := openFile(wc.path, wc.perm)
 	if err != nil {
 		logging.Errorf("%s: File Open() failed err: %v", logPrefix, err)
@@ -92,21 +120,138 @@ func (wc *appLogCloser) manageLogFiles() {
 	}
 	oldFptr.ptr = nil
 	oldFptr.lock.Unlock()
-	for ; wc.lowIndex+wc.maxFiles <= wc.highIndex; wc.lowIndex++ {
-		if err = os.Remove(fmt.Sprintf("%s.%d", wc.path, wc.lowIndex)); err != nil {
+
+	if settings.compress {
+		if err = compressFile(rotated); err != nil {
+			logging.Errorf("%s: File compression failed err: %v", logPrefix, err)
+		}
+	}
+
+	for ; wc.lowIndex+settings.maxFiles <= wc.highIndex; wc.lowIndex++ {
+		if err = removeRotatedFile(fmt.Sprintf("%s.%d", wc.path, wc.lowIndex)); err != nil {
+			logging.Errorf("%s: File Remove() failed err: %v", logPrefix, err)
+		}
+	}
+}
+
+// Removes rotated files last written to before max age, oldest first so that index range of
+// files kept stays contiguous
+func (wc *appLogCloser) removeAgedFiles() {
+	logPrefix := "removeAgedFiles:" + wc.path
+	maxAge := wc.getSettings().maxAge
+	if maxAge <= 0 {
+		return
+	}
+
+	for ; wc.lowIndex <= wc.highIndex; wc.lowIndex++ {
+		file := fmt.Sprintf("%s.%d", wc.path, wc.lowIndex)
+		fi, err := os.Stat(file)
+		if os.IsNotExist(err) {
+			file += ".gz"
+			fi, err = os.Stat(file)
+		}
+		if err != nil {
+			if !os.IsNotExist(err) {
+				logging.Errorf("%s: File Stat() failed err: %v", logPrefix, err)
+			}
+			continue
+		}
+		if time.Since(fi.ModTime()) < maxAge {
+			return
+		}
+		if err = os.Remove(file); err != nil {
 			logging.Errorf("%s: File Remove() failed err: %v", logPrefix, err)
 		}
 	}
 }
 
+// Whether current file has outlived the hour or day it was opened in
+func (wc *appLogCloser) periodElapsed(now time.Time) bool {
+	var start func(t time.Time) time.Time
+	switch wc.getSettings().interval {
+	case appLogRotateHourly:
+		start = func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+		}
+	case appLogRotateDaily:
+		start = func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		}
+	default:
+		return false
+	}
+	return start(now).After(wc.openedAt)
+}
+
+// Compresses a rotated file into file.gz, written aside first so a partly compressed file is
+// never taken for a rotated one
+func compressFile(file string) error {
+	src, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp := file + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fi.Mode())
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if err == nil {
+		err = gz.Close()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		// Age of a rotated file is told by when it was last written to
+		os.Chtimes(tmp, fi.ModTime(), fi.ModTime())
+		err = os.Rename(tmp, file+".gz")
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Remove(file)
+}
+
+// Removes a rotated file, whether or not it got compressed
+func removeRotatedFile(file string) error {
+	err := os.Remove(file)
+	if os.IsNotExist(err) {
+		err = os.Remove(file + ".gz")
+	}
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
 func (wc *appLogCloser) cleanupTask() {
+	var ageCheckedAt time.Time
 	for {
 		select {
 		case <-wc.exitCh:
 			return
 		default:
 		}
-		if wc.maxSize <= atomic.LoadInt64(&wc.size) {
+
+		now := time.Now()
+		if now.Sub(ageCheckedAt) >= appLogAgeCheckInterval {
+			wc.removeAgedFiles()
+			ageCheckedAt = now
+		}
+
+		size := atomic.LoadInt64(&wc.size)
+		if wc.getSettings().maxSize <= size || (size > 0 && wc.periodElapsed(now)) {
 			wc.manageLogFiles()
 		} else {
 			wc.Flush()
@@ -119,6 +264,8 @@ func (wc *appLogCloser) init() {
 	go wc.cleanupTask()
 }
 
+// Index range of rotated files of path. Leftovers of compression cut short, like by a crash,
+// are removed as the file being compressed is still there
 func getFileIndexRange(path string) (int64, int64) {
 	files, err := filepath.Glob(path + ".*")
 	if err != nil || len(files) == 0 {
@@ -127,7 +274,11 @@ func getFileIndexRange(path string) (int64, int64) {
 	var lowIndex int64 = math.MaxInt64
 	var highIndex int64
 	for _, file := range files {
-		tokens := strings.Split(file, ".")
+		if strings.HasSuffix(file, ".gz.tmp") {
+			os.Remove(file)
+			continue
+		}
+		tokens := strings.Split(strings.TrimSuffix(file, ".gz"), ".")
 		if index, err := strconv.ParseInt(tokens[len(tokens)-1], 10, 64); err == nil {
 			if index < lowIndex {
 				lowIndex = index
@@ -138,19 +289,23 @@ func getFileIndexRange(path string) (int64, int64) {
 			}
 		}
 	}
+	if lowIndex > highIndex {
+		return 1, 0
+	}
 	return lowIndex, highIndex
 }
 
-func openAppLog(path string, perm os.FileMode, maxSize, maxFiles int64) (io.WriteCloser, error) {
-	if maxSize < 1 {
+func openAppLog(path string, perm os.FileMode, settings *appLogSettings) (io.WriteCloser, error) {
+	if settings.maxSize < 1 {
 		return nil, fmt.Errorf("maxSize should be > 1")
 	}
-	if maxFiles < 1 {
+	if settings.maxFiles < 1 {
 		return nil, fmt.Errorf("maxFiles should be > 1")
 	}
 
 	// If path exists determine size and check path is a regular file.
 	var size int64
+	openedAt := time.Now()
 	fi, err := os.Lstat(path)
 	if err != nil && !os.IsNotExist(err) {
 		return nil, err
@@ -161,6 +316,9 @@ func openAppLog(path string, perm os.FileMode, maxSize, maxFiles int64) (io.Writ
 			return nil, fmt.Errorf("Supplied app log file, path: %s is not a regular file", path)
 		}
 		size = fi.Size()
+		if size > 0 {
+			openedAt = fi.ModTime()
+		}
 	}
 
 	// Open path for reading/writing, create if necessary.
@@ -175,18 +333,17 @@ func openAppLog(path string, perm os.FileMode, maxSize, maxFiles int64) (io.Writ
 		path:      path,
 		filePtr:   unsafe.Pointer(&filePtr{ptr: file, wptr: w}),
 		perm:      perm,
-		maxSize:   maxSize,
-		maxFiles:  maxFiles,
+		settings:  unsafe.Pointer(settings),
 		size:      size,
 		lowIndex:  low,
 		highIndex: high,
+		openedAt:  openedAt,
 		exitCh:    make(chan struct{}, 1),
 	}
 	logger.init()
 	return logger, nil
 }
 
-func updateApplogSetting(wc *appLogCloser, maxFileCount, maxFileSize int64) {
-	wc.maxFiles = maxFileCount
-	wc.maxSize = maxFileSize
+func updateApplogSetting(wc *appLogCloser, settings *appLogSettings) {
+	atomic.StorePointer(&wc.settings, unsafe.Pointer(settings))
 }
@@ -0,0 +1,141 @@
+package producer
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestPeriodElapsed(t *testing.T) {
+	openedAt := time.Date(2021, 3, 14, 23, 40, 0, 0, time.UTC)
+
+	tests := []struct {
+		interval string
+		now      time.Time
+		elapsed  bool
+	}{
+		{interval: appLogRotateHourly, now: openedAt.Add(15 * time.Minute), elapsed: false},
+		{interval: appLogRotateHourly, now: openedAt.Add(20 * time.Minute), elapsed: true},
+		{interval: appLogRotateDaily, now: openedAt.Add(19 * time.Minute), elapsed: false},
+		{interval: appLogRotateDaily, now: openedAt.Add(20 * time.Minute), elapsed: true},
+		{interval: appLogRotateDaily, now: openedAt.Add(30 * time.Hour), elapsed: true},
+		{interval: appLogRotateNone, now: openedAt.Add(30 * time.Hour), elapsed: false},
+	}
+
+	for _, test := range tests {
+		wc := &appLogCloser{
+			settings: unsafe.Pointer(&appLogSettings{interval: test.interval}),
+			openedAt: openedAt,
+		}
+		if elapsed := wc.periodElapsed(test.now); elapsed != test.elapsed {
+			t.Errorf("Interval: %s now: %v expected elapsed: %t, got %t", test.interval, test.now, test.elapsed, elapsed)
+		}
+	}
+}
+
+func TestCompressAndRemoveAgedFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "applog")
+	if err != nil {
+		t.Fatalf("Failed to create dir, err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fn.log")
+	aged := time.Now().Add(-2 * time.Hour)
+	for index := 1; index <= 3; index++ {
+		file := fmt.Sprintf("%s.%d", path, index)
+		if err = ioutil.WriteFile(file, []byte(fmt.Sprintf("line %d\n", index)), 0600); err != nil {
+			t.Fatalf("Failed to write %s, err: %v", file, err)
+		}
+		if index < 3 {
+			os.Chtimes(file, aged, aged)
+		}
+	}
+
+	for index := 1; index <= 2; index++ {
+		file := fmt.Sprintf("%s.%d", path, index)
+		if err = compressFile(file); err != nil {
+			t.Fatalf("Failed to compress %s, err: %v", file, err)
+		}
+		if _, err = os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be removed once compressed, got err: %v", file, err)
+		}
+		if _, err = os.Stat(file + ".gz.tmp"); !os.IsNotExist(err) {
+			t.Errorf("Expected no %s.gz.tmp to be left, got err: %v", file, err)
+		}
+	}
+
+	// Compressed file keeps content and age of file it was compressed from
+	fp, err := os.Open(path + ".1.gz")
+	if err != nil {
+		t.Fatalf("Failed to open compressed file, err: %v", err)
+	}
+	gz, err := gzip.NewReader(fp)
+	if err != nil {
+		t.Fatalf("Failed to read compressed file, err: %v", err)
+	}
+	content, err := ioutil.ReadAll(gz)
+	fp.Close()
+	if err != nil || string(content) != "line 1\n" {
+		t.Errorf("Expected compressed content %q, got %q err: %v", "line 1\n", content, err)
+	}
+	if fi, err := os.Stat(path + ".1.gz"); err != nil || fi.ModTime().Unix() != aged.Unix() {
+		t.Errorf("Expected compressed file to be last modified at %v, got %v err: %v", aged, fi, err)
+	}
+
+	wc := &appLogCloser{
+		path:      path,
+		settings:  unsafe.Pointer(&appLogSettings{maxAge: time.Hour}),
+		lowIndex:  1,
+		highIndex: 3,
+	}
+	wc.removeAgedFiles()
+
+	if wc.lowIndex != 3 {
+		t.Errorf("Expected low index to move to 3, got %d", wc.lowIndex)
+	}
+	for _, file := range []string{path + ".1.gz", path + ".2.gz"} {
+		if _, err = os.Stat(file); !os.IsNotExist(err) {
+			t.Errorf("Expected aged %s to be removed, got err: %v", file, err)
+		}
+	}
+	if _, err = os.Stat(path + ".3"); err != nil {
+		t.Errorf("Expected %s.3 to be kept, got err: %v", path, err)
+	}
+}
+
+func TestGetFileIndexRange(t *testing.T) {
+	dir, err := ioutil.TempDir("", "applog")
+	if err != nil {
+		t.Fatalf("Failed to create dir, err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "fn.log")
+	if low, high := getFileIndexRange(path); low != 1 || high != 0 {
+		t.Errorf("Expected range 1, 0 without rotated files, got %d, %d", low, high)
+	}
+
+	// Compression of fn.log.1 cut short leaves only a leftover
+	ioutil.WriteFile(path+".1.gz.tmp", nil, 0600)
+	if low, high := getFileIndexRange(path); low != 1 || high != 0 {
+		t.Errorf("Expected range 1, 0 with only a leftover, got %d, %d", low, high)
+	}
+
+	for _, file := range []string{path + ".2.gz", path + ".3", path + ".4", path + ".4.gz.tmp"} {
+		ioutil.WriteFile(file, nil, 0600)
+	}
+	if low, high := getFileIndexRange(path); low != 2 || high != 4 {
+		t.Errorf("Expected range 2, 4, got %d, %d", low, high)
+	}
+
+	leftovers, _ := filepath.Glob(path + ".*.gz.tmp")
+	if len(leftovers) != 0 {
+		t.Errorf("Expected leftovers to be removed, got %v", leftovers)
+	}
+}
@@ -27,11 +27,18 @@ func NewProducer(appName, debuggerPort, eventingPort, eventingSSLPort, eventingD
 	memoryQuota int64, numVbuckets int, superSup common.EventingSuperSup) *Producer {
 	p := &Producer{
 		appName:                      appName,
+		appLogSinksRWMutex:           &sync.RWMutex{},
+		tracerRWMutex:                &sync.RWMutex{},
+		backfillRWMutex:              &sync.RWMutex{},
+		backfillStatus:               &common.BackfillJobStatus{},
 		bootstrapFinishCh:            make(chan struct{}, 1),
 		cleanupTimers:                cleanupTimers,
 		consumerListeners:            make(map[common.EventingConsumer]net.Listener),
+		crashHistory:                 make([]*common.CrashEntry, 0),
+		crashRWMutex:                 &sync.RWMutex{},
 		dcpConfig:                    make(map[string]interface{}),
 		ejectNodeUUIDs:               make([]string, 0),
+		eventCrashCounts:             make(map[string]int),
 		eventingNodeUUIDs:            make([]string, 0),
 		feedbackListeners:            make(map[common.EventingConsumer]net.Listener),
 		handleV8ConsumerMutex:        &sync.Mutex{},
@@ -45,6 +52,7 @@ func NewProducer(appName, debuggerPort, eventingPort, eventingSSLPort, eventingD
 		numVbuckets:                  numVbuckets,
 		pauseProducerCh:              make(chan struct{}, 1),
 		plannerNodeMappingsRWMutex:   &sync.RWMutex{},
+		quarantinedEvents:            make(map[uint16]map[uint64]struct{}),
 		MemoryQuota:                  memoryQuota,
 		retryCount:                   -1,
 		runningConsumersRWMutex:      &sync.RWMutex{},
@@ -53,6 +61,7 @@ func NewProducer(appName, debuggerPort, eventingPort, eventingSSLPort, eventingD
 		statsRWMutex:                 &sync.RWMutex{},
 		stopCh:                       make(chan struct{}, 1),
 		superSup:                     superSup,
+		timerCheckMutex:              &sync.Mutex{},
 		topologyChangeCh:             make(chan *common.TopologyChangeMsg, 10),
 		uuid:                         uuid,
 		vbEventingNodeAssignRWMutex:  &sync.RWMutex{},
@@ -60,6 +69,7 @@ func NewProducer(appName, debuggerPort, eventingPort, eventingSSLPort, eventingD
 		vbMapping:                    make(map[uint16]*vbNodeWorkerMapping),
 		vbMappingRWMutex:             &sync.RWMutex{},
 		workerNameConsumerMap:        make(map[string]common.EventingConsumer),
+		workerCrashTimestamps:        make(map[string][]time.Time),
 		workerNameConsumerMapRWMutex: &sync.RWMutex{},
 		workerVbMapRWMutex:           &sync.RWMutex{},
 		handlerConfig:                &common.HandlerConfig{},
@@ -116,12 +126,14 @@ func (p *Producer) Serve() {
 
 	go p.pollForDeletedVbs()
 
-	p.appLogWriter, err = openAppLog(p.appLogPath, 0600, p.appLogMaxSize, p.appLogMaxFiles)
+	p.appLogWriter, err = openAppLog(p.appLogPath, 0600, p.appLogSettings())
 	if err != nil {
 		logging.Fatalf("%s [%s:%d] Failure to open application log writer handle, err: %v",
 			logPrefix, p.appName, p.LenRunningConsumers(), err)
 		return
 	}
+	p.updateAppLogSinks()
+	p.updateTracer()
 
 	p.isPlannerRunning = true
 	logging.Infof("%s [%s:%d] Planner status: %t, before vbucket to node assignment", logPrefix, p.appName, p.LenRunningConsumers(), p.isPlannerRunning)
@@ -176,6 +188,20 @@ func (p *Producer) Serve() {
 		return
 	}
 
+	err = p.carryOverTimers()
+	if err == common.ErrRetryTimeout {
+		logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+		return
+	}
+
+	if p.handlerConfig.BackfillJob {
+		err = p.initBackfillJob()
+		if err == common.ErrRetryTimeout {
+			logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+			return
+		}
+	}
+
 	p.startBucket()
 
 	p.bootstrapFinishCh <- struct{}{}
@@ -185,6 +211,10 @@ func (p *Producer) Serve() {
 
 	go p.updateStats()
 
+	if p.handlerConfig.UsingTimer && p.handlerConfig.TimerCheckInterval > 0 {
+		go p.checkTimersRoutine()
+	}
+
 	// Inserting twice because producer can be stopped either because of pause/undeploy
 	for i := 0; i < 2; i++ {
 		p.notifyInitCh <- struct{}{}
@@ -313,6 +343,8 @@ func (p *Producer) Serve() {
 			if p.appLogWriter != nil {
 				p.appLogWriter.Close()
 			}
+			p.stopAppLogSinks()
+			p.stopTracer()
 
 			if !p.stopChClosed {
 				close(p.stopCh)
@@ -411,6 +443,8 @@ func (p *Producer) Stop(context string) {
 	if p.appLogWriter != nil {
 		p.appLogWriter.Close()
 	}
+	p.stopAppLogSinks()
+	p.stopTracer()
 
 	logging.Infof("%s [%s:%d] Closed function log writer handle",
 		logPrefix, p.appName, p.LenRunningConsumers())
@@ -629,6 +663,8 @@ func (p *Producer) KillAndRespawnEventingConsumer(c common.EventingConsumer) {
 
 	p.workerSpawnCounter++
 
+	p.recordWorkerCrash(c)
+
 	consumerIndex := c.Index()
 
 	p.runningConsumersRWMutex.Lock()
@@ -819,6 +855,13 @@ func (p *Producer) updateStats() {
 				return
 			}
 
+			err = p.updateBackfillJobStatus()
+			if err == common.ErrRetryTimeout {
+				logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+				p.updateStatsTicker.Stop()
+				return
+			}
+
 		case <-p.stopCh:
 			logging.Infof("%s [%s:%d] Got message on stop chan, exiting", logPrefix, p.appName, p.LenRunningConsumers())
 			p.updateStatsTicker.Stop()
@@ -844,8 +887,102 @@ func (p *Producer) updateAppLogSetting(settings map[string]interface{}) {
 		p.appLogMaxFiles = int64(val.(float64))
 	}
 
+	if val, ok := settings["app_log_compress"]; ok {
+		p.appLogCompress = val.(bool)
+	}
+
+	if val, ok := settings["app_log_max_age"]; ok {
+		p.appLogMaxAge = int64(val.(float64))
+	}
+
+	if val, ok := settings["app_log_rotation_interval"]; ok {
+		p.appLogInterval = val.(string)
+	}
+
+	if val, ok := settings["app_log_sinks"]; ok {
+		p.appLogSinksRWMutex.Lock()
+		p.appLogSinkURLs = util.ToStringArray(val)
+		p.appLogSinksRWMutex.Unlock()
+	}
+
+	if val, ok := settings["trace_sample_rate"]; ok {
+		p.setTraceSampleRate(val.(float64))
+	}
+
+	if val, ok := settings["trace_export_url"]; ok {
+		p.tracerRWMutex.Lock()
+		p.traceExportURL = val.(string)
+		p.tracerRWMutex.Unlock()
+	}
+
 	logger := p.appLogWriter.(*appLogCloser)
-	updateApplogSetting(logger, p.appLogMaxFiles, p.appLogMaxSize)
+	updateApplogSetting(logger, p.appLogSettings())
+	p.updateAppLogSinks()
+	p.updateTracer()
+}
+
+// Opens sinks application log is to be forwarded to and stops ones it no longer is
+func (p *Producer) updateAppLogSinks() {
+	logPrefix := "Producer::updateAppLogSinks"
+
+	p.appLogSinksRWMutex.Lock()
+	urls := p.appLogSinkURLs
+	if urls == nil {
+		urls = p.defaultAppLogSinkURLs
+	}
+
+	current := make(map[string]*appLogSink)
+	for _, sink := range p.appLogSinks {
+		current[sink.rawURL] = sink
+	}
+
+	sinks := make([]*appLogSink, 0, len(urls))
+	for _, rawURL := range urls {
+		if sink, ok := current[rawURL]; ok {
+			sinks = append(sinks, sink)
+			delete(current, rawURL)
+			continue
+		}
+
+		sink, err := newAppLogSink(rawURL, p.appName)
+		if err != nil {
+			logging.Errorf("%s [%s:%d] Failed to open app log sink: %rs, err: %v",
+				logPrefix, p.appName, p.LenRunningConsumers(), util.RedactURL(rawURL), err)
+			continue
+		}
+		logging.Infof("%s [%s:%d] Forwarding app log to sink: %rs",
+			logPrefix, p.appName, p.LenRunningConsumers(), sink.name)
+		sinks = append(sinks, sink)
+	}
+	p.appLogSinks = sinks
+	p.appLogSinksRWMutex.Unlock()
+
+	for _, sink := range current {
+		logging.Infof("%s [%s:%d] Stopped forwarding app log to sink: %rs",
+			logPrefix, p.appName, p.LenRunningConsumers(), sink.name)
+		sink.stop()
+	}
+}
+
+func (p *Producer) stopAppLogSinks() {
+	p.appLogSinksRWMutex.Lock()
+	sinks := p.appLogSinks
+	p.appLogSinks = nil
+	p.appLogSinksRWMutex.Unlock()
+
+	for _, sink := range sinks {
+		sink.stop()
+	}
+}
+
+func (p *Producer) appLogSettings() *appLogSettings {
+	return &appLogSettings{
+		compress: p.appLogCompress,
+		interval: p.appLogInterval,
+		maxAge:   time.Duration(p.appLogMaxAge) * time.Second,
+		maxFiles: p.appLogMaxFiles,
+		maxSize:  p.appLogMaxSize,
+	}
 }
 
 func (p *Producer) pollForDeletedVbs() {
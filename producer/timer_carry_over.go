@@ -0,0 +1,304 @@
+package producer
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/eventing/timers"
+	"github.com/couchbase/eventing/util"
+)
+
+// Name and parameter list following the function keyword in a declaration
+var callbackDeclRegex = regexp.MustCompile(`^\s+([A-Za-z_$][\w$]*)\s*\(`)
+
+func (p *Producer) carryingOverTimers() bool {
+	return p.handlerConfig.CarryOverTimers && p.app.UsingTimer
+}
+
+// Key of blob recording where timers were left at undeploy. It's keyed by function name,
+// rather than by metadata prefix, as prefix changes when function is recreated
+func (p *Producer) timerCarryOverKey() common.Key {
+	return common.NewKey(p.app.UserPrefix, "timers_carry_over", p.appName)
+}
+
+// Records metadata prefix of timers left behind at undeploy when carrying over timers, and
+// forgets any recorded earlier otherwise as those timers are cleaned up now
+func (p *Producer) recordTimerCarryOver() {
+	logPrefix := "Producer::recordTimerCarryOver"
+
+	if p.metadataBucketHandle == nil {
+		return
+	}
+
+	key := p.timerCarryOverKey()
+	if !p.carryingOverTimers() {
+		util.Retry(util.NewFixedBackoff(bucketOpRetryInterval), &p.retryCount, deleteOpCallback, p, key.Raw())
+		return
+	}
+
+	blob := &timerCarryOverBlob{
+		Prefix:              p.GetMetadataPrefix(),
+		UndeployedTimestamp: time.Now().Format(time.RFC3339),
+	}
+
+	err := util.Retry(util.NewFixedBackoff(bucketOpRetryInterval), &p.retryCount, setOpCallback, p, key, blob)
+	if err == common.ErrRetryTimeout {
+		logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+		return
+	}
+
+	logging.Infof("%s [%s:%d] Leaving timers behind under prefix: %s",
+		logPrefix, p.appName, p.LenRunningConsumers(), blob.Prefix)
+}
+
+// Picks up timers left behind at last undeploy, for vbuckets owned by local eventing node.
+// Timers left under a different metadata prefix are moved to current one, and timers whose
+// callback isn't declared in handler code are reported to application log. Runs before
+// consumers are spawned, so nothing else touches the timer stores meanwhile
+func (p *Producer) carryOverTimers() error {
+	logPrefix := "Producer::carryOverTimers"
+
+	if !p.carryingOverTimers() {
+		return nil
+	}
+
+	blob := &timerCarryOverBlob{}
+	err := util.Retry(util.NewFixedBackoff(bucketOpRetryInterval), &p.retryCount, getOpCallback, p, p.timerCarryOverKey(), blob)
+	if err == common.ErrRetryTimeout {
+		logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+		return err
+	}
+
+	if blob.Prefix == "" {
+		logging.Infof("%s [%s:%d] No timers left behind by earlier deployment", logPrefix, p.appName, p.LenRunningConsumers())
+		return nil
+	}
+
+	hostAddress := net.JoinHostPort(util.Localhost(), p.GetNsServerPort())
+	eventingNodeAddr, err := util.CurrentEventingNodeAddress(p.auth, hostAddress)
+	if err != nil {
+		logging.Errorf("%s [%s:%d] Failed to get address for current eventing node, err: %v",
+			logPrefix, p.appName, p.LenRunningConsumers(), err)
+		return err
+	}
+
+	// Vbuckets carried over before this node restarted are skipped
+	done := make(map[uint16]struct{})
+	for _, vb := range blob.CarriedVbs {
+		done[vb] = struct{}{}
+	}
+
+	vbs := make([]uint16, 0)
+	p.vbEventingNodeAssignRWMutex.RLock()
+	for vb, node := range p.vbEventingNodeAssignMap {
+		if _, ok := done[vb]; !ok && node == eventingNodeAddr {
+			vbs = append(vbs, vb)
+		}
+	}
+	p.vbEventingNodeAssignRWMutex.RUnlock()
+	sort.Sort(util.Uint16Slice(vbs))
+
+	callbacks := topLevelFunctions(p.app.AppCode)
+
+	from, to := blob.Prefix, p.GetMetadataPrefix()
+	logging.Infof("%s [%s:%d] Carrying over timers left at %s from prefix: %s to prefix: %s, vbs len: %d dump: %s",
+		logPrefix, p.appName, p.LenRunningConsumers(), blob.UndeployedTimestamp, from, to, len(vbs), util.Condense(vbs))
+
+	connStr := "couchbase://" + strings.Join(p.KvHostPorts(), ",")
+	if util.IsIPv6() {
+		connStr += "?ipv6=allow"
+	}
+
+	missing := make(map[string]uint64)
+	var carried uint64
+
+	for _, vb := range vbs {
+		count, err := p.carryOverVbTimers(int(vb), from, to, connStr, callbacks, missing)
+		if err != nil {
+			logging.Errorf("%s [%s:%d] vb: %d failed to carry over timers, err: %v",
+				logPrefix, p.appName, p.LenRunningConsumers(), vb, err)
+			return err
+		}
+		carried += count
+	}
+
+	err = util.Retry(util.NewFixedBackoff(bucketOpRetryInterval), &p.retryCount, completeTimerCarryOverCallback, p, vbs)
+	if err == common.ErrRetryTimeout {
+		logging.Errorf("%s [%s:%d] Exiting due to timeout", logPrefix, p.appName, p.LenRunningConsumers())
+		return err
+	}
+
+	var missingCount uint64
+	names := make([]string, 0, len(missing))
+	for callback, count := range missing {
+		names = append(names, fmt.Sprintf("%s (%d)", callback, count))
+		missingCount += count
+	}
+	sort.Strings(names)
+
+	atomic.StoreUint64(&p.timersCarriedOver, carried)
+	atomic.StoreUint64(&p.timersMissingCallback, missingCount)
+
+	logging.Infof("%s [%s:%d] Carried over timers: %d missing callback: %d",
+		logPrefix, p.appName, p.LenRunningConsumers(), carried, missingCount)
+
+	if carried > 0 {
+		p.WriteAppLog(fmt.Sprintf("Carried over %d timers from earlier deployment", carried))
+	}
+	if missingCount > 0 {
+		p.WriteAppLog(fmt.Sprintf("%d carried over timers have callbacks that no longer exist in handler code and will fail when they fire: %s",
+			missingCount, strings.Join(names, ", ")))
+	}
+	return nil
+}
+
+// Moves pending timers of a vbucket from one metadata prefix to another, or only inspects them
+// if prefix is unchanged, counting timers per callback missing from handler code
+func (p *Producer) carryOverVbTimers(vb int, from, to, connStr string, callbacks map[string]bool,
+	missing map[string]uint64) (uint64, error) {
+
+	err := timers.Create(to, vb, connStr, p.metadatabucket, p.handlerConfig.TimerResolution)
+	if err != nil {
+		return 0, err
+	}
+	dest, _ := timers.Fetch(to, vb)
+	defer dest.Free()
+
+	src := dest
+	if from != to {
+		err = timers.Create(from, vb, connStr, p.metadatabucket, p.handlerConfig.TimerResolution)
+		if err != nil {
+			return 0, err
+		}
+		src, _ = timers.Fetch(from, vb)
+	}
+
+	var count uint64
+	iter := src.ScanRange(0, 0)
+	for {
+		entry, err := iter.ScanNext()
+		if err != nil {
+			return count, err
+		}
+		if entry == nil {
+			break
+		}
+		count++
+
+		context, _ := entry.Context.(map[string]interface{})
+		callback, _ := context["callback"].(string)
+		if !callbacks[callback] {
+			missing[callback]++
+		}
+
+		if src == dest {
+			continue
+		}
+
+		// Timers stored before references were kept can only be told apart by their context key
+		reference, _ := context["reference"].(string)
+		if reference == "" {
+			reference = entry.ContextRef
+		}
+
+		due := entry.RequestedDue
+		if due == 0 {
+			due = entry.AlarmDue
+		}
+
+		if err = dest.Set(due, callback+":"+reference, entry.Context); err != nil {
+			return count, err
+		}
+		if err = src.Delete(entry); err != nil {
+			return count, err
+		}
+	}
+
+	if src != dest {
+		return count, src.Drop()
+	}
+	return count, nil
+}
+
+// Names of functions declared at top level of handler code, which timer callbacks have to be.
+// Comments, string and template literals are skipped, and functions declared within braces
+// or parentheses don't count. Regex literals aren't told apart from division, so a quote or
+// brace in one can throw this off
+func topLevelFunctions(code string) map[string]bool {
+	functions := make(map[string]bool)
+	depth := 0
+
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case strings.HasPrefix(code[i:], "//"):
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				return functions
+			}
+			i += end + 1
+
+		case strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return functions
+			}
+			i += end + 4
+
+		case c == '\'' || c == '"' || c == '`':
+			i = skipQuoted(code, i)
+
+		case c == '{' || c == '(':
+			depth++
+			i++
+
+		case c == '}' || c == ')':
+			if depth > 0 {
+				depth--
+			}
+			i++
+
+		case isIdentByte(c):
+			start := i
+			for i < len(code) && isIdentByte(code[i]) {
+				i++
+			}
+			if depth > 0 || code[start:i] != "function" {
+				continue
+			}
+			if match := callbackDeclRegex.FindStringSubmatch(code[i:]); match != nil {
+				functions[match[1]] = true
+			}
+
+		default:
+			i++
+		}
+	}
+
+	return functions
+}
+
+// Returns position past the literal quoted by the character at start
+func skipQuoted(code string, start int) int {
+	quote := code[start]
+	for i := start + 1; i < len(code); i++ {
+		switch code[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return len(code)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,34 @@
+package producer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTopLevelFunctions(t *testing.T) {
+	code := `
+function OnUpdate(doc, meta) {
+	function nested(x) { return x; }
+	createTimer(reminder, new Date(), meta.id, {msg: "function quoted() {"});
+}
+
+// function commentedOut() {}
+/* function blockCommented() {
+} */
+
+var expr = (function named() {});
+var tmpl = ` + "`function inTemplate() { ${doc}`" + `;
+
+function reminder(context) {
+	log('}', context);
+}
+
+function	spaced  (a, b) {}
+async function OnDelete(meta) {}
+`
+
+	expected := map[string]bool{"OnUpdate": true, "reminder": true, "spaced": true, "OnDelete": true}
+	if functions := topLevelFunctions(code); !reflect.DeepEqual(functions, expected) {
+		t.Errorf("Expected top level functions %v, got %v", expected, functions)
+	}
+}
@@ -0,0 +1,74 @@
+package producer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+)
+
+// CheckTimers checks consistency of timer stores of vbuckets owned by local eventing node,
+// repairing them if asked to. Consumers check their stores in parallel, while checks of a
+// function run one at a time
+func (p *Producer) CheckTimers(repair bool) *common.TimerCheckReport {
+	logPrefix := "Producer::CheckTimers"
+
+	p.timerCheckMutex.Lock()
+	defer p.timerCheckMutex.Unlock()
+
+	report := &common.TimerCheckReport{
+		FunctionName: p.appName,
+		Repair:       repair,
+		Stores:       make([]*common.TimerStoreReport, 0),
+		Timestamp:    time.Now().Format(time.RFC3339),
+	}
+
+	consumers := p.getConsumers()
+	consumerReports := make([][]*common.TimerStoreReport, len(consumers))
+
+	var wg sync.WaitGroup
+	for i, c := range consumers {
+		wg.Add(1)
+		go func(i int, c common.EventingConsumer) {
+			defer wg.Done()
+			consumerReports[i] = c.CheckTimers(repair)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for _, stores := range consumerReports {
+		for _, store := range stores {
+			report.Add(store)
+		}
+	}
+
+	logging.Infof("%s [%s:%d] Checked timer stores: %d alarms: %d orphan alarms: %d orphan contexts: %d span issues: %d orphans removed: %d spans rewritten: %d",
+		logPrefix, p.appName, p.LenRunningConsumers(), report.StoresChecked, report.AlarmsChecked, report.OrphanAlarms,
+		report.OrphanContexts, report.SpanIssues, report.OrphansRemoved, report.SpansRewritten)
+	return report
+}
+
+// Periodically checks timer stores as per timer_check_interval, reporting inconsistencies to
+// application log
+func (p *Producer) checkTimersRoutine() {
+	ticker := time.NewTicker(time.Duration(p.handlerConfig.TimerCheckInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			report := p.CheckTimers(p.handlerConfig.TimerCheckRepair)
+			if len(report.Stores) == 0 {
+				continue
+			}
+
+			p.WriteAppLog(fmt.Sprintf("Timer consistency check found %d orphan alarms, %d orphan contexts and %d span issues across %d vbuckets, removed %d orphans and rewrote %d spans",
+				report.OrphanAlarms, report.OrphanContexts, report.SpanIssues, len(report.Stores), report.OrphansRemoved, report.SpansRewritten))
+
+		case <-p.stopCh:
+			return
+		}
+	}
+}
@@ -19,6 +19,7 @@ const (
 	metakvRebalanceTokenPath = metakvEventingPath + "rebalanceToken/"
 	metakvRebalanceProgress  = metakvEventingPath + "rebalanceProgress/"
 	metakvAppsRetryPath      = metakvEventingPath + "retry/"
+	metakvAppsReprocessPath  = metakvEventingPath + "reprocess/"
 	metakvTempAppsPath       = metakvEventingPath + "tempApps/"
 	metakvChecksumPath       = metakvEventingPath + "checksum/"
 	metakvTempChecksumPath   = metakvEventingPath + "tempchecksum/"
@@ -37,11 +38,25 @@ const (
 
 const (
 	headerKey                = "status"
+	failedNodesHeaderKey     = "failed_nodes"
 	maxApplicationNameLength = 100
 	maxAliasLength           = 20 // Technically, there isn't any limit on a JavaScript variable length.
 	maxPrefixLength          = 16
+	maxVbuckets              = 1024
 
 	rebalanceStalenessCounter = 200
+
+	// Page size bounds for listing pending timers
+	defaultTimersPageSize = 100
+	maxTimersPageSize     = 1000
+
+	// Bounds on entries of application log returned, and how often a followed log is polled
+	defaultAppLogLimit   = 100
+	maxAppLogLimit       = 1000
+	appLogFollowInterval = time.Second
+
+	// How long latency histograms gathered from eventing nodes are reused across stats calls
+	clusterLatencyCacheTTL = 10 * time.Second
 )
 
 var (
@@ -53,6 +68,7 @@ type ServiceMgr struct {
 	adminHTTPPort     string
 	adminSSLPort      string
 	auth              string
+	clusterLatency    *clusterLatencyCache
 	graph             *bucketMultiDiGraph
 	certFile          string
 	config            util.ConfigHolder
@@ -66,6 +82,7 @@ type ServiceMgr struct {
 	keyFile           string
 	lcbCredsCounter   int64
 	mu                *sync.RWMutex
+	statsHistory      *statsHistory
 	statsWritten      bool
 	uuid              string
 
@@ -88,6 +105,13 @@ type ServiceMgr struct {
 	errorCodes    map[int]errorPayload
 }
 
+// Latency histograms of functions merged across eventing nodes, as last gathered
+type clusterLatencyCache struct {
+	sync.Mutex
+	histograms map[string]map[string]*util.Histogram
+	fetchedAt  time.Time
+}
+
 type doneCallback func(err error, cancel <-chan struct{})
 type progressCallback func(progress float64, cancel <-chan struct{})
 
@@ -165,7 +189,9 @@ type backlogStat struct {
 }
 
 type stats struct {
+	AppLogSinkStats                 interface{} `json:"app_log_sink_stats,omitempty"`
 	CheckpointBlobDump              interface{} `json:"checkpoint_blob_dump,omitempty"`
+	ClusterLatencyHistogramStats    interface{} `json:"cluster_latency_histogram_stats,omitempty"`
 	DCPFeedBoundary                 interface{} `json:"dcp_feed_boundary"`
 	DocTimerDebugStats              interface{} `json:"doc_timer_debug_stats,omitempty"`
 	EventProcessingStats            interface{} `json:"event_processing_stats,omitempty"`
@@ -176,16 +202,22 @@ type stats struct {
 	GocbCredsRequestCounter         interface{} `json:"gocb_creds_request_counter,omitempty"`
 	FunctionID                      interface{} `json:"function_id,omitempty"`
 	InternalVbDistributionStats     interface{} `json:"internal_vb_distribution_stats,omitempty"`
+	LatencyHistogramStats           interface{} `json:"latency_histogram_stats,omitempty"`
 	LatencyPercentileStats          interface{} `json:"latency_percentile_stats,omitempty"`
 	LatencyStats                    interface{} `json:"latency_stats,omitempty"`
 	CurlLatencyStats                interface{} `json:"curl_latency_stats,omitempty"`
+	DcpLatencyStats                 interface{} `json:"dcp_latency_stats,omitempty"`
 	LcbCredsRequestCounter          interface{} `json:"lcb_creds_request_counter,omitempty"`
 	LcbExceptionStats               interface{} `json:"lcb_exception_stats,omitempty"`
 	PlannerStats                    interface{} `json:"planner_stats,omitempty"`
+	MemoryStats                     interface{} `json:"memory_stats,omitempty"`
 	MetastoreStats                  interface{} `json:"metastore_stats,omitempty"`
 	RebalanceStats                  interface{} `json:"rebalance_stats,omitempty"`
 	SeqsProcessed                   interface{} `json:"seqs_processed,omitempty"`
 	SpanBlobDump                    interface{} `json:"span_blob_dump,omitempty"`
+	TimerLatenessPercentileStats    interface{} `json:"timer_lateness_percentile_stats,omitempty"`
+	TimerLatenessStats              interface{} `json:"timer_lateness_stats,omitempty"`
+	TimerMaxLateness                interface{} `json:"timer_max_lateness,omitempty"`
 	VbDcpEventsRemaining            interface{} `json:"dcp_event_backlog_per_vb,omitempty"`
 	VbDistributionStatsFromMetadata interface{} `json:"vb_distribution_stats_from_metadata,omitempty"`
 	VbSeqnoStats                    interface{} `json:"vb_seq_no_stats,omitempty"`
@@ -201,12 +233,20 @@ type retry struct {
 }
 
 type appStatus struct {
-	CompositeStatus       string `json:"composite_status"`
-	Name                  string `json:"name"`
-	NumBootstrappingNodes int    `json:"num_bootstrapping_nodes"`
-	NumDeployedNodes      int    `json:"num_deployed_nodes"`
-	DeploymentStatus      bool   `json:"deployment_status"`
-	ProcessingStatus      bool   `json:"processing_status"`
+	BackfillJob           *common.BackfillJobStatus `json:"backfill_job,omitempty"`
+	CompositeStatus       string                    `json:"composite_status"`
+	CrashLoopFailed       bool                      `json:"crash_loop_failed,omitempty"`
+	Name                  string                    `json:"name"`
+	NumBootstrappingNodes int                       `json:"num_bootstrapping_nodes"`
+	NumDeployedNodes      int                       `json:"num_deployed_nodes"`
+	DeploymentStatus      bool                      `json:"deployment_status"`
+	ProcessingStatus      bool                      `json:"processing_status"`
+}
+
+type timerListResponse struct {
+	Next       string                 `json:"next,omitempty"`
+	NodeErrors map[string]string      `json:"node_errors,omitempty"`
+	Timers     []*common.PendingTimer `json:"timers"`
 }
 
 type appStatusResponse struct {
@@ -16,9 +16,9 @@ import (
 	"runtime"
 	"runtime/debug"
 	"runtime/trace"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/couchbase/cbauth"
@@ -798,6 +798,52 @@ func (m *ServiceMgr) getLatencyStats(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, "Function: %s not deployed", appName)
 }
 
+// Latency histograms of a function on this node, keyed by kind of latency
+func (m *ServiceMgr) latencyHistograms(appName string) map[string]map[string]uint64 {
+	return map[string]map[string]uint64{
+		"curl":      m.superSup.GetCurlLatencyStats(appName),
+		"dcp":       m.superSup.GetDcpLatencyStats(appName),
+		"execution": m.superSup.GetLatencyStats(appName),
+	}
+}
+
+// Reports latency histograms of functions deployed on this node, for them to be merged
+// across nodes
+func (m *ServiceMgr) getLatencyHistograms(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::getLatencyHistograms"
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		return
+	}
+
+	histograms := make(map[string]map[string]map[string]uint64)
+	for _, app := range m.getTempStoreAll() {
+		if m.checkIfDeployed(app.Name) {
+			histograms[app.Name] = m.latencyHistograms(app.Name)
+		}
+	}
+
+	data, err := json.Marshal(histograms)
+	if err != nil {
+		info := &runtimeInfo{}
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("failed to marshal latency histograms, err: %v", err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	fmt.Fprintf(w, "%s", string(data))
+}
+
+func summarizeLatencies(histograms map[string]*util.Histogram) map[string]*common.LatencySummary {
+	summaries := make(map[string]*common.LatencySummary)
+	for kind, h := range histograms {
+		summaries[kind] = h.Summary()
+	}
+	return summaries
+}
+
 func (m *ServiceMgr) getExecutionStats(w http.ResponseWriter, r *http.Request) {
 	logPrefix := "ServiceMgr::getExecutionStats"
 	if !m.validateAuth(w, r, EventingPermissionManage) {
@@ -885,6 +931,357 @@ func (m *ServiceMgr) getSeqsProcessed(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func (m *ServiceMgr) getCrashHistory(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::getCrashHistory"
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		return
+	}
+
+	appName := r.URL.Query().Get("name")
+	info := &runtimeInfo{}
+
+	if !m.checkIfDeployed(appName) {
+		info.Code = m.statusCodes.errAppNotDeployed.Code
+		info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	history, err := m.superSup.CrashHistory(appName)
+	if err != nil {
+		info.Code = m.statusCodes.errGetCrashHistory.Code
+		info.Info = fmt.Sprintf("Function: %s failed to fetch crash history, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("Function: %s failed to marshal crash history, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	fmt.Fprintf(w, "%s", string(data))
+}
+
+func (m *ServiceMgr) getTimers(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::getTimers"
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		return
+	}
+
+	params := r.URL.Query()
+	appName := params.Get("name")
+	info := &runtimeInfo{}
+
+	if !m.checkIfDeployed(appName) {
+		info.Code = m.statusCodes.errAppNotDeployed.Code
+		info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	filter, info := m.validateTimerFilter(params, 0)
+	if info.Code != m.statusCodes.ok.Code {
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	pending, err := m.superSup.ListTimers(appName, filter)
+	if err != nil {
+		info.Code = m.statusCodes.errGetTimers.Code
+		info.Info = fmt.Sprintf("Function: %s failed to list timers, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	data, err := json.Marshal(pending)
+	if err != nil {
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("Function: %s failed to marshal timers, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	fmt.Fprintf(w, "%s", string(data))
+}
+
+// Reads application log of a function on this node, whether or not it's deployed
+func (m *ServiceMgr) getAppLog(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::getAppLog"
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		return
+	}
+
+	params := r.URL.Query()
+	appName := params.Get("name")
+
+	filter, info := m.validateAppLogFilter(params)
+	if info.Code != m.statusCodes.ok.Code {
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	path, info := m.appLogPath(appName)
+	if info.Code != m.statusCodes.ok.Code {
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	tail, err := util.ReadAppLog(path, filter)
+	if err != nil {
+		info.Code = m.statusCodes.errGetAppLog.Code
+		info.Info = fmt.Sprintf("Function: %s failed to read application log, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	data, err := json.Marshal(tail)
+	if err != nil {
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("Function: %s failed to marshal application log, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	fmt.Fprintf(w, "%s", string(data))
+}
+
+// Application log of a function is at app_log_dir setting of it, if set, as producer opens it
+func (m *ServiceMgr) appLogPath(appName string) (path string, info *runtimeInfo) {
+	info = &runtimeInfo{}
+
+	data, err := util.ReadAppContent(metakvTempAppsPath, metakvTempChecksumPath, appName)
+	if err != nil || data == nil {
+		info.Code = m.statusCodes.errAppNotFoundTs.Code
+		info.Info = fmt.Sprintf("Function: %s not found", appName)
+		return
+	}
+
+	var app application
+	if err = json.Unmarshal(data, &app); err != nil {
+		info.Code = m.statusCodes.errUnmarshalPld.Code
+		info.Info = fmt.Sprintf("Function: %s failed to unmarshal definition, err: %v", appName, err)
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	if dir, ok := app.Settings["app_log_dir"].(string); ok {
+		path = fmt.Sprintf("%s/%s", dir, appName)
+		return
+	}
+	path = fmt.Sprintf("%s/%s.log", m.config.Load()["eventing_dir"], appName)
+	return
+}
+
+// Streams application log of a function as it gets written, an entry per line, polling each
+// eventing node for what got written past where it was last read from
+func (m *ServiceMgr) followAppLog(w http.ResponseWriter, r *http.Request, params url.Values, tails map[string]*common.AppLogTail, limit int) {
+	logPrefix := "ServiceMgr::followAppLog"
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(w)
+	write := func(entries []*common.AppLogEntry) bool {
+		for _, entry := range entries {
+			if err := enc.Encode(entry); err != nil {
+				return false
+			}
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	if !write(util.MergeAppLogs(tails, limit)) {
+		return
+	}
+
+	offsets := make(map[string]int64)
+	for node, tail := range tails {
+		offsets[node] = tail.Offset
+	}
+	params.Del("limit")
+	params.Del("since")
+	appName := params.Get("name")
+
+	ticker := time.NewTicker(appLogFollowInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+
+		// Nodes are read from offsets of their own, so each is polled by a request of its own
+		latest := make(map[string]*common.AppLogTail)
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for node, offset := range offsets {
+			params.Set("offset", strconv.FormatInt(offset, 10))
+			wg.Add(1)
+			go func(node, urlSuffix string) {
+				defer wg.Done()
+
+				nodeTails, errs := util.GetAppLog(urlSuffix, []string{node})
+				if len(errs) > 0 {
+					logging.Warnf("%s Function: %s failed to follow application log on node: %rs, err: %v",
+						logPrefix, appName, node, errs[node])
+					return
+				}
+
+				mu.Lock()
+				latest[node] = nodeTails[node]
+				mu.Unlock()
+			}(node, "/getAppLog?"+params.Encode())
+		}
+		wg.Wait()
+
+		for node, tail := range latest {
+			offsets[node] = tail.Offset
+		}
+
+		if !write(util.MergeAppLogs(latest, 0)) {
+			return
+		}
+	}
+}
+
+func (m *ServiceMgr) cancelTimer(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::cancelTimer"
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		return
+	}
+
+	if r.Method != "DELETE" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	appName := params.Get("name")
+	callback := params.Get("callback")
+	reference := params.Get("reference")
+	info := &runtimeInfo{}
+
+	if !m.checkIfDeployed(appName) {
+		info.Code = m.statusCodes.errAppNotDeployed.Code
+		info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	cancelled, err := m.superSup.CancelTimer(appName, callback, reference)
+	if err != nil {
+		info.Code = m.statusCodes.errCancelTimer.Code
+		info.Info = fmt.Sprintf("Function: %s failed to cancel timer, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	fmt.Fprintf(w, "%t", cancelled)
+}
+
+func (m *ServiceMgr) checkTimers(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::checkTimers"
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		return
+	}
+
+	// Repair deletes documents, so checks aren't taken over GET
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	appName := params.Get("name")
+	repair := params.Get("repair") == "true"
+	info := &runtimeInfo{}
+
+	if !m.checkIfDeployed(appName) {
+		info.Code = m.statusCodes.errAppNotDeployed.Code
+		info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	report, err := m.superSup.CheckTimers(appName, repair)
+	if err != nil {
+		info.Code = m.statusCodes.errCheckTimers.Code
+		info.Info = fmt.Sprintf("Function: %s failed to check timers, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("Function: %s failed to marshal timer check report, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	fmt.Fprintf(w, "%s", string(data))
+}
+
+func (m *ServiceMgr) getRollbackHistory(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::getRollbackHistory"
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		return
+	}
+
+	appName := r.URL.Query().Get("name")
+	info := &runtimeInfo{}
+
+	if !m.checkIfDeployed(appName) {
+		info.Code = m.statusCodes.errAppNotDeployed.Code
+		info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	history, err := m.superSup.RollbackHistory(appName)
+	if err != nil {
+		info.Code = m.statusCodes.errGetRollbackHistory.Code
+		info.Info = fmt.Sprintf("Function: %s failed to fetch rollback history, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	data, err := json.Marshal(history)
+	if err != nil {
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("Function: %s failed to marshal rollback history, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	fmt.Fprintf(w, "%s", string(data))
+}
+
 func (m *ServiceMgr) setSettingsHandler(w http.ResponseWriter, r *http.Request) {
 	logPrefix := "ServiceMgr::setSettingsHandler"
 	if !m.validateAuth(w, r, EventingPermissionManage) {
@@ -954,7 +1351,7 @@ func (m *ServiceMgr) setSettings(appName string, data []byte) (info *runtimeInfo
 		return
 	}
 
-	logging.Infof("%s Function: %s settings params: %+v", logPrefix, appName, settings)
+	logging.Infof("%s Function: %s settings params: %+v", logPrefix, appName, util.RedactURLSettings(settings))
 
 	_, procStatExists := settings["processing_status"]
 	_, depStatExists := settings["deployment_status"]
@@ -980,6 +1377,12 @@ func (m *ServiceMgr) setSettings(appName string, data []byte) (info *runtimeInfo
 	processingStatus, pOk := app.Settings["processing_status"].(bool)
 	deploymentStatus, dOk := app.Settings["deployment_status"].(bool)
 
+	// Completion of an earlier run of backfill job or crash loop failure isn't relevant once it's deployed again
+	if dOk && deploymentStatus {
+		delete(app.Settings, "backfill_completed")
+		delete(app.Settings, "crash_loop_failed")
+	}
+
 	logging.Infof("%s Function: %s deployment status: %t processing status: %t",
 		logPrefix, appName, deploymentStatus, processingStatus)
 
@@ -1023,7 +1426,7 @@ func (m *ServiceMgr) setSettings(appName string, data []byte) (info *runtimeInfo
 
 		if deploymentStatus && processingStatus && m.superSup.GetAppState(appName) == common.AppStatePaused {
 			switch filterFeedBoundary(settings) {
-			case common.DcpFromNow, common.DcpEverything:
+			case common.DcpFromNow, common.DcpEverything, common.DcpFromSeqNos, common.DcpFromTimestamp:
 				info.Code = m.statusCodes.errInvalidConfig.Code
 				info.Info = fmt.Sprintf("Function: %s only from_prior feed boundary is allowed during resume", appName)
 				logging.Errorf("%s %s", logPrefix, info.Info)
@@ -1540,7 +1943,7 @@ func (m *ServiceMgr) savePrimaryStore(app *application) (info *runtimeInfo) {
 
 	if m.superSup.GetAppState(app.Name) == common.AppStatePaused {
 		switch filterFeedBoundary(app.Settings) {
-		case common.DcpFromNow, common.DcpEverything:
+		case common.DcpFromNow, common.DcpEverything, common.DcpFromSeqNos, common.DcpFromTimestamp:
 			info.Code = m.statusCodes.errInvalidConfig.Code
 			info.Info = fmt.Sprintf("Function: %s only from_prior feed boundary is allowed during resume", app.Name)
 			logging.Errorf("%s %s", logPrefix, info.Info)
@@ -1883,7 +2286,7 @@ func (m *ServiceMgr) getConfig() (c common.Config, info *runtimeInfo) {
 		}
 	}
 
-	logging.Infof("%s Retrieving config from metakv: %+v", logPrefix, c)
+	logging.Infof("%s Retrieving config from metakv: %+v", logPrefix, util.RedactURLSettings(c))
 	info.Code = m.statusCodes.ok.Code
 	return
 }
@@ -1905,7 +2308,7 @@ func (m *ServiceMgr) saveConfig(c common.Config) (info *runtimeInfo) {
 		return
 	}
 
-	logging.Infof("%s Saving config into metakv: %v", logPrefix, c)
+	logging.Infof("%s Saving config into metakv: %v", logPrefix, util.RedactURLSettings(c))
 	util.Retry(util.NewFixedBackoff(metakvOpRetryInterval),
 		nil, metakvSetCallback, metakvConfigPath, data)
 	info.Code = m.statusCodes.ok.Code
@@ -2049,28 +2452,440 @@ func (m *ServiceMgr) assignFunctionInstanceID(functionName string, app *applicat
 			logging.Errorf("%s %s, err: %v", logPrefix, info.Info, err)
 			return fmt.Errorf("%s err: %v", info.Info, err)
 		}
-		prevApp := m.parseFunctionPayload(data, functionName)
-		app.FunctionInstanceID = prevApp.FunctionInstanceID
-		logging.Infof("%s Function: %s assigned previous FunctionInstanceID: %s", logPrefix, app.Name, app.FunctionInstanceID)
-	}
-	return nil
-}
+		prevApp := m.parseFunctionPayload(data, functionName)
+		app.FunctionInstanceID = prevApp.FunctionInstanceID
+		logging.Infof("%s Function: %s assigned previous FunctionInstanceID: %s", logPrefix, app.Name, app.FunctionInstanceID)
+	}
+	return nil
+}
+
+func (m *ServiceMgr) functionsHandler(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::functionsHandler"
+
+	w.Header().Set("Content-Type", "application/json")
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		cbauth.SendForbidden(w, EventingPermissionManage)
+		return
+	}
+
+	functions := regexp.MustCompile("^/api/v1/functions/?$")
+	functionsName := regexp.MustCompile("^/api/v1/functions/(.*[^/])/?$") // Match is agnostic of trailing '/'
+	functionsNameSettings := regexp.MustCompile("^/api/v1/functions/(.*[^/])/settings/?$")
+	functionsNameRetry := regexp.MustCompile("^/api/v1/functions/(.*[^/])/retry/?$")
+	functionsNameRollbacks := regexp.MustCompile("^/api/v1/functions/(.*[^/])/rollbacks/?$")
+	functionsNameCrashes := regexp.MustCompile("^/api/v1/functions/(.*[^/])/crashes/?$")
+	functionsNameReprocess := regexp.MustCompile("^/api/v1/functions/(.*[^/])/reprocess/?$")
+	functionsNameTimers := regexp.MustCompile("^/api/v1/functions/(.*[^/])/timers/?$")
+	functionsNameTimersCheck := regexp.MustCompile("^/api/v1/functions/(.*[^/])/timers/check/?$")
+	functionsNameTimer := regexp.MustCompile("^/api/v1/functions/(.*[^/])/timers/(.*[^/])/?$")
+	functionsNameAppLog := regexp.MustCompile("^/api/v1/functions/(.*[^/])/applog/?$")
+	functionsNameLag := regexp.MustCompile("^/api/v1/functions/(.*[^/])/lag/?$")
+
+	// Timers may be cancelled by a reference named check, so only POST is taken as a check
+	if match := functionsNameTimersCheck.FindStringSubmatch(r.URL.Path); len(match) != 0 && r.Method == "POST" {
+		appName := match[1]
+		info := &runtimeInfo{}
+
+		if !m.checkIfDeployed(appName) {
+			info.Code = m.statusCodes.errAppNotDeployed.Code
+			info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		repair := r.URL.Query().Get("repair") == "true"
+		if repair {
+			if info = m.checkLifeCycleOpsDuringRebalance(); info.Code != m.statusCodes.ok.Code {
+				m.sendErrorInfo(w, info)
+				return
+			}
+		}
+
+		params := url.Values{}
+		params.Set("name", appName)
+		params.Set("repair", strconv.FormatBool(repair))
+
+		util.Retry(util.NewFixedBackoff(time.Second), nil, getEventingNodesAddressesOpCallback, m)
+
+		report, errs := util.CheckTimers("/checkTimers?"+params.Encode(), m.eventingNodeAddrs)
+		if allNodesFailed(errs, m.eventingNodeAddrs) {
+			info.Code = m.statusCodes.errCheckTimers.Code
+			info.Info = fmt.Sprintf("failed to check timers, err: %v", errs)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+		report.FunctionName = appName
+		report.NodeErrors = errs.Messages()
+		report.Repair = repair
+
+		response, err := json.Marshal(report)
+		if err != nil {
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal timer check report, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		logging.Infof("%s Function: %s checked timers, repair: %t orphan alarms: %d orphan contexts: %d span issues: %d",
+			logPrefix, appName, repair, report.OrphanAlarms, report.OrphanContexts, report.SpanIssues)
+		m.sendNodesResponse(w, response, errs)
+	} else if match := functionsNameTimers.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+		info := &runtimeInfo{}
+
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !m.checkIfDeployed(appName) {
+			info.Code = m.statusCodes.errAppNotDeployed.Code
+			info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		params := r.URL.Query()
+		filter, info := m.validateTimerFilter(params, maxTimersPageSize)
+		if info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		// Each eventing node returns its earliest timers past the cursor, one more than
+		// the page holds to signal presence of a next page
+		params.Set("limit", strconv.Itoa(filter.Limit+1))
+		params.Set("name", appName)
+
+		util.Retry(util.NewFixedBackoff(time.Second), nil, getEventingNodesAddressesOpCallback, m)
+
+		pending, errs := util.GetPendingTimers("/getTimers?"+params.Encode(), m.eventingNodeAddrs)
+		if allNodesFailed(errs, m.eventingNodeAddrs) {
+			info.Code = m.statusCodes.errGetTimers.Code
+			info.Info = fmt.Sprintf("failed to fetch timers, err: %v", errs)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		resp := timerListResponse{NodeErrors: errs.Messages(), Timers: pending}
+		if len(pending) > filter.Limit {
+			resp.Timers = pending[:filter.Limit]
+			last := resp.Timers[filter.Limit-1]
+			resp.Next = formatTimerCursor(&common.TimerCursor{Due: last.Due, Seq: last.Seq, Vbucket: last.Vbucket})
+		}
+
+		response, err := json.Marshal(resp)
+		if err != nil {
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal timers, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		m.sendNodesResponse(w, response, errs)
+	} else if match := functionsNameTimer.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+		reference := match[2]
+		info := &runtimeInfo{}
+
+		if r.Method != "DELETE" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		callback := r.URL.Query().Get("callback")
+		if callback == "" {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = "callback of the timer to cancel is required"
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		if !m.checkIfDeployed(appName) {
+			info.Code = m.statusCodes.errAppNotDeployed.Code
+			info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		if info = m.checkLifeCycleOpsDuringRebalance(); info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		params := url.Values{}
+		params.Set("name", appName)
+		params.Set("callback", callback)
+		params.Set("reference", reference)
+
+		util.Retry(util.NewFixedBackoff(time.Second), nil, getEventingNodesAddressesOpCallback, m)
+
+		// Timer is with one of the nodes, so nodes failing only matter if none cancelled it
+		cancelled, errs := util.CancelTimer("/cancelTimer?"+params.Encode(), m.eventingNodeAddrs)
+		if !cancelled && len(errs) > 0 {
+			info.Code = m.statusCodes.errCancelTimer.Code
+			info.Info = fmt.Sprintf("failed to cancel timer, err: %v", errs)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		if !cancelled {
+			info.Code = m.statusCodes.errTimerNotFound.Code
+			info.Info = fmt.Sprintf("Function: %s has no pending timer with callback: %s reference: %s", appName, callback, reference)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		logging.Infof("%s Function: %s cancelled timer with callback: %s reference: %ru", logPrefix, appName, callback, reference)
+		w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+		fmt.Fprintf(w, "Function: %s timer cancelled", appName)
+	} else if match := functionsNameAppLog.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		params := r.URL.Query()
+		params.Del("offset")
+		filter, info := m.validateAppLogFilter(params)
+		if info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		if _, info = m.appLogPath(appName); info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		follow := params.Get("follow") == "true"
+		params.Del("follow")
+		params.Set("name", appName)
+		params.Set("limit", strconv.Itoa(filter.Limit))
+
+		util.Retry(util.NewFixedBackoff(time.Second), nil, getEventingNodesAddressesOpCallback, m)
+
+		tails, errs := util.GetAppLog("/getAppLog?"+params.Encode(), m.eventingNodeAddrs)
+		if allNodesFailed(errs, m.eventingNodeAddrs) {
+			info.Code = m.statusCodes.errGetAppLog.Code
+			info.Info = fmt.Sprintf("failed to read application log, err: %v", errs)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		if follow {
+			m.followAppLog(w, r, params, tails, filter.Limit)
+			return
+		}
+
+		response, err := json.Marshal(util.MergeAppLogs(tails, filter.Limit))
+		if err != nil {
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal application log, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		m.sendNodesResponse(w, response, errs)
+	} else if match := functionsNameLag.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+		info := &runtimeInfo{}
+
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !m.checkIfDeployed(appName) {
+			info.Code = m.statusCodes.errAppNotDeployed.Code
+			info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		util.Retry(util.NewFixedBackoff(time.Second), nil, getEventingNodesAddressesOpCallback, m)
+
+		query := url.Values{}
+		query.Set("name", appName)
+
+		lags, errs := util.GetVbLag("/getVbLag?"+query.Encode(), m.eventingNodeAddrs)
+		if allNodesFailed(errs, m.eventingNodeAddrs) {
+			info.Code = m.statusCodes.errGetVbLag.Code
+			info.Info = fmt.Sprintf("failed to gather vbucket lag, err: %v", errs)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		lags = mergeVbLags(lags)
+		report := &lagReport{
+			Function:   appName,
+			NodeErrors: errs.Messages(),
+			Summary:    summarizeVbLags(lags),
+			Vbuckets:   lags,
+		}
+
+		response, err := json.Marshal(report)
+		if err != nil {
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal vbucket lag, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		m.sendNodesResponse(w, response, errs)
+	} else if match := functionsNameReprocess.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+		info := &runtimeInfo{}
+
+		if r.Method != "POST" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !m.checkIfDeployed(appName) {
+			info.Code = m.statusCodes.errAppNotDeployed.Code
+			info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		if m.superSup.GetAppState(appName) == common.AppStatePaused {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("Function: %s is paused, resume it before reprocessing", appName)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		if info = m.checkLifeCycleOpsDuringRebalance(); info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			info.Code = m.statusCodes.errReadReq.Code
+			info.Info = fmt.Sprintf("failed to read request body, err : %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		var req common.ReprocessRequest
+		if len(data) > 0 {
+			err = json.Unmarshal(data, &req)
+			if err != nil {
+				info.Code = m.statusCodes.errMarshalResp.Code
+				info.Info = fmt.Sprintf("failed to unmarshal reprocess request, err: %v", err)
+				logging.Errorf("%s %s", logPrefix, info.Info)
+				m.sendErrorInfo(w, info)
+				return
+			}
+		}
+
+		if info = m.validateReprocessRequest(&req); info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		if info = m.notifyReprocessToAllProducers(appName, &req); info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+		fmt.Fprintf(w, "Function: %s reprocess requested", appName)
+	} else if match := functionsNameRollbacks.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+		info := &runtimeInfo{}
+
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !m.checkIfDeployed(appName) {
+			info.Code = m.statusCodes.errAppNotDeployed.Code
+			info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		util.Retry(util.NewFixedBackoff(time.Second), nil, getEventingNodesAddressesOpCallback, m)
+
+		query := url.Values{}
+		query.Set("name", appName)
+
+		history, errs := util.GetRollbackHistory("/getRollbackHistory?"+query.Encode(), m.eventingNodeAddrs)
+		if allNodesFailed(errs, m.eventingNodeAddrs) {
+			info.Code = m.statusCodes.errGetRollbackHistory.Code
+			info.Info = fmt.Sprintf("failed to fetch rollback history, err: %v", errs)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		response, err := json.Marshal(history)
+		if err != nil {
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal rollback history, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		m.sendNodesResponse(w, response, errs)
+	} else if match := functionsNameCrashes.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		appName := match[1]
+		info := &runtimeInfo{}
+
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		if !m.checkIfDeployed(appName) {
+			info.Code = m.statusCodes.errAppNotDeployed.Code
+			info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+			m.sendErrorInfo(w, info)
+			return
+		}
 
-func (m *ServiceMgr) functionsHandler(w http.ResponseWriter, r *http.Request) {
-	logPrefix := "ServiceMgr::functionsHandler"
+		util.Retry(util.NewFixedBackoff(time.Second), nil, getEventingNodesAddressesOpCallback, m)
 
-	w.Header().Set("Content-Type", "application/json")
-	if !m.validateAuth(w, r, EventingPermissionManage) {
-		cbauth.SendForbidden(w, EventingPermissionManage)
-		return
-	}
+		query := url.Values{}
+		query.Set("name", appName)
 
-	functions := regexp.MustCompile("^/api/v1/functions/?$")
-	functionsName := regexp.MustCompile("^/api/v1/functions/(.*[^/])/?$") // Match is agnostic of trailing '/'
-	functionsNameSettings := regexp.MustCompile("^/api/v1/functions/(.*[^/])/settings/?$")
-	functionsNameRetry := regexp.MustCompile("^/api/v1/functions/(.*[^/])/retry/?$")
+		history, errs := util.GetCrashHistory("/getCrashHistory?"+query.Encode(), m.eventingNodeAddrs)
+		if allNodesFailed(errs, m.eventingNodeAddrs) {
+			info.Code = m.statusCodes.errGetCrashHistory.Code
+			info.Info = fmt.Sprintf("failed to fetch crash history, err: %v", errs)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
 
-	if match := functionsNameRetry.FindStringSubmatch(r.URL.Path); len(match) != 0 {
+		response, err := json.Marshal(history)
+		if err != nil {
+			info.Code = m.statusCodes.errMarshalResp.Code
+			info.Info = fmt.Sprintf("failed to marshal crash history, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		m.sendNodesResponse(w, response, errs)
+	} else if match := functionsNameRetry.FindStringSubmatch(r.URL.Path); len(match) != 0 {
 		appName := match[1]
 		info := &runtimeInfo{}
 
@@ -2317,6 +3132,48 @@ func (m *ServiceMgr) notifyRetryToAllProducers(appName string, r *retry) (info *
 	return
 }
 
+func (m *ServiceMgr) notifyReprocessToAllProducers(appName string, req *common.ReprocessRequest) (info *runtimeInfo) {
+	logPrefix := "ServiceMgr::notifyReprocessToAllProducers"
+
+	info = &runtimeInfo{}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("failed to marshal reprocess request, err: %v", err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	util.Retry(util.NewFixedBackoff(time.Second), nil, getEventingNodesAddressesOpCallback, m)
+
+	uuidAddrMap, err := util.GetNodeUUIDs("/uuid", m.eventingNodeAddrs)
+	if err != nil {
+		info.Code = m.statusCodes.errAppReprocess.Code
+		info.Info = fmt.Sprintf("failed to get eventing node uuids, err: %v", err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		return
+	}
+
+	// Request is written for each node on its own, so that each of them can delete
+	// it once applied, without waiting on rest of the nodes
+	for uuid := range uuidAddrMap {
+		err = util.MetakvSet(metakvAppsReprocessPath+appName+"/"+uuid, data, nil)
+		if err != nil {
+			info.Code = m.statusCodes.errAppReprocess.Code
+			info.Info = fmt.Sprintf("unable to set metakv path for reprocess, err : %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			return
+		}
+	}
+
+	logging.Infof("%s Function: %s reprocess requested from boundary: %s seq no: %d timestamp: %s vbs: %s",
+		logPrefix, appName, req.Boundary, req.StartSeqNo, req.BoundaryTimestamp, util.Condense(req.Vbuckets))
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
 func (m *ServiceMgr) statusHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	if !m.validateAuth(w, r, EventingPermissionManage) {
@@ -2374,12 +3231,32 @@ func (m *ServiceMgr) statusHandlerImpl() (response appStatusResponse, info *runt
 		if num, exists := appBootstrappingNodesCounter[app.Name]; exists {
 			status.NumBootstrappingNodes = num
 		}
+		if failed, ok := app.Settings["crash_loop_failed"].(bool); ok {
+			status.CrashLoopFailed = failed
+		}
 		status.CompositeStatus = determineStatus(status, numEventingNodes)
+		status.BackfillJob = m.getBackfillJobStatus(app)
 		response.Apps = append(response.Apps, status)
 	}
 	return
 }
 
+func (m *ServiceMgr) getBackfillJobStatus(app application) *common.BackfillJobStatus {
+	if backfillJob, ok := app.Settings["backfill_job"].(bool); !ok || !backfillJob {
+		return nil
+	}
+
+	if completed, ok := app.Settings["backfill_completed"].(bool); ok && completed {
+		return &common.BackfillJobStatus{Completed: true, Progress: 100}
+	}
+
+	if status, err := m.superSup.BackfillJobStatus(app.Name); err == nil && status != nil {
+		return status
+	}
+
+	return &common.BackfillJobStatus{}
+}
+
 func determineStatus(status appStatus, numEventingNodes int) string {
 	logPrefix := "ServiceMgr::determineStatus"
 
@@ -2403,6 +3280,9 @@ func determineStatus(status appStatus, numEventingNodes int) string {
 
 	if !status.DeploymentStatus && !status.ProcessingStatus {
 		if status.NumDeployedNodes == 0 {
+			if status.CrashLoopFailed {
+				return "failed"
+			}
 			return "undeployed"
 		}
 		return "undeploying"
@@ -2434,6 +3314,7 @@ func (m *ServiceMgr) statsHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		statsList := m.populateStats(fullStats)
+		m.populateClusterLatencyStats(statsList)
 
 		response, err := json.Marshal(statsList)
 		if err != nil {
@@ -2450,33 +3331,139 @@ func (m *ServiceMgr) statsHandler(w http.ResponseWriter, r *http.Request) {
 	return
 }
 
-func percentileN(latencyStats map[string]uint64, p int) int {
-	var samples sort.IntSlice
-	var numSamples uint64
-	for bin, binCount := range latencyStats {
-		sample, err := strconv.Atoi(bin)
-		if err == nil {
-			samples = append(samples, sample)
-			numSamples += binCount
-		}
+// Reports lag of vbuckets of a function owned by this node
+func (m *ServiceMgr) getVbLag(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::getVbLag"
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		return
 	}
-	sort.Sort(samples)
-	i := numSamples*uint64(p)/100 - 1
 
-	var counter uint64
-	var prevSample int
-	for _, sample := range samples {
-		if counter > i {
-			return prevSample
-		}
-		counter += latencyStats[strconv.Itoa(sample)]
-		prevSample = sample
+	params := r.URL.Query()
+	appName := params.Get("name")
+	info := &runtimeInfo{}
+
+	if !m.checkIfDeployed(appName) {
+		info.Code = m.statusCodes.errAppNotDeployed.Code
+		info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	lags, err := m.superSup.VbLag(appName)
+	if err != nil {
+		info.Code = m.statusCodes.errGetVbLag.Code
+		info.Info = fmt.Sprintf("Function: %s failed to fetch vbucket lag, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	data, err := json.Marshal(lags)
+	if err != nil {
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("Function: %s failed to marshal vbucket lag, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	fmt.Fprintf(w, "%s", string(data))
+}
+
+// Reports stats history of a function on this node
+func (m *ServiceMgr) getStatsHistory(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::getStatsHistory"
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		return
+	}
+
+	params := r.URL.Query()
+	appName := params.Get("name")
+
+	window, info := m.validateStatsHistoryWindow(params)
+	if info.Code != m.statusCodes.ok.Code {
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	data, err := json.Marshal(m.statsHistory.samples(appName, time.Now().Add(-window)))
+	if err != nil {
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("Function: %s failed to marshal stats history, err: %v", appName, err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	fmt.Fprintf(w, "%s", string(data))
+}
+
+// Serves samples of stats of a function over a recent window, merged across eventing nodes
+func (m *ServiceMgr) statsHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::statsHistoryHandler"
+
+	w.Header().Set("Content-Type", "application/json")
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		cbauth.SendForbidden(w, EventingPermissionManage)
+		return
+	}
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	appName := params.Get("function")
+	info := &runtimeInfo{}
+
+	if !m.checkIfDeployed(appName) {
+		info.Code = m.statusCodes.errAppNotDeployed.Code
+		info.Info = fmt.Sprintf("Function: %s not deployed", appName)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	window, info := m.validateStatsHistoryWindow(params)
+	if info.Code != m.statusCodes.ok.Code {
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	query := url.Values{}
+	query.Set("name", appName)
+	query.Set("window", window.String())
+
+	util.Retry(util.NewFixedBackoff(time.Second), nil, getEventingNodesAddressesOpCallback, m)
+
+	nodeSamples, errs := util.GetStatsHistory("/getStatsHistory?"+query.Encode(), m.eventingNodeAddrs)
+	if allNodesFailed(errs, m.eventingNodeAddrs) {
+		info.Code = m.statusCodes.errGetStatsHistory.Code
+		info.Info = fmt.Sprintf("failed to gather stats history, err: %v", errs)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	history := &statsHistoryResponse{
+		Function:   appName,
+		Interval:   int(statsHistoryInterval / time.Second),
+		NodeErrors: errs.Messages(),
+		Samples:    mergeStatsHistory(nodeSamples),
 	}
 
-	if len(samples) > 0 {
-		return samples[len(samples)-1]
+	response, err := json.Marshal(history)
+	if err != nil {
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("failed to marshal stats history, err: %v", err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
 	}
-	return 0
+
+	m.sendNodesResponse(w, response, errs)
 }
 
 func (m *ServiceMgr) populateStats(fullStats bool) []stats {
@@ -2499,20 +3486,44 @@ func (m *ServiceMgr) populateStats(fullStats bool) []stats {
 			stats.LcbCredsRequestCounter = m.lcbCredsCounter
 			stats.LcbExceptionStats = m.superSup.GetLcbExceptionsStats(app.Name)
 			stats.MetastoreStats = m.superSup.GetMetaStoreStats(app.Name)
+			if memoryStats := m.superSup.GetMemoryStats(app.Name); memoryStats != nil {
+				stats.MemoryStats = memoryStats
+			}
+			if sinkStats := m.superSup.GetAppLogSinkStats(app.Name); len(sinkStats) > 0 {
+				stats.AppLogSinkStats = sinkStats
+			}
 			stats.WorkerPids = m.superSup.GetEventingConsumerPids(app.Name)
 			stats.PlannerStats = m.superSup.PlannerStats(app.Name)
 			stats.VbDistributionStatsFromMetadata = m.superSup.VbDistributionStatsFromMetadata(app.Name)
 
-			latencyStats := m.superSup.GetLatencyStats(app.Name)
+			histograms := make(map[string]*util.Histogram)
+			for kind, buckets := range m.latencyHistograms(app.Name) {
+				histograms[kind] = util.HistogramFromBuckets(buckets)
+			}
+			stats.LatencyHistogramStats = summarizeLatencies(histograms)
+
+			executionLatency := histograms["execution"]
 			ls := make(map[string]int)
-			ls["50"] = percentileN(latencyStats, 50)
-			ls["80"] = percentileN(latencyStats, 80)
-			ls["90"] = percentileN(latencyStats, 90)
-			ls["95"] = percentileN(latencyStats, 95)
-			ls["99"] = percentileN(latencyStats, 99)
-			ls["100"] = percentileN(latencyStats, 100)
+			ls["50"] = int(executionLatency.Percentile(50))
+			ls["80"] = int(executionLatency.Percentile(80))
+			ls["90"] = int(executionLatency.Percentile(90))
+			ls["95"] = int(executionLatency.Percentile(95))
+			ls["99"] = int(executionLatency.Percentile(99))
+			ls["100"] = int(executionLatency.Max())
 			stats.LatencyPercentileStats = ls
 
+			latenessStats := m.superSup.GetTimerLatenessStats(app.Name)
+			if len(latenessStats) > 0 {
+				lateness := util.HistogramFromBuckets(latenessStats)
+				tls := make(map[string]int)
+				tls["50"] = int(lateness.Percentile(50))
+				tls["90"] = int(lateness.Percentile(90))
+				tls["99"] = int(lateness.Percentile(99))
+				tls["100"] = int(lateness.Max())
+				stats.TimerLatenessPercentileStats = tls
+				stats.TimerMaxLateness = m.superSup.GetTimerMaxLateness(app.Name)
+			}
+
 			if m.rebalancer != nil {
 				rebalanceStats := make(map[string]interface{})
 				rebalanceStats["is_leader"] = true
@@ -2534,6 +3545,8 @@ func (m *ServiceMgr) populateStats(fullStats bool) []stats {
 
 				stats.LatencyStats = m.superSup.GetLatencyStats(app.Name)
 				stats.CurlLatencyStats = m.superSup.GetCurlLatencyStats(app.Name)
+				stats.DcpLatencyStats = m.superSup.GetDcpLatencyStats(app.Name)
+				stats.TimerLatenessStats = m.superSup.GetTimerLatenessStats(app.Name)
 				stats.SeqsProcessed = m.superSup.GetSeqsProcessed(app.Name)
 				spanBlobDump, err := m.superSup.SpanBlobDump(app.Name)
 				if err == nil {
@@ -2558,6 +3571,54 @@ func (m *ServiceMgr) populateStats(fullStats bool) []stats {
 	return statsList
 }
 
+// Adds latency histograms of functions merged across eventing nodes to their stats. Stats are
+// left as they are if some node couldn't be reached, as merging only some of them would mislead
+func (m *ServiceMgr) populateClusterLatencyStats(statsList []stats) {
+	if len(statsList) == 0 {
+		return
+	}
+
+	histograms := m.getClusterLatencyHistograms()
+	if histograms == nil {
+		return
+	}
+
+	for i := range statsList {
+		appName, _ := statsList[i].FunctionName.(string)
+		if appHistograms, ok := histograms[appName]; ok {
+			statsList[i].ClusterLatencyHistogramStats = summarizeLatencies(appHistograms)
+		}
+	}
+}
+
+// Gathers latency histograms from eventing nodes, at most once per clusterLatencyCacheTTL, so
+// that polling stats doesn't fan out to every node on each call. Callers arriving while they
+// are being gathered wait for them. Returns nil if some node couldn't be reached
+func (m *ServiceMgr) getClusterLatencyHistograms() map[string]map[string]*util.Histogram {
+	logPrefix := "ServiceMgr::getClusterLatencyHistograms"
+
+	cache := m.clusterLatency
+	cache.Lock()
+	defer cache.Unlock()
+
+	if cache.histograms != nil && time.Since(cache.fetchedAt) < clusterLatencyCacheTTL {
+		return cache.histograms
+	}
+
+	util.Retry(util.NewFixedBackoff(time.Second), nil, getEventingNodesAddressesOpCallback, m)
+
+	histograms, errs := util.GetLatencyHistograms("/getLatencyHistograms", m.eventingNodeAddrs)
+	if len(errs) > 0 {
+		logging.Errorf("%s Failed to gather latency histograms from eventing nodes: %rs, err: %v",
+			logPrefix, errs.Nodes(), errs)
+		cache.histograms = nil
+		return nil
+	}
+
+	cache.histograms, cache.fetchedAt = histograms, time.Now()
+	return histograms
+}
+
 // Clears up all Eventing related artifacts from metakv, typically will be used for rebalance tests
 func (m *ServiceMgr) cleanupEventing(w http.ResponseWriter, r *http.Request) {
 	if !m.validateAuth(w, r, EventingPermissionManage) {
@@ -2828,6 +3889,95 @@ func (m *ServiceMgr) checkVersionCompat(required string, info *runtimeInfo) {
 	info.Code = m.statusCodes.ok.Code
 }
 
+// Reports and sets log levels of this node per component and per function, at runtime. These
+// are not persisted and are lost on restart of eventing
+func (m *ServiceMgr) logLevelsHandler(w http.ResponseWriter, r *http.Request) {
+	logPrefix := "ServiceMgr::logLevelsHandler"
+
+	w.Header().Set("Content-Type", "application/json")
+	if !m.validateAuth(w, r, EventingPermissionManage) {
+		cbauth.SendForbidden(w, EventingPermissionManage)
+		return
+	}
+
+	info := &runtimeInfo{}
+
+	switch r.Method {
+	case "GET":
+
+	case "POST":
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			info.Code = m.statusCodes.errReadReq.Code
+			info.Info = fmt.Sprintf("failed to read request body, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		var override logging.LevelOverride
+		err = json.Unmarshal(data, &override)
+		if err != nil {
+			info.Code = m.statusCodes.errUnmarshalPld.Code
+			info.Info = fmt.Sprintf("failed to unmarshal log level, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		if info = m.validateLogLevelOverride(&override); info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		override.Level = strings.ToUpper(override.Level)
+
+		// Overrides live in global config, which every eventing node observes and applies
+		c, info := m.getConfig()
+		if info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		overrides, err := logging.LevelOverridesFrom(c["log_level_overrides"])
+		if err != nil {
+			info.Code = m.statusCodes.errUnmarshalPld.Code
+			info.Info = fmt.Sprintf("failed to read log level overrides from config, err: %v", err)
+			logging.Errorf("%s %s", logPrefix, info.Info)
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		overrides = logging.MergeLevelOverride(overrides, override)
+		if info = m.saveConfig(common.Config{"log_level_overrides": overrides}); info.Code != m.statusCodes.ok.Code {
+			m.sendErrorInfo(w, info)
+			return
+		}
+
+		logging.SetLogLevelOverrides(overrides)
+		logging.Infof("%s Log level for component: %s function: %s set to: %s",
+			logPrefix, override.Component, override.Function, override.Level)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	response, err := json.Marshal(map[string]interface{}{
+		"level":     logging.GetLogLevel().String(),
+		"overrides": logging.LogLevelOverrides(),
+	})
+	if err != nil {
+		info.Code = m.statusCodes.errMarshalResp.Code
+		info.Info = fmt.Sprintf("failed to marshal log levels, err: %v", err)
+		logging.Errorf("%s %s", logPrefix, info.Info)
+		m.sendErrorInfo(w, info)
+		return
+	}
+
+	fmt.Fprintf(w, "%s", string(response))
+}
+
 func (m *ServiceMgr) triggerGC(w http.ResponseWriter, r *http.Request) {
 	logPrefix := "ServiceMgr::triggerGC"
 
@@ -0,0 +1,84 @@
+package servicemanager
+
+import (
+	"sort"
+
+	"github.com/couchbase/eventing/common"
+)
+
+// Stream status consumers report for vbuckets being streamed
+const dcpStreamRunning = "running"
+
+// Lag of a function across eventing nodes. Function catches up once its slowest vbucket does,
+// so its ETA is the highest of vbuckets, and nil if some vbucket with backlog isn't progressing
+type lagSummary struct {
+	Backlog         uint64  `json:"backlog"`
+	Throughput      float64 `json:"throughput"`
+	EtaSecs         *int64  `json:"eta_secs"`
+	Vbuckets        int     `json:"vbuckets"`
+	VbucketsBehind  int     `json:"vbuckets_behind"`
+	VbucketsStalled int     `json:"vbuckets_stalled"`
+}
+
+type lagReport struct {
+	Function   string            `json:"function"`
+	NodeErrors map[string]string `json:"node_errors,omitempty"`
+	Summary    *lagSummary       `json:"summary"`
+	Vbuckets   []*common.VbLag   `json:"vbuckets"`
+}
+
+// Picks one report per vbucket, as during rebalance both old and new owners may report it.
+// Owner streaming the vbucket is preferred, and then the one furthest ahead
+func mergeVbLags(lags []*common.VbLag) []*common.VbLag {
+	byVb := make(map[uint16]*common.VbLag)
+	for _, lag := range lags {
+		cur, ok := byVb[lag.Vbucket]
+		if !ok {
+			byVb[lag.Vbucket] = lag
+			continue
+		}
+
+		curRunning, running := cur.StreamStatus == dcpStreamRunning, lag.StreamStatus == dcpStreamRunning
+		if (running && !curRunning) || (running == curRunning && lag.ProcessedSeqNo > cur.ProcessedSeqNo) {
+			byVb[lag.Vbucket] = lag
+		}
+	}
+
+	merged := make([]*common.VbLag, 0, len(byVb))
+	for _, lag := range byVb {
+		merged = append(merged, lag)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Vbucket < merged[j].Vbucket
+	})
+	return merged
+}
+
+func summarizeVbLags(lags []*common.VbLag) *lagSummary {
+	summary := &lagSummary{Vbuckets: len(lags)}
+
+	var eta int64
+	stalled := false
+	for _, lag := range lags {
+		summary.Backlog += lag.Backlog
+		summary.Throughput += lag.Throughput
+
+		if lag.Backlog == 0 {
+			continue
+		}
+		summary.VbucketsBehind++
+
+		if lag.EtaSecs == nil {
+			summary.VbucketsStalled++
+			stalled = true
+		} else if *lag.EtaSecs > eta {
+			eta = *lag.EtaSecs
+		}
+	}
+
+	if !stalled {
+		summary.EtaSecs = &eta
+	}
+	return summary
+}
@@ -0,0 +1,73 @@
+package servicemanager
+
+import (
+	"testing"
+
+	"github.com/couchbase/eventing/common"
+)
+
+func TestMergeVbLags(t *testing.T) {
+	merged := mergeVbLags([]*common.VbLag{
+		{Vbucket: 2, Node: "new", StreamStatus: "", ProcessedSeqNo: 50},
+		{Vbucket: 2, Node: "old", StreamStatus: dcpStreamRunning, ProcessedSeqNo: 40},
+		{Vbucket: 1, Node: "old", StreamStatus: "", ProcessedSeqNo: 10},
+		{Vbucket: 1, Node: "new", StreamStatus: "", ProcessedSeqNo: 30},
+		{Vbucket: 0, Node: "old", StreamStatus: dcpStreamRunning, ProcessedSeqNo: 80},
+		{Vbucket: 0, Node: "new", StreamStatus: dcpStreamRunning, ProcessedSeqNo: 70},
+	})
+
+	if len(merged) != 3 {
+		t.Fatalf("Expected a report per vbucket, got %d", len(merged))
+	}
+
+	expected := []struct {
+		vb   uint16
+		node string
+	}{
+		{vb: 0, node: "old"}, // Both streaming, old one further ahead
+		{vb: 1, node: "new"}, // Neither streaming, new one further ahead
+		{vb: 2, node: "old"}, // Only old one streaming, though behind
+	}
+	for i, exp := range expected {
+		if merged[i].Vbucket != exp.vb || merged[i].Node != exp.node {
+			t.Errorf("Expected vb: %d reported by %s, got vb: %d by %s", exp.vb, exp.node, merged[i].Vbucket, merged[i].Node)
+		}
+	}
+
+	if merged := mergeVbLags(nil); len(merged) != 0 {
+		t.Errorf("Expected no lags to merge into none, got %d", len(merged))
+	}
+}
+
+func TestSummarizeVbLags(t *testing.T) {
+	eta := func(secs int64) *int64 { return &secs }
+
+	summary := summarizeVbLags([]*common.VbLag{
+		{Vbucket: 0, Backlog: 0, Throughput: 5},
+		{Vbucket: 1, Backlog: 100, Throughput: 10, EtaSecs: eta(10)},
+		{Vbucket: 2, Backlog: 300, Throughput: 5, EtaSecs: eta(60)},
+	})
+
+	if summary.Vbuckets != 3 || summary.Backlog != 400 || summary.Throughput != 20 ||
+		summary.VbucketsBehind != 2 || summary.VbucketsStalled != 0 {
+		t.Errorf("Unexpected summary %+v", *summary)
+	}
+	if summary.EtaSecs == nil || *summary.EtaSecs != 60 {
+		t.Errorf("Expected ETA of slowest vbucket, 60s, got %v", summary.EtaSecs)
+	}
+
+	// Vbucket with backlog that isn't progressing leaves function without ETA
+	summary = summarizeVbLags([]*common.VbLag{
+		{Vbucket: 0, Backlog: 100, EtaSecs: eta(10)},
+		{Vbucket: 1, Backlog: 50},
+	})
+	if summary.EtaSecs != nil || summary.VbucketsStalled != 1 || summary.VbucketsBehind != 2 {
+		t.Errorf("Expected stalled vbucket to leave out ETA, got %+v", *summary)
+	}
+
+	// Caught up function is due now
+	summary = summarizeVbLags([]*common.VbLag{{Vbucket: 0}})
+	if summary.EtaSecs == nil || *summary.EtaSecs != 0 || summary.VbucketsBehind != 0 {
+		t.Errorf("Expected ETA of 0 for function caught up, got %+v", *summary)
+	}
+}
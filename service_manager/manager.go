@@ -30,6 +30,7 @@ func NewServiceMgr(config util.Config, rebalanceRunning bool, superSup common.Ev
 	mu := &sync.RWMutex{}
 
 	mgr := &ServiceMgr{
+		clusterLatency:    &clusterLatencyCache{},
 		graph:             newBucketMultiDiGraph(),
 		fnsInPrimaryStore: make(map[string]depCfg),
 		fnsInTempStore:    make(map[string]struct{}),
@@ -42,6 +43,7 @@ func NewServiceMgr(config util.Config, rebalanceRunning bool, superSup common.Ev
 			rev:           0,
 			servers:       make([]service.NodeID, 0),
 		},
+		statsHistory: newStatsHistory(),
 		statsWritten: true,
 		stopTracerCh: make(chan struct{}, 1),
 		superSup:     superSup,
@@ -91,6 +93,8 @@ func (m *ServiceMgr) initService() {
 
 	m.disableDebugger()
 
+	go m.recordStatsHistory()
+
 	mux := http.NewServeMux()
 
 	//pprof REST APIs
@@ -104,6 +108,8 @@ func (m *ServiceMgr) initService() {
 	mux.HandleFunc("/debug/vars", m.expvarHandler)
 
 	// Internal REST APIs
+	mux.HandleFunc("/cancelTimer", m.cancelTimer)
+	mux.HandleFunc("/checkTimers", m.checkTimers)
 	mux.HandleFunc("/cleanupEventing", m.cleanupEventing)
 	mux.HandleFunc("/clearEventStats", m.clearEventStats)
 	mux.HandleFunc("/die", m.die)
@@ -128,12 +134,19 @@ func (m *ServiceMgr) initService() {
 	mux.HandleFunc("/getExecutionStats", m.getExecutionStats)
 	mux.HandleFunc("/getFailureStats", m.getFailureStats)
 	mux.HandleFunc("/getLatencyStats", m.getLatencyStats)
+	mux.HandleFunc("/getLatencyHistograms", m.getLatencyHistograms)
+	mux.HandleFunc("/getStatsHistory", m.getStatsHistory)
 	mux.HandleFunc("/getLocallyDeployedApps", m.getLocallyDeployedApps)
 	mux.HandleFunc("/getNamedParams", m.getNamedParamsHandler)
 	mux.HandleFunc("/getRebalanceProgress", m.getRebalanceProgress)
+	mux.HandleFunc("/getCrashHistory", m.getCrashHistory)
+	mux.HandleFunc("/getRollbackHistory", m.getRollbackHistory)
 	mux.HandleFunc("/getRebalanceStatus", m.getRebalanceStatus)
 	mux.HandleFunc("/getRunningApps", m.getRunningApps)
 	mux.HandleFunc("/getSeqsProcessed", m.getSeqsProcessed)
+	mux.HandleFunc("/getTimers", m.getTimers)
+	mux.HandleFunc("/getVbLag", m.getVbLag)
+	mux.HandleFunc("/getAppLog", m.getAppLog)
 	mux.HandleFunc("/getLocalDebugUrl/", m.getLocalDebugURL)
 	mux.HandleFunc("/getWorkerCount", m.getWorkerCount)
 	mux.HandleFunc("/logFileLocation", m.logFileLocation)
@@ -153,6 +166,7 @@ func (m *ServiceMgr) initService() {
 	// Public REST APIs
 	mux.HandleFunc("/api/v1/status", m.statusHandler)
 	mux.HandleFunc("/api/v1/stats", m.statsHandler)
+	mux.HandleFunc("/api/v1/stats/history", m.statsHistoryHandler)
 	mux.HandleFunc("/api/v1/config", m.configHandler)
 	mux.HandleFunc("/api/v1/config/", m.configHandler)
 	mux.HandleFunc("/api/v1/functions", m.functionsHandler)
@@ -161,6 +175,7 @@ func (m *ServiceMgr) initService() {
 	mux.HandleFunc("/api/v1/export/", m.exportHandler)
 	mux.HandleFunc("/api/v1/import", m.importHandler)
 	mux.HandleFunc("/api/v1/import/", m.importHandler)
+	mux.HandleFunc("/api/v1/loglevels", m.logLevelsHandler)
 
 	go func() {
 		addr := net.JoinHostPort("", m.adminHTTPPort)
@@ -0,0 +1,222 @@
+package servicemanager
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/util"
+)
+
+// Stats of deployed functions are sampled every statsHistoryInterval, and statsHistorySize
+// latest samples of each are kept, an hour's worth
+const (
+	statsHistoryInterval = 10 * time.Second
+	statsHistorySize     = 360
+	statsHistoryWindow   = 10 * time.Minute
+)
+
+// Cumulative counters of a function as of a sample, which the next sample is worked out from
+type statsCounters struct {
+	dcpBacklog      uint64
+	eventsProcessed uint64
+	failures        uint64
+	latency         map[string]uint64
+	timersFired     uint64
+}
+
+type fnStatsHistory struct {
+	last    *statsCounters
+	samples []*common.StatsSample // Ring buffer, oldest sample at next once full
+	next    int
+}
+
+// statsHistory keeps recent samples of stats of functions deployed on this node
+type statsHistory struct {
+	fns map[string]*fnStatsHistory
+	mu  *sync.RWMutex
+}
+
+type statsHistorySample struct {
+	DcpBacklog      uint64                 `json:"dcp_backlog"`
+	EventsProcessed uint64                 `json:"events_processed"`
+	Failures        uint64                 `json:"failures"`
+	Latency         *common.LatencySummary `json:"latency"`
+	Nodes           int                    `json:"nodes"`
+	TimersFired     uint64                 `json:"timers_fired"`
+	Timestamp       time.Time              `json:"ts"`
+}
+
+type statsHistoryResponse struct {
+	Function   string                `json:"function"`
+	Interval   int                   `json:"interval"`
+	NodeErrors map[string]string     `json:"node_errors,omitempty"`
+	Samples    []*statsHistorySample `json:"samples"`
+}
+
+func newStatsHistory() *statsHistory {
+	return &statsHistory{
+		fns: make(map[string]*fnStatsHistory),
+		mu:  &sync.RWMutex{},
+	}
+}
+
+// Counters only go down when the worker restarted or stats were cleared, and count afresh since
+func counterDelta(cur, prev uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
+
+func (h *statsHistory) record(appName string, ts time.Time, counters *statsCounters) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fn, ok := h.fns[appName]
+	if !ok {
+		fn = &fnStatsHistory{samples: make([]*common.StatsSample, 0, statsHistorySize)}
+		h.fns[appName] = fn
+	}
+
+	last := fn.last
+	fn.last = counters
+	if last == nil {
+		return
+	}
+
+	latency := util.HistogramFromBuckets(counters.latency)
+	latency.Sub(util.HistogramFromBuckets(last.latency))
+
+	sample := &common.StatsSample{
+		DcpBacklog:      counters.dcpBacklog,
+		EventsProcessed: counterDelta(counters.eventsProcessed, last.eventsProcessed),
+		Failures:        counterDelta(counters.failures, last.failures),
+		Latency:         latency.Buckets(),
+		TimersFired:     counterDelta(counters.timersFired, last.timersFired),
+		Timestamp:       ts,
+	}
+
+	if len(fn.samples) < statsHistorySize {
+		fn.samples = append(fn.samples, sample)
+		return
+	}
+	fn.samples[fn.next] = sample
+	fn.next = (fn.next + 1) % statsHistorySize
+}
+
+// Drops history of functions no longer deployed
+func (h *statsHistory) retain(deployed map[string]struct{}) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for appName := range h.fns {
+		if _, ok := deployed[appName]; !ok {
+			delete(h.fns, appName)
+		}
+	}
+}
+
+// Samples of a function taken after since, oldest first
+func (h *statsHistory) samples(appName string, since time.Time) []*common.StatsSample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	samples := make([]*common.StatsSample, 0)
+	fn, ok := h.fns[appName]
+	if !ok {
+		return samples
+	}
+
+	for i := 0; i < len(fn.samples); i++ {
+		sample := fn.samples[(fn.next+i)%len(fn.samples)]
+		if sample.Timestamp.After(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// Samples stats of deployed functions at multiples of statsHistoryInterval, so that samples
+// taken on eventing nodes line up for merging
+func (m *ServiceMgr) recordStatsHistory() {
+	now := time.Now()
+	time.Sleep(now.Truncate(statsHistoryInterval).Add(statsHistoryInterval).Sub(now))
+
+	ticker := time.NewTicker(statsHistoryInterval)
+	defer ticker.Stop()
+
+	for {
+		ts := time.Now().Round(statsHistoryInterval)
+
+		deployed := make(map[string]struct{})
+		for _, appName := range m.superSup.DeployedAppList() {
+			deployed[appName] = struct{}{}
+			m.statsHistory.record(appName, ts, m.statsCounters(appName))
+		}
+		m.statsHistory.retain(deployed)
+
+		<-ticker.C
+	}
+}
+
+func (m *ServiceMgr) statsCounters(appName string) *statsCounters {
+	executionStats := m.superSup.GetExecutionStats(appName)
+
+	return &statsCounters{
+		dcpBacklog: m.superSup.GetDcpEventsRemainingToProcess(appName),
+		eventsProcessed: statValue(executionStats, "on_update_success", "on_update_failure",
+			"on_delete_success", "on_delete_failure"),
+		failures:    statValue(executionStats, "on_update_failure", "on_delete_failure"),
+		latency:     m.superSup.GetLatencyStats(appName),
+		timersFired: statValue(executionStats, "timer_msg_counter"),
+	}
+}
+
+// Sums up counters of stats reported by eventing-consumer, which are numbers as JSON has them
+func statValue(stats map[string]interface{}, keys ...string) uint64 {
+	var value uint64
+	for _, key := range keys {
+		if v, ok := stats[key].(float64); ok && v > 0 {
+			value += uint64(v)
+		}
+	}
+	return value
+}
+
+// Merges samples of a function taken on eventing nodes at the same time
+func mergeStatsHistory(nodeSamples map[string][]*common.StatsSample) []*statsHistorySample {
+	merged := make(map[int64]*statsHistorySample)
+	latencies := make(map[int64]*util.Histogram)
+
+	for _, samples := range nodeSamples {
+		for _, sample := range samples {
+			key := sample.Timestamp.Unix()
+			s, ok := merged[key]
+			if !ok {
+				s = &statsHistorySample{Timestamp: sample.Timestamp}
+				merged[key] = s
+				latencies[key] = util.NewHistogram()
+			}
+
+			s.DcpBacklog += sample.DcpBacklog
+			s.EventsProcessed += sample.EventsProcessed
+			s.Failures += sample.Failures
+			s.Nodes++
+			s.TimersFired += sample.TimersFired
+			latencies[key].Merge(util.HistogramFromBuckets(sample.Latency))
+		}
+	}
+
+	samples := make([]*statsHistorySample, 0, len(merged))
+	for key, s := range merged {
+		s.Latency = latencies[key].Summary()
+		samples = append(samples, s)
+	}
+
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].Timestamp.Before(samples[j].Timestamp)
+	})
+	return samples
+}
@@ -0,0 +1,113 @@
+package servicemanager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+)
+
+func TestStatsHistoryRecord(t *testing.T) {
+	h := newStatsHistory()
+	start := time.Unix(1600000000, 0)
+
+	h.record("fn", start, &statsCounters{eventsProcessed: 100, failures: 1, latency: map[string]uint64{"10": 5}})
+	if samples := h.samples("fn", time.Time{}); len(samples) != 0 {
+		t.Fatalf("Expected first record to only set baseline, got %d samples", len(samples))
+	}
+
+	h.record("fn", start.Add(statsHistoryInterval), &statsCounters{dcpBacklog: 7, eventsProcessed: 150, failures: 3,
+		latency: map[string]uint64{"10": 8, "1000": 2}, timersFired: 4})
+
+	// Worker restarted, so counters start afresh
+	h.record("fn", start.Add(2*statsHistoryInterval), &statsCounters{eventsProcessed: 20, latency: map[string]uint64{"10": 1}})
+
+	samples := h.samples("fn", time.Time{})
+	if len(samples) != 2 {
+		t.Fatalf("Expected 2 samples, got %d", len(samples))
+	}
+
+	first := samples[0]
+	if first.DcpBacklog != 7 || first.EventsProcessed != 50 || first.Failures != 2 || first.TimersFired != 4 {
+		t.Errorf("Expected deltas over previous sample, got %+v", *first)
+	}
+	if len(first.Latency) != 2 || first.Latency["10"] != 3 || first.Latency["1000"] != 2 {
+		t.Errorf("Expected latency delta of 3 in bucket 10 and 2 in 1000, got %v", first.Latency)
+	}
+
+	if second := samples[1]; second.EventsProcessed != 20 || second.Failures != 0 {
+		t.Errorf("Expected counters to count afresh after going down, got %+v", *second)
+	}
+
+	if samples := h.samples("other", time.Time{}); len(samples) != 0 {
+		t.Errorf("Expected no samples of unknown function, got %d", len(samples))
+	}
+}
+
+func TestStatsHistoryRing(t *testing.T) {
+	h := newStatsHistory()
+	start := time.Unix(1600000000, 0)
+	at := func(i int) time.Time { return start.Add(time.Duration(i) * statsHistoryInterval) }
+
+	// First record sets baseline, so this leaves statsHistorySize+5 samples, of which oldest 5 are overwritten
+	for i := 0; i <= statsHistorySize+5; i++ {
+		h.record("fn", at(i), &statsCounters{eventsProcessed: uint64(i)})
+	}
+
+	samples := h.samples("fn", time.Time{})
+	if len(samples) != statsHistorySize {
+		t.Fatalf("Expected %d samples, got %d", statsHistorySize, len(samples))
+	}
+	for i, sample := range samples {
+		if !sample.Timestamp.Equal(at(i + 6)) {
+			t.Fatalf("Expected sample %d at %v, got %v", i, at(i+6), sample.Timestamp)
+		}
+	}
+
+	// Samples are those taken after since
+	recent := h.samples("fn", at(statsHistorySize+3))
+	if len(recent) != 2 || !recent[0].Timestamp.Equal(at(statsHistorySize+4)) {
+		t.Errorf("Expected 2 latest samples, got %d", len(recent))
+	}
+
+	h.retain(map[string]struct{}{"other": {}})
+	if samples := h.samples("fn", time.Time{}); len(samples) != 0 {
+		t.Errorf("Expected history of undeployed function to be dropped, got %d samples", len(samples))
+	}
+}
+
+func TestMergeStatsHistory(t *testing.T) {
+	t1, t2 := time.Unix(1600000000, 0), time.Unix(1600000010, 0)
+
+	merged := mergeStatsHistory(map[string][]*common.StatsSample{
+		"10.1.1.1:8096": {
+			{Timestamp: t2, EventsProcessed: 5, Latency: map[string]uint64{"1000": 1}},
+			{Timestamp: t1, DcpBacklog: 10, EventsProcessed: 100, Failures: 1, TimersFired: 2, Latency: map[string]uint64{"10": 99}},
+		},
+		"10.1.1.2:8096": {
+			{Timestamp: t1, DcpBacklog: 5, EventsProcessed: 50, Failures: 2, TimersFired: 3, Latency: map[string]uint64{"10": 1}},
+		},
+	})
+
+	if len(merged) != 2 {
+		t.Fatalf("Expected 2 merged samples, got %d", len(merged))
+	}
+
+	first, second := merged[0], merged[1]
+	if !first.Timestamp.Equal(t1) || !second.Timestamp.Equal(t2) {
+		t.Fatalf("Expected samples ordered by time, got %v and %v", first.Timestamp, second.Timestamp)
+	}
+	if first.Nodes != 2 || first.DcpBacklog != 15 || first.EventsProcessed != 150 || first.Failures != 3 || first.TimersFired != 5 {
+		t.Errorf("Expected sample at %v summed across 2 nodes, got %+v", t1, *first)
+	}
+	if first.Latency.Count != 100 || first.Latency.P99 != 10 {
+		t.Errorf("Expected merged latency of 100 executions with p99 of 10, got %+v", *first.Latency)
+	}
+	if second.Nodes != 1 || second.EventsProcessed != 5 || second.Latency.Max != 1007 {
+		t.Errorf("Expected sample at %v from one node, got %+v latency: %+v", t2, *second, *second.Latency)
+	}
+
+	if merged := mergeStatsHistory(nil); len(merged) != 0 {
+		t.Errorf("Expected no samples to merge into none, got %d", len(merged))
+	}
+}
@@ -88,6 +88,16 @@ type statusCodes struct {
 	errBucketAccess           statusBase
 	errInterFunctionRecursion statusBase
 	errInterBucketRecursion   statusBase
+	errGetRollbackHistory     statusBase
+	errAppReprocess           statusBase
+	errGetTimers              statusBase
+	errCancelTimer            statusBase
+	errTimerNotFound          statusBase
+	errCheckTimers            statusBase
+	errGetAppLog              statusBase
+	errGetStatsHistory        statusBase
+	errGetVbLag               statusBase
+	errGetCrashHistory        statusBase
 }
 
 func (m *ServiceMgr) getDisposition(code int) int {
@@ -182,6 +192,26 @@ func (m *ServiceMgr) getDisposition(code int) int {
 		return http.StatusBadRequest
 	case m.statusCodes.errInterBucketRecursion.Code:
 		return http.StatusBadRequest
+	case m.statusCodes.errGetRollbackHistory.Code:
+		return http.StatusInternalServerError
+	case m.statusCodes.errAppReprocess.Code:
+		return http.StatusInternalServerError
+	case m.statusCodes.errGetTimers.Code:
+		return http.StatusInternalServerError
+	case m.statusCodes.errCancelTimer.Code:
+		return http.StatusInternalServerError
+	case m.statusCodes.errTimerNotFound.Code:
+		return http.StatusNotFound
+	case m.statusCodes.errCheckTimers.Code:
+		return http.StatusInternalServerError
+	case m.statusCodes.errGetAppLog.Code:
+		return http.StatusInternalServerError
+	case m.statusCodes.errGetStatsHistory.Code:
+		return http.StatusInternalServerError
+	case m.statusCodes.errGetVbLag.Code:
+		return http.StatusInternalServerError
+	case m.statusCodes.errGetCrashHistory.Code:
+		return http.StatusInternalServerError
 	default:
 		logging.Warnf("Unknown status code: %v", code)
 		return http.StatusInternalServerError
@@ -236,6 +266,16 @@ func (m *ServiceMgr) initErrCodes() {
 		errBucketAccess:           statusBase{"ERR_BUCKET_ACCESS", 49},
 		errInterFunctionRecursion: statusBase{"ERR_INTER_FUNCTION_RECURSION", 50},
 		errInterBucketRecursion:   statusBase{"ERR_INTER_BUCKET_RECURSION", 51},
+		errGetRollbackHistory:     statusBase{"ERR_GET_ROLLBACK_HISTORY", 52},
+		errAppReprocess:           statusBase{"ERR_APP_REPROCESS", 53},
+		errGetTimers:              statusBase{"ERR_GET_TIMERS", 54},
+		errCancelTimer:            statusBase{"ERR_CANCEL_TIMER", 55},
+		errTimerNotFound:          statusBase{"ERR_TIMER_NOT_FOUND", 56},
+		errCheckTimers:            statusBase{"ERR_CHECK_TIMERS", 57},
+		errGetAppLog:              statusBase{"ERR_GET_APP_LOG", 58},
+		errGetStatsHistory:        statusBase{"ERR_GET_STATS_HISTORY", 59},
+		errGetVbLag:               statusBase{"ERR_GET_VB_LAG", 60},
+		errGetCrashHistory:        statusBase{"ERR_GET_CRASH_HISTORY", 61},
 	}
 
 	errors := []errorPayload{
@@ -487,6 +527,56 @@ func (m *ServiceMgr) initErrCodes() {
 			Code:        m.statusCodes.errInterBucketRecursion.Code,
 			Description: "Inter bucket recursion error, deployment of current handler will cause inter bucket recursion",
 		},
+		{
+			Name:        m.statusCodes.errGetRollbackHistory.Name,
+			Code:        m.statusCodes.errGetRollbackHistory.Code,
+			Description: "Failed to fetch rollback history from eventing nodes",
+		},
+		{
+			Name:        m.statusCodes.errAppReprocess.Name,
+			Code:        m.statusCodes.errAppReprocess.Code,
+			Description: "Failed to notify reprocess request to all eventing nodes",
+		},
+		{
+			Name:        m.statusCodes.errGetTimers.Name,
+			Code:        m.statusCodes.errGetTimers.Code,
+			Description: "Failed to fetch pending timers from eventing nodes",
+		},
+		{
+			Name:        m.statusCodes.errCancelTimer.Name,
+			Code:        m.statusCodes.errCancelTimer.Code,
+			Description: "Failed to cancel timer on eventing nodes",
+		},
+		{
+			Name:        m.statusCodes.errTimerNotFound.Name,
+			Code:        m.statusCodes.errTimerNotFound.Code,
+			Description: "Timer not found",
+		},
+		{
+			Name:        m.statusCodes.errCheckTimers.Name,
+			Code:        m.statusCodes.errCheckTimers.Code,
+			Description: "Failed to check consistency of timer stores",
+		},
+		{
+			Name:        m.statusCodes.errGetAppLog.Name,
+			Code:        m.statusCodes.errGetAppLog.Code,
+			Description: "Failed to read application log",
+		},
+		{
+			Name:        m.statusCodes.errGetStatsHistory.Name,
+			Code:        m.statusCodes.errGetStatsHistory.Code,
+			Description: "Failed to gather stats history",
+		},
+		{
+			Name:        m.statusCodes.errGetVbLag.Name,
+			Code:        m.statusCodes.errGetVbLag.Code,
+			Description: "Failed to gather vbucket lag",
+		},
+		{
+			Name:        m.statusCodes.errGetCrashHistory.Name,
+			Code:        m.statusCodes.errGetCrashHistory.Code,
+			Description: "Failed to gather crash history",
+		},
 	}
 
 	m.errorCodes = make(map[int]errorPayload)
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/couchbase/cbauth/service"
 	"github.com/couchbase/eventing/gen/flatbuf/cfg"
@@ -37,10 +38,17 @@ func encodeRev(rev uint64) service.Revision {
 
 func fillMissingWithDefaults(settings map[string]interface{}) {
 	// Handler related configurations
+	fillMissingDefault(settings, "backfill_drain_timers", false)
+	fillMissingDefault(settings, "backfill_job", false)
+	fillMissingDefault(settings, "carry_over_timers", false)
 	fillMissingDefault(settings, "checkpoint_interval", float64(60000))
 	fillMissingDefault(settings, "cleanup_timers", false)
 	fillMissingDefault(settings, "cpp_worker_thread_count", float64(2))
+	fillMissingDefault(settings, "crash_loop_action", "respawn")
+	fillMissingDefault(settings, "crash_loop_threshold", float64(5))
+	fillMissingDefault(settings, "crash_loop_window", float64(300))
 	fillMissingDefault(settings, "deadline_timeout", float64(62))
+	fillMissingDefault(settings, "deliver_rollback_event", false)
 	fillMissingDefault(settings, "execution_timeout", float64(60))
 	fillMissingDefault(settings, "feedback_batch_size", float64(100))
 	fillMissingDefault(settings, "feedback_read_buffer_size", float64(65536))
@@ -50,6 +58,8 @@ func fillMissingWithDefaults(settings map[string]interface{}) {
 	fillMissingDefault(settings, "poll_bucket_interval", float64(10))
 	fillMissingDefault(settings, "sock_batch_size", float64(100))
 	fillMissingDefault(settings, "tick_duration", float64(60000))
+	fillMissingDefault(settings, "timer_check_interval", float64(0))
+	fillMissingDefault(settings, "timer_check_repair", false)
 	fillMissingDefault(settings, "timer_context_size", float64(1024))
 	fillMissingDefault(settings, "undeploy_routine_count", float64(6))
 	fillMissingDefault(settings, "worker_count", float64(3))
@@ -64,6 +74,8 @@ func fillMissingWithDefaults(settings map[string]interface{}) {
 	fillMissingDefault(settings, "timer_storage_chan_size", float64(10*1000))
 	fillMissingDefault(settings, "timer_queue_mem_cap", float64(50))
 	fillMissingDefault(settings, "timer_queue_size", float64(10000))
+	fillMissingDefault(settings, "timer_resolution", float64(7))
+	fillMissingDefault(settings, "timer_scan_vb_limit", float64(500))
 
 	// Process related configuration
 	fillMissingDefault(settings, "breakpad_on", true)
@@ -75,8 +87,15 @@ func fillMissingWithDefaults(settings map[string]interface{}) {
 	// Application logging related configurations
 	fillMissingDefault(settings, "app_log_max_size", float64(1024*1024*40))
 	fillMissingDefault(settings, "app_log_max_files", float64(10))
+	fillMissingDefault(settings, "app_log_compress", false)
+	fillMissingDefault(settings, "app_log_max_age", float64(0))
+	fillMissingDefault(settings, "app_log_rotation_interval", "none")
 	fillMissingDefault(settings, "enable_applog_rotation", true)
 
+	// Event tracing related configurations
+	fillMissingDefault(settings, "trace_sample_rate", float64(0))
+	fillMissingDefault(settings, "trace_export_url", "")
+
 	// DCP connection related configurations
 	fillMissingDefault(settings, "agg_dcp_feed_mem_cap", float64(1024))
 	fillMissingDefault(settings, "data_chan_size", float64(50))
@@ -142,6 +161,22 @@ func (m *ServiceMgr) sendRuntimeInfo(w http.ResponseWriter, runtimeInfo *runtime
 	fmt.Fprintf(w, string(response))
 }
 
+// Responds with what was gathered from eventing nodes. If some of them failed, response is
+// sent as multi-status, listing nodes that failed in failedNodesHeaderKey header
+func (m *ServiceMgr) sendNodesResponse(w http.ResponseWriter, response []byte, errs util.NodeErrors) {
+	w.Header().Add(headerKey, strconv.Itoa(m.statusCodes.ok.Code))
+	if len(errs) > 0 {
+		w.Header().Add(failedNodesHeaderKey, strings.Join(errs.Nodes(), ","))
+		w.WriteHeader(http.StatusMultiStatus)
+	}
+	fmt.Fprintf(w, "%s", string(response))
+}
+
+// Reports whether none of eventing nodes requested could respond
+func allNodesFailed(errs util.NodeErrors, nodeAddrs []string) bool {
+	return len(errs) > 0 && len(errs) >= len(nodeAddrs)
+}
+
 func (m *ServiceMgr) sendRuntimeInfoList(w http.ResponseWriter, runtimeInfoList []*runtimeInfo) {
 	response, err := json.Marshal(runtimeInfoList)
 	if err != nil {
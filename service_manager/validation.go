@@ -5,11 +5,16 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/couchbase/cbauth"
+	"github.com/couchbase/eventing/common"
 	"github.com/couchbase/eventing/logging"
 	"github.com/couchbase/eventing/util"
 )
@@ -302,6 +307,58 @@ func (m *ServiceMgr) validateConfig(c map[string]interface{}) (info *runtimeInfo
 		return
 	}
 
+	if info = m.validatePossibleValues("log_format", c, []string{"text", "json"}); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateAppLogSinks("app_log_sinks", c); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if value, ok := c["log_level_overrides"]; ok {
+		overrides, err := logging.LevelOverridesFrom(value)
+		if err != nil {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("log_level_overrides must be a list of overrides, err: %v", err)
+			return
+		}
+		for i := range overrides {
+			if overrides[i].Level == "" {
+				info.Code = m.statusCodes.errInvalidConfig.Code
+				info.Info = "level must be specified for each of log_level_overrides"
+				return
+			}
+			if info = m.validateLogLevelOverride(&overrides[i]); info.Code != m.statusCodes.ok.Code {
+				return
+			}
+		}
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// Log level override needs a component or function to apply to, while empty level drops it
+func (m *ServiceMgr) validateLogLevelOverride(override *logging.LevelOverride) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	if override.Component == "" && override.Function == "" {
+		info.Info = "component or function must be specified"
+		return
+	}
+
+	if override.Component != "" && !util.Contains(override.Component, logging.Components) {
+		info.Info = fmt.Sprintf("Invalid value for component, possible values are %s", strings.Join(logging.Components, ", "))
+		return
+	}
+
+	levels := []string{"SILENT", "FATAL", "ERROR", "WARN", "INFO", "VERBOSE", "TIMING", "DEBUG", "TRACE"}
+	if override.Level != "" && !util.Contains(strings.ToUpper(override.Level), levels) {
+		info.Info = fmt.Sprintf("Invalid value for level, possible values are %s", strings.Join(levels, ", "))
+		return
+	}
+
 	info.Code = m.statusCodes.ok.Code
 	return
 }
@@ -600,6 +657,21 @@ func (m *ServiceMgr) validateTimerContextSize(field string, settings map[string]
 	return
 }
 
+func (m *ServiceMgr) validateTimerResolution(field string, settings map[string]interface{}) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	if val, ok := settings[field]; ok {
+		if val.(float64) > 60 {
+			info.Info = fmt.Sprintf("%s value can not be more than 60 seconds", field)
+			return
+		}
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
 func (m *ServiceMgr) validatePossibleValues(field string, settings map[string]interface{}, possibleValues []string) (info *runtimeInfo) {
 	info = &runtimeInfo{}
 	info.Code = m.statusCodes.errInvalidConfig.Code
@@ -632,6 +704,22 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 		return
 	}
 
+	if info = m.validateBoolean("backfill_job", true, settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateBoolean("backfill_drain_timers", true, settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateBackfillJob(settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateBoolean("carry_over_timers", true, settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("checkpoint_interval", settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
@@ -640,19 +728,44 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 		return
 	}
 
+	if info = m.validateCarryOverTimers(settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("cpp_worker_thread_count", settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
 
-	dcpStreamBoundaryValues := []string{"everything", "from_now", "from_prior"}
+	crashLoopActionValues := []string{"fail", "respawn", "skip_event"}
+	if info = m.validatePossibleValues("crash_loop_action", settings, crashLoopActionValues); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateZeroOrPositiveInteger("crash_loop_threshold", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validatePositiveInteger("crash_loop_window", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	dcpStreamBoundaryValues := []string{"everything", "from_now", "from_prior", "from_seqnos", "from_timestamp"}
 	if info = m.validatePossibleValues("dcp_stream_boundary", settings, dcpStreamBoundaryValues); info.Code != m.statusCodes.ok.Code {
 		return
 	}
 
+	if info = m.validateStreamBoundary(settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("deadline_timeout", settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
 
+	if info = m.validateBoolean("deliver_rollback_event", true, settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("execution_timeout", settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
@@ -698,6 +811,14 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 		return
 	}
 
+	if info = m.validateZeroOrPositiveInteger("timer_check_interval", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateBoolean("timer_check_repair", true, settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("timer_context_size", settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
@@ -751,6 +872,18 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 		return
 	}
 
+	if info = m.validatePositiveInteger("timer_resolution", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateTimerResolution("timer_resolution", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateZeroOrPositiveInteger("timer_scan_vb_limit", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validatePositiveInteger("undeploy_routine_count", settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
@@ -782,10 +915,35 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 		return
 	}
 
+	if info = m.validateBoolean("app_log_compress", true, settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateZeroOrPositiveInteger("app_log_max_age", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validatePossibleValues("app_log_rotation_interval", settings, []string{"none", "hourly", "daily"}); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateAppLogSinks("app_log_sinks", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	if info = m.validateBoolean("enable_applog_rotation", true, settings); info.Code != m.statusCodes.ok.Code {
 		return
 	}
 
+	// Event tracing related configurations
+	if info = m.validateTraceSampleRate("trace_sample_rate", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if info = m.validateTraceExportURL("trace_export_url", settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
 	// DCP connection related configurations
 	if info = m.validatePositiveInteger("agg_dcp_feed_mem_cap", settings); info.Code != m.statusCodes.ok.Code {
 		return
@@ -807,6 +965,387 @@ func (m *ServiceMgr) validateSettings(settings map[string]interface{}) (info *ru
 	return
 }
 
+func (m *ServiceMgr) validateBackfillJob(settings map[string]interface{}) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	if backfillJob, ok := settings["backfill_job"].(bool); ok && backfillJob {
+		if boundary, ok := settings["dcp_stream_boundary"].(string); ok && boundary == "from_now" {
+			info.Info = "backfill_job can't be used with dcp_stream_boundary from_now, as there is nothing to backfill"
+			return
+		}
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+func (m *ServiceMgr) validateCarryOverTimers(settings map[string]interface{}) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	carryOver, _ := settings["carry_over_timers"].(bool)
+	cleanup, _ := settings["cleanup_timers"].(bool)
+	if carryOver && cleanup {
+		info.Info = "carry_over_timers can't be used with cleanup_timers, as timers being carried over would be deleted"
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+func (m *ServiceMgr) validateStreamBoundary(settings map[string]interface{}) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	boundary, _ := settings["dcp_stream_boundary"].(string)
+
+	if val, ok := settings["dcp_stream_boundary_seqnos"]; ok {
+		seqNos, ok := val.(map[string]interface{})
+		if !ok {
+			info.Info = "dcp_stream_boundary_seqnos must be a map of vbucket to seq no"
+			return
+		}
+
+		for vb, seqNo := range seqNos {
+			vbNo, err := strconv.Atoi(vb)
+			if err != nil || vbNo < 0 || vbNo >= maxVbuckets {
+				info.Info = fmt.Sprintf("dcp_stream_boundary_seqnos has invalid vbucket: %s", vb)
+				return
+			}
+
+			if val, ok := seqNo.(float64); !ok || val < 0 || math.Trunc(val) != val {
+				info.Info = fmt.Sprintf("dcp_stream_boundary_seqnos has invalid seq no for vbucket: %s, must be zero or positive integer", vb)
+				return
+			}
+		}
+	} else if boundary == "from_seqnos" {
+		info.Info = "dcp_stream_boundary_seqnos must be specified for dcp_stream_boundary from_seqnos"
+		return
+	}
+
+	if val, ok := settings["dcp_stream_boundary_timestamp"]; ok {
+		tsStr, ok := val.(string)
+		if !ok {
+			info.Info = "dcp_stream_boundary_timestamp must be a string"
+			return
+		}
+
+		ts, err := time.Parse(time.RFC3339, tsStr)
+		if err != nil {
+			info.Info = fmt.Sprintf("dcp_stream_boundary_timestamp must be in RFC3339 format, err: %v", err)
+			return
+		}
+
+		if ts.After(time.Now()) {
+			info.Info = "dcp_stream_boundary_timestamp can not be in the future"
+			return
+		}
+	} else if boundary == "from_timestamp" {
+		info.Info = "dcp_stream_boundary_timestamp must be specified for dcp_stream_boundary from_timestamp"
+		return
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+func (m *ServiceMgr) validateReprocessRequest(req *common.ReprocessRequest) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	switch req.Boundary {
+	case "", common.DcpFromSeqNos:
+		req.Boundary = common.DcpFromSeqNos
+
+	case common.DcpEverything:
+		req.StartSeqNo = 0
+
+	case common.DcpFromTimestamp:
+		ts, err := time.Parse(time.RFC3339, req.BoundaryTimestamp)
+		if err != nil {
+			info.Info = fmt.Sprintf("boundary_timestamp must be in RFC3339 format for boundary from_timestamp, err: %v", err)
+			return
+		}
+
+		if ts.After(time.Now()) {
+			info.Info = "boundary_timestamp can not be in the future"
+			return
+		}
+
+	default:
+		info.Info = fmt.Sprintf("boundary: %s must be one of everything, from_seqnos or from_timestamp", req.Boundary)
+		return
+	}
+
+	vbs := make(map[uint16]struct{})
+	for _, vb := range req.Vbuckets {
+		if vb >= maxVbuckets {
+			info.Info = fmt.Sprintf("vbuckets has invalid vbucket: %d", vb)
+			return
+		}
+		vbs[vb] = struct{}{}
+	}
+
+	req.Vbuckets = make([]uint16, 0, len(vbs))
+	for vb := range vbs {
+		req.Vbuckets = append(req.Vbuckets, vb)
+	}
+	sort.Sort(util.Uint16Slice(req.Vbuckets))
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// Parses filters for searching application logs from query params. Since is taken either as
+// a timestamp or as a duration before now, like 10m
+func (m *ServiceMgr) validateAppLogFilter(params url.Values) (filter *common.AppLogFilter, info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	filter = &common.AppLogFilter{
+		Grep:   params.Get("grep"),
+		Limit:  defaultAppLogLimit,
+		Offset: -1,
+	}
+
+	if val := params.Get("since"); val != "" {
+		if ts, err := time.Parse(time.RFC3339, val); err == nil {
+			filter.Since = ts
+		} else if d, err := time.ParseDuration(val); err == nil && d > 0 {
+			filter.Since = time.Now().Add(-d)
+		} else {
+			info.Info = "since should be a timestamp in RFC3339 format or a positive duration like 10m"
+			return
+		}
+	}
+
+	if filter.Grep != "" {
+		if _, err := regexp.Compile(filter.Grep); err != nil {
+			info.Info = fmt.Sprintf("grep should be a valid regular expression, err: %v", err)
+			return
+		}
+	}
+
+	if val := params.Get("limit"); val != "" {
+		limit, err := strconv.Atoi(val)
+		if err != nil || limit <= 0 || limit > maxAppLogLimit {
+			info.Info = fmt.Sprintf("limit should be a positive integer not more than %d", maxAppLogLimit)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	if val := params.Get("offset"); val != "" {
+		offset, err := strconv.ParseInt(val, 10, 64)
+		if err != nil || offset < 0 {
+			info.Info = "offset should be a non-negative integer"
+			return
+		}
+		filter.Offset = offset
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// Window of stats history asked for, which can't be longer than history kept
+func (m *ServiceMgr) validateStatsHistoryWindow(params url.Values) (window time.Duration, info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	window = statsHistoryWindow
+	if val := params.Get("window"); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d <= 0 || d > statsHistoryInterval*statsHistorySize {
+			info.Info = fmt.Sprintf("window should be a positive duration like 10m, not longer than %v",
+				statsHistoryInterval*statsHistorySize)
+			return
+		}
+		window = d
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// Parses filters for listing pending timers from query params. Non positive maxLimit leaves
+// page size unbounded, which is used by eventing nodes serving a page to the node fanning out
+func (m *ServiceMgr) validateTimerFilter(params url.Values, maxLimit int) (filter *common.TimerFilter, info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	filter = &common.TimerFilter{
+		Callback:  params.Get("callback"),
+		Limit:     defaultTimersPageSize,
+		Reference: params.Get("reference"),
+		Vbucket:   -1,
+	}
+
+	if val := params.Get("vb"); val != "" {
+		vb, err := strconv.Atoi(val)
+		if err != nil || vb < 0 || vb >= maxVbuckets {
+			info.Info = fmt.Sprintf("vb should be an integer between 0 and %d", maxVbuckets-1)
+			return
+		}
+		filter.Vbucket = vb
+	}
+
+	if val := params.Get("from"); val != "" {
+		ts, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			info.Info = fmt.Sprintf("from should be a timestamp in RFC3339 format, err: %v", err)
+			return
+		}
+		filter.From = ts.Unix()
+	}
+
+	if val := params.Get("to"); val != "" {
+		ts, err := time.Parse(time.RFC3339, val)
+		if err != nil {
+			info.Info = fmt.Sprintf("to should be a timestamp in RFC3339 format, err: %v", err)
+			return
+		}
+		filter.To = ts.Unix()
+	}
+
+	if filter.From != 0 && filter.To != 0 && filter.From > filter.To {
+		info.Info = "from should not be later than to"
+		return
+	}
+
+	if val := params.Get("limit"); val != "" {
+		limit, err := strconv.Atoi(val)
+		if err != nil || limit <= 0 || (maxLimit > 0 && limit > maxLimit) {
+			info.Info = fmt.Sprintf("limit should be a positive integer not more than %d", maxLimit)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	if val := params.Get("after"); val != "" {
+		after, err := parseTimerCursor(val)
+		if err != nil {
+			info.Info = fmt.Sprintf("after should be next of an earlier page of timers, err: %v", err)
+			return
+		}
+		filter.After = after
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+// Timer cursor is passed around as due:vb:seq of the last timer of a page
+func formatTimerCursor(cursor *common.TimerCursor) string {
+	return fmt.Sprintf("%d:%d:%d", cursor.Due, cursor.Vbucket, cursor.Seq)
+}
+
+func parseTimerCursor(val string) (*common.TimerCursor, error) {
+	parts := strings.Split(val, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expected due:vb:seq, got: %s", val)
+	}
+
+	due, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	vb, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	seq, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.TimerCursor{Due: due, Seq: seq, Vbucket: uint16(vb)}, nil
+}
+
+// App log sinks are syslog over a unix socket or UDP, or an HTTP collector
+func (m *ServiceMgr) validateAppLogSinks(field string, settings map[string]interface{}) (info *runtimeInfo) {
+	if info = m.validateStringArray(field, settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	info.Code = m.statusCodes.errInvalidConfig.Code
+	for _, sink := range util.ToStringArray(settings[field]) {
+		u, err := url.Parse(sink)
+		if err != nil {
+			info.Info = fmt.Sprintf("In %s %s is not a valid URL, err: %v", field, sink, err)
+			return
+		}
+
+		switch u.Scheme {
+		case "syslog+unix":
+			if u.Path == "" {
+				info.Info = fmt.Sprintf("In %s %s must carry path of syslog socket", field, sink)
+				return
+			}
+		case "syslog+udp", "http", "https":
+			if u.Host == "" {
+				info.Info = fmt.Sprintf("In %s %s must carry host", field, sink)
+				return
+			}
+		default:
+			info.Info = fmt.Sprintf("In %s %s must be a syslog+unix, syslog+udp, http or https URL", field, sink)
+			return
+		}
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
+func (m *ServiceMgr) validateTraceSampleRate(field string, settings map[string]interface{}) (info *runtimeInfo) {
+	if info = m.validateNumber(field, settings); info.Code != m.statusCodes.ok.Code {
+		return
+	}
+
+	if val, ok := settings[field]; ok {
+		if rate := val.(float64); rate < 0 || rate > 1 {
+			info.Code = m.statusCodes.errInvalidConfig.Code
+			info.Info = fmt.Sprintf("%s must be between 0 and 1", field)
+		}
+	}
+	return
+}
+
+// Traces are exported into a file alongside function log if URL is empty, or else to an
+// OTLP/HTTP collector
+func (m *ServiceMgr) validateTraceExportURL(field string, settings map[string]interface{}) (info *runtimeInfo) {
+	info = &runtimeInfo{}
+	info.Code = m.statusCodes.errInvalidConfig.Code
+
+	if val, ok := settings[field]; ok {
+		rawURL, ok := val.(string)
+		if !ok {
+			info.Info = fmt.Sprintf("%s must be a string", field)
+			return
+		}
+
+		if rawURL != "" {
+			u, err := url.Parse(rawURL)
+			if err != nil {
+				info.Info = fmt.Sprintf("%s %s is not a valid URL, err: %v", field, rawURL, err)
+				return
+			}
+
+			if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				info.Info = fmt.Sprintf("%s %s must be an http or https URL carrying host", field, rawURL)
+				return
+			}
+		}
+	}
+
+	info.Code = m.statusCodes.ok.Code
+	return
+}
+
 func (m *ServiceMgr) validateStringArray(field string, settings map[string]interface{}) (info *runtimeInfo) {
 	info = &runtimeInfo{}
 	info.Code = m.statusCodes.errInvalidConfig.Code
@@ -84,6 +84,13 @@ var undeployFunctionCallback = func(args ...interface{}) error {
 	settings["deployment_status"] = false
 	settings["processing_status"] = false
 
+	// Optional settings to be persisted along with undeploy request
+	if len(args) > 2 {
+		for k, v := range args[2].(map[string]interface{}) {
+			settings[k] = v
+		}
+	}
+
 	data, err := json.Marshal(&settings)
 	if err != nil {
 		logging.Errorf("%s [%d] Function: %s failed to marshal settings", logPrefix, s.runningFnsCount(), appName)
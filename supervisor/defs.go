@@ -17,6 +17,10 @@ const (
 	// from operations that are retried upon failure
 	MetakvAppsRetryPath = metakvEventingPath + "retry/"
 
+	// MetakvAppsReprocessPath refers to path where requests to replay vbuckets
+	// of a deployed function are written, one for each eventing node
+	MetakvAppsReprocessPath = metakvEventingPath + "reprocess/"
+
 	// MetakvAppSettingsPath refers to path under metakv where app settings are stored
 	MetakvAppSettingsPath       = metakvEventingPath + "appsettings/"
 	metakvProducerHostPortsPath = metakvEventingPath + "hostports/"
@@ -95,7 +99,13 @@ type SuperSupervisor struct {
 	locallyDeployedApps map[string]string
 
 	// Global config
-	memoryQuota int64 // In MB
+	memoryQuota int64 // In MB, accessed atomically
+
+	appLogSinks        []string // Access controlled by appLogSinksRWMutex
+	appLogSinksRWMutex *sync.RWMutex
+
+	memoryStats        map[string]*common.FunctionMemoryStats // Access controlled by memoryStatsRWMutex
+	memoryStatsRWMutex *sync.RWMutex
 
 	cleanedUpAppMap            map[string]struct{} // Access controlled by default lock
 	mu                         *sync.RWMutex
@@ -7,6 +7,7 @@ import (
 	"github.com/couchbase/eventing/common"
 	"github.com/couchbase/eventing/logging"
 	"github.com/couchbase/eventing/timers"
+	"github.com/couchbase/eventing/util"
 )
 
 // ClearEventStats flushes event processing stats
@@ -97,6 +98,51 @@ func (s *SuperSupervisor) GetCurlLatencyStats(appName string) map[string]uint64
 	return nil
 }
 
+// GetDcpLatencyStats returns histogram of latency from DCP till handler execution
+func (s *SuperSupervisor) GetDcpLatencyStats(appName string) map[string]uint64 {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.GetDcpLatencyStats()
+	}
+	return nil
+}
+
+// GetTimerLatenessStats returns histogram of how late timers of the function fired
+func (s *SuperSupervisor) GetTimerLatenessStats(appName string) map[string]uint64 {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.GetTimerLatenessStats()
+	}
+	return nil
+}
+
+// GetTimerMaxLateness returns how late, in seconds, timers of the function fired in the last minute or two
+func (s *SuperSupervisor) GetTimerMaxLateness(appName string) int64 {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.GetTimerMaxLateness()
+	}
+	return 0
+}
+
+// GetAppLogSinkStats returns counts of application log records of the function forwarded to sinks
+func (s *SuperSupervisor) GetAppLogSinkStats(appName string) map[string]*common.AppLogSinkStats {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.GetAppLogSinkStats()
+	}
+	return nil
+}
+
+// GetMemoryStats returns memory usage of the function against its allowance out of node quota,
+// as of latest pass of memory governor
+func (s *SuperSupervisor) GetMemoryStats(appName string) *common.FunctionMemoryStats {
+	s.memoryStatsRWMutex.RLock()
+	defer s.memoryStatsRWMutex.RUnlock()
+
+	if stats, ok := s.memoryStats[appName]; ok {
+		statsCopy := *stats
+		return &statsCopy
+	}
+	return nil
+}
+
 // GetLocallyDeployedApps returns list of deployed apps and their last deployment time
 func (s *SuperSupervisor) GetLocallyDeployedApps() map[string]string {
 	s.appListRWMutex.RLock()
@@ -159,6 +205,71 @@ func (s *SuperSupervisor) RestPort() string {
 	return s.restPort
 }
 
+// BackfillJobStatus returns progress of a function deployed as one-shot backfill job
+func (s *SuperSupervisor) BackfillJobStatus(appName string) (*common.BackfillJobStatus, error) {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.BackfillJobStatus(), nil
+	}
+	return nil, fmt.Errorf("Eventing.Producer isn't alive")
+}
+
+// CancelTimer cancels timers created with callback and reference by a deployed function on local node
+func (s *SuperSupervisor) CancelTimer(appName, callback, reference string) (bool, error) {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.CancelTimer(callback, reference)
+	}
+	return false, fmt.Errorf("Eventing.Producer isn't alive")
+}
+
+// CheckTimers checks consistency of timer stores of a deployed function on local node
+func (s *SuperSupervisor) CheckTimers(appName string, repair bool) (*common.TimerCheckReport, error) {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.CheckTimers(repair), nil
+	}
+	return nil, fmt.Errorf("Eventing.Producer isn't alive")
+}
+
+// CompleteBackfillJob undeploys a backfill job across the cluster and records its completion in function settings
+func (s *SuperSupervisor) CompleteBackfillJob(appName string) {
+	settings := map[string]interface{}{
+		"backfill_completed": true,
+	}
+	util.Retry(util.NewExponentialBackoff(), &s.retryCount, undeployFunctionCallback, s, appName, settings)
+}
+
+// CrashHistory returns recent crashes of eventing-consumers of a deployed function on local node
+func (s *SuperSupervisor) CrashHistory(appName string) ([]*common.CrashEntry, error) {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.CrashHistory(), nil
+	}
+	return nil, fmt.Errorf("Eventing.Producer isn't alive")
+}
+
+// FailFunction undeploys function whose eventing-consumers are crash looping and
+// marks it as failed
+func (s *SuperSupervisor) FailFunction(appName string) {
+	settings := map[string]interface{}{
+		"crash_loop_failed": true,
+	}
+	util.Retry(util.NewExponentialBackoff(), &s.retryCount, undeployFunctionCallback, s, appName, settings)
+}
+
+// ListTimers returns pending timers of a deployed function on local node
+func (s *SuperSupervisor) ListTimers(appName string, filter *common.TimerFilter) ([]*common.PendingTimer, error) {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.ListTimers(filter)
+	}
+	return nil, fmt.Errorf("Eventing.Producer isn't alive")
+}
+
+// RollbackHistory returns recent DCP rollbacks seen by a deployed function on local node
+func (s *SuperSupervisor) RollbackHistory(appName string) ([]*common.RollbackEntry, error) {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.RollbackHistory(), nil
+	}
+	return nil, fmt.Errorf("Eventing.Producer isn't alive")
+}
+
 // SignalStopDebugger stops V8 Debugger for a specific deployed lambda
 func (s *SuperSupervisor) SignalStopDebugger(appName string) error {
 	logPrefix := "SuperSupervisor::SignalStopDebugger"
@@ -356,6 +467,14 @@ func (s *SuperSupervisor) VbSeqnoStats(appName string) (map[int][]map[string]int
 	return nil, fmt.Errorf("Eventing.Producer isn't alive")
 }
 
+// VbLag returns how far behind vbuckets a deployed function handles on local node are
+func (s *SuperSupervisor) VbLag(appName string) ([]*common.VbLag, error) {
+	if p, ok := s.runningFns()[appName]; ok {
+		return p.VbLag(), nil
+	}
+	return nil, fmt.Errorf("Eventing.Producer isn't alive")
+}
+
 // RemoveProducerToken takes out appName from supervision tree
 func (s *SuperSupervisor) RemoveProducerToken(appName string) {
 	if p, exists := s.runningFns()[appName]; exists {
@@ -0,0 +1,133 @@
+package supervisor
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+)
+
+const (
+	memoryGovernorInterval = 5 * time.Second
+
+	// Allowance over current usage given to a function, so a function that's getting busier
+	// isn't pinned at what it uses right now
+	memoryHeadroomPercent = 25
+)
+
+// Periodically redistributes node memory quota across running functions, till stopCh is closed
+func (s *SuperSupervisor) governMemory(stopCh <-chan struct{}) {
+	tick := time.NewTicker(memoryGovernorInterval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			s.redistributeMemoryQuota()
+
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// Gives each running function an allowance out of node memory quota based on what its queues
+// actually hold, rather than an equal split. While usage across functions is over node quota,
+// functions using more than their allowance have their DCP feed throttled
+func (s *SuperSupervisor) redistributeMemoryQuota() {
+	logPrefix := "SuperSupervisor::redistributeMemoryQuota"
+
+	memoryQuota := atomic.LoadInt64(&s.memoryQuota)
+	quota := memoryQuota * 1024 * 1024
+	fns := s.runningFns()
+
+	if quota <= 0 || len(fns) == 0 {
+		s.memoryStatsRWMutex.Lock()
+		s.memoryStats = make(map[string]*common.FunctionMemoryStats)
+		s.memoryStatsRWMutex.Unlock()
+		return
+	}
+
+	usage := make(map[string]*common.MemoryUsage)
+	used := make(map[string]int64)
+	var nodeUsed int64
+	for appName, p := range fns {
+		usage[appName] = p.GetMemoryUsage()
+		used[appName] = usage[appName].Total()
+		nodeUsed += used[appName]
+	}
+
+	allowances := allotMemory(quota, used)
+	overQuota := nodeUsed > quota
+
+	memoryStats := make(map[string]*common.FunctionMemoryStats)
+	s.memoryStatsRWMutex.Lock()
+	prevStats := s.memoryStats
+	for appName := range fns {
+		memoryStats[appName] = &common.FunctionMemoryStats{
+			Allowance: allowances[appName],
+			Throttled: overQuota && used[appName] > allowances[appName],
+			Usage:     *usage[appName],
+			Used:      used[appName],
+		}
+	}
+	s.memoryStats = memoryStats
+	s.memoryStatsRWMutex.Unlock()
+
+	if overQuota {
+		logging.Infof("%s [%d] Memory used: %d MB over quota: %d MB", logPrefix, len(fns),
+			nodeUsed/(1024*1024), memoryQuota)
+	}
+
+	for appName, p := range fns {
+		stats := memoryStats[appName]
+
+		// Queue caps are set in MB, so allowance is pushed down only when that changes
+		allowance := stats.Allowance / (1024 * 1024)
+		if prev, ok := prevStats[appName]; !ok || prev.Allowance/(1024*1024) != allowance {
+			logging.Infof("%s [%d] Function: %s used: %d MB allowance: %d MB",
+				logPrefix, len(fns), appName, stats.Used/(1024*1024), allowance)
+			if allowance < 1 {
+				allowance = 1
+			}
+			p.UpdateMemoryQuota(allowance)
+		}
+
+		p.SetMemoryThrottle(stats.Throttled)
+	}
+}
+
+// Splits quota across functions in proportion to their demand, which is current usage plus
+// headroom, but no less than half of an equal split. Quota left over once demand is met is
+// shared equally, so functions can grow into it
+func allotMemory(quota int64, used map[string]int64) map[string]int64 {
+	allowances := make(map[string]int64)
+	if len(used) == 0 {
+		return allowances
+	}
+
+	floor := quota / int64(2*len(used))
+	var totalDemand int64
+	for appName, fnUsed := range used {
+		demand := fnUsed + fnUsed*memoryHeadroomPercent/100
+		if demand < floor {
+			demand = floor
+		}
+		allowances[appName] = demand
+		totalDemand += demand
+	}
+
+	if totalDemand <= quota {
+		spare := (quota - totalDemand) / int64(len(used))
+		for appName := range allowances {
+			allowances[appName] += spare
+		}
+		return allowances
+	}
+
+	for appName, demand := range allowances {
+		allowances[appName] = int64(float64(quota) * float64(demand) / float64(totalDemand))
+	}
+	return allowances
+}
@@ -0,0 +1,75 @@
+package supervisor
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllotMemory(t *testing.T) {
+	tests := []struct {
+		name       string
+		quota      int64
+		used       map[string]int64
+		allowances map[string]int64
+	}{
+		{
+			name:       "no functions",
+			quota:      1000,
+			used:       map[string]int64{},
+			allowances: map[string]int64{},
+		},
+		{
+			name:       "idle functions split equally",
+			quota:      1000,
+			used:       map[string]int64{"a": 0, "b": 0, "c": 0},
+			allowances: map[string]int64{"a": 333, "b": 333, "c": 333},
+		},
+		{
+			// Demand of both is the floor of 250, spare is shared equally
+			name:       "under quota",
+			quota:      1000,
+			used:       map[string]int64{"a": 100, "b": 200},
+			allowances: map[string]int64{"a": 500, "b": 500},
+		},
+		{
+			// Demand of a is 600 with headroom, b is held at floor of 250
+			name:       "busy function under quota",
+			quota:      1000,
+			used:       map[string]int64{"a": 480, "b": 10},
+			allowances: map[string]int64{"a": 675, "b": 325},
+		},
+		{
+			// Demand of a is 1000 and b is 250, scaled down to fit quota
+			name:       "over quota",
+			quota:      1000,
+			used:       map[string]int64{"a": 800, "b": 0},
+			allowances: map[string]int64{"a": 800, "b": 200},
+		},
+	}
+
+	for _, test := range tests {
+		allowances := allotMemory(test.quota, test.used)
+		if !reflect.DeepEqual(allowances, test.allowances) {
+			t.Errorf("%s: expected allowances %v, got %v", test.name, test.allowances, allowances)
+		}
+	}
+}
+
+func TestAllotMemoryWithinQuota(t *testing.T) {
+	quota := int64(4096)
+	used := map[string]int64{"a": 3000, "b": 2000, "c": 1, "d": 0}
+
+	allowances := allotMemory(quota, used)
+
+	var total int64
+	for _, allowance := range allowances {
+		total += allowance
+	}
+	if total > quota {
+		t.Errorf("Allowances add up to %d, over quota: %d", total, quota)
+	}
+
+	if allowances["a"] <= allowances["b"] || allowances["b"] <= allowances["c"] || allowances["c"] != allowances["d"] {
+		t.Errorf("Expected allowances to follow usage, got %v", allowances)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/couchbase/eventing/common"
@@ -27,6 +28,7 @@ func NewSuperSupervisor(adminPort AdminPortConfig, eventingDir, kvPort, restPort
 		appProcessingStatus:        make(map[string]bool),
 		bootstrappingApps:          make(map[string]string),
 		CancelCh:                   make(chan struct{}, 1),
+		appLogSinksRWMutex:         &sync.RWMutex{},
 		cleanedUpAppMap:            make(map[string]struct{}),
 		deployedApps:               make(map[string]string),
 		diagDir:                    diagDir,
@@ -35,6 +37,8 @@ func NewSuperSupervisor(adminPort AdminPortConfig, eventingDir, kvPort, restPort
 		keepNodes:                  make([]string, 0),
 		kvPort:                     kvPort,
 		locallyDeployedApps:        make(map[string]string),
+		memoryStats:                make(map[string]*common.FunctionMemoryStats),
+		memoryStatsRWMutex:         &sync.RWMutex{},
 		numVbuckets:                numVbuckets,
 		producerSupervisorTokenMap: make(map[common.EventingProducer]suptree.ServiceToken),
 		restPort:                   restPort,
@@ -80,6 +84,8 @@ func NewSuperSupervisor(adminPort AdminPortConfig, eventingDir, kvPort, restPort
 			}
 		}
 	}()
+
+	go s.governMemory(s.CancelCh)
 	return s
 }
 
@@ -276,6 +282,13 @@ func (s *SuperSupervisor) SettingsChangeCallback(path string, value []byte, rev
 						return err
 					}
 
+					// Reprocess requests left over from previous deployment don't apply to this one
+					if err := util.MetaKvDelete(MetakvAppsReprocessPath+appName+"/"+s.uuid, nil); err != nil {
+						logging.Errorf("%s [%d] Function: %s failed to delete reprocess request from metakv path, err : %v",
+							logPrefix, s.runningFnsCount(), appName, err)
+						return err
+					}
+
 					if state == common.AppStatePaused {
 						if p, ok := s.runningFns()[appName]; ok {
 							logging.Infof("%s [%d] Function: %s stopping running producer instance", logPrefix, s.runningFnsCount(), appName)
@@ -513,10 +526,35 @@ func (s *SuperSupervisor) HandleGlobalConfigChange(config common.Config) error {
 		switch key {
 		case "ram_quota":
 			if quota, ok := value.(float64); ok {
-				s.memoryQuota = int64(quota)
+				atomic.StoreInt64(&s.memoryQuota, int64(quota))
 				s.updateQuotaForRunningFns()
 			}
 
+		case "log_format":
+			if logFormat, ok := value.(string); ok {
+				logging.SetLogFormat(logging.Format(logFormat))
+			}
+
+		case "log_level_overrides":
+			overrides, err := logging.LevelOverridesFrom(value)
+			if err != nil {
+				logging.Errorf("%s [%d] Failed to read log level overrides, err: %v", logPrefix, s.runningFnsCount(), err)
+				continue
+			}
+			logging.SetLogLevelOverrides(overrides)
+
+		case "app_log_sinks":
+			if _, ok := value.([]interface{}); ok {
+				sinks := util.ToStringArray(value)
+				s.appLogSinksRWMutex.Lock()
+				s.appLogSinks = sinks
+				s.appLogSinksRWMutex.Unlock()
+
+				for _, p := range s.runningFns() {
+					p.SetDefaultAppLogSinks(sinks)
+				}
+			}
+
 		case "function_size":
 			if size, ok := value.(float64); ok {
 				util.SetMaxFunctionSize(int(size))
@@ -543,18 +581,24 @@ func (s *SuperSupervisor) HandleGlobalConfigChange(config common.Config) error {
 func (s *SuperSupervisor) updateQuotaForRunningFns() {
 	logPrefix := "SuperSupervisor::updateQuotaForRunningFns"
 
-	if s.memoryQuota <= 0 {
+	memoryQuota := atomic.LoadInt64(&s.memoryQuota)
+	if memoryQuota <= 0 {
 		return
 	}
 
+	// Memory governor pushes its allowances again on next pass, on top of equal split
+	s.memoryStatsRWMutex.Lock()
+	s.memoryStats = make(map[string]*common.FunctionMemoryStats)
+	s.memoryStatsRWMutex.Unlock()
+
 	for _, p := range s.runningFns() {
 		fnCount := int64(s.runningFnsCount())
 		if fnCount > 0 {
 			logging.Infof("%s [%d] Notifying Eventing.Producer instances to update memory quota to %d MB",
-				logPrefix, s.runningFnsCount(), s.memoryQuota)
-			p.UpdateMemoryQuota(s.memoryQuota / fnCount)
+				logPrefix, s.runningFnsCount(), memoryQuota)
+			p.UpdateMemoryQuota(memoryQuota / fnCount)
 		} else {
-			p.UpdateMemoryQuota(s.memoryQuota)
+			p.UpdateMemoryQuota(memoryQuota)
 		}
 	}
 }
@@ -583,15 +627,55 @@ func (s *SuperSupervisor) AppsRetryCallback(path string, value []byte, rev inter
 	return nil
 }
 
+// AppsReprocessCallback informs running function to replay requested vbuckets. Requests are
+// written for each node under reprocess/<appName>/<node uuid>, and are deleted by the node
+// once applied, so that they aren't replayed when eventing restarts
+func (s *SuperSupervisor) AppsReprocessCallback(path string, value []byte, rev interface{}) error {
+	logPrefix := "SuperSupervisor::AppsReprocessCallback"
+	if value == nil {
+		return nil
+	}
+
+	split := strings.Split(strings.TrimPrefix(path, MetakvAppsReprocessPath), "/")
+	if len(split) != 2 || split[1] != s.uuid {
+		return nil
+	}
+	appName := split[0]
+
+	var req common.ReprocessRequest
+	err := json.Unmarshal(value, &req)
+	if err != nil {
+		logging.Errorf("%s [%d] Function: %s Unable to unmarshal reprocess request, err: %v",
+			logPrefix, s.runningFnsCount(), appName, err)
+	} else {
+		logging.Infof("%s [%d] Function: %s Got reprocess request: %#v", logPrefix, s.runningFnsCount(), appName, req)
+
+		if p, exists := s.runningFns()[appName]; exists {
+			p.Reprocess(&req)
+		}
+	}
+
+	if err = util.MetaKvDelete(path, nil); err != nil {
+		logging.Errorf("%s [%d] Function: %s failed to delete reprocess request from metakv, err: %v",
+			logPrefix, s.runningFnsCount(), appName, err)
+	}
+
+	return nil
+}
+
 func (s *SuperSupervisor) spawnApp(appName string, cleanupTimers bool) {
 	logPrefix := "SuperSupervisor::spawnApp"
 
 	metakvAppHostPortsPath := fmt.Sprintf("%s%s/", metakvProducerHostPortsPath, appName)
 
 	p := producer.NewProducer(appName, s.adminPort.DebuggerPort, s.adminPort.HTTPPort, s.adminPort.SslPort, s.eventingDir,
-		s.kvPort, metakvAppHostPortsPath, s.restPort, s.uuid, s.diagDir, cleanupTimers, s.memoryQuota, s.numVbuckets, s)
+		s.kvPort, metakvAppHostPortsPath, s.restPort, s.uuid, s.diagDir, cleanupTimers, atomic.LoadInt64(&s.memoryQuota), s.numVbuckets, s)
+
+	s.appLogSinksRWMutex.RLock()
+	p.SetDefaultAppLogSinks(s.appLogSinks)
+	s.appLogSinksRWMutex.RUnlock()
 
-	logging.Infof("%s [%d] Function: %s spawning up, memory quota: %d", logPrefix, s.runningFnsCount(), appName, s.memoryQuota)
+	logging.Infof("%s [%d] Function: %s spawning up, memory quota: %d", logPrefix, s.runningFnsCount(), appName, atomic.LoadInt64(&s.memoryQuota))
 
 	token := s.superSup.Add(p)
 	s.addToRunningProducers(appName, p)
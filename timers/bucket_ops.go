@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"reflect"
 	"runtime"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -36,6 +38,7 @@ type kvPool struct {
 type poolStats struct {
 	incrCounter    uint64 `json:"kvpool_incr"`
 	insertCounter  uint64 `json:"kvpool_insert"`
+	listCounter    uint64 `json:"kvpool_list"`
 	lookupCounter  uint64 `json:"kvpool_lookup"`
 	replaceCounter uint64 `json:"kvpool_replace"`
 	removeCounter  uint64 `json:"kvpool_remove"`
@@ -172,6 +175,42 @@ func (r *kvPool) Get(bucket, key string, valuePtr interface{}) (cas gocb.Cas, ab
 	return
 }
 
+// ListKeys lists keys through query service, at request plus consistency. It fails unless
+// query service is up and bucket has an index on document keys, such as a primary index
+func (r *kvPool) ListKeys(bucket, prefix string) ([]string, error) {
+	if r.status != nil {
+		return nil, r.status
+	}
+
+	conn, err := r.getConn(bucket)
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&r.stats.listCounter, 1)
+
+	// Prefix is matched again here, as LIKE takes _ in keys for a wildcard
+	query := gocb.NewN1qlQuery(fmt.Sprintf("SELECT RAW META().id FROM `%s` WHERE META().id LIKE $1", bucket))
+	query.Consistency(gocb.RequestPlus)
+	rows, err := conn.ExecuteN1qlQuery(query, []interface{}{prefix + "%"})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0)
+	var key string
+	for rows.Next(&key) {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	if err = rows.Close(); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(keys)
+	return keys, nil
+}
+
 func (r *kvPool) Insert(bucket, key string, value interface{}, expiry uint32) (rcas gocb.Cas, mismatch bool, err error) {
 	if r.status != nil {
 		return 0, false, r.status
@@ -183,13 +222,19 @@ func (r *kvPool) Insert(bucket, key string, value interface{}, expiry uint32) (r
 	}
 	atomic.AddUint64(&r.stats.insertCounter, 1)
 	rcas, err = conn.Insert(key, value, expiry)
-	if err != nil && gocb.IsKeyNotFoundError(err) {
-		mismatch = true
-		err = nil
-	}
+	mismatch, err = insertResult(err)
 	return
 }
 
+// Insert of a key that's already there fails with key exists, which is reported as a cas
+// mismatch so that callers racing another node to create a document can tell it apart
+func insertResult(err error) (mismatch bool, rerr error) {
+	if err != nil && gocb.IsKeyExistsError(err) {
+		return true, nil
+	}
+	return false, err
+}
+
 func (r *kvPool) Replace(bucket, key string, value interface{}, cas gocb.Cas, expiry uint32) (rcas gocb.Cas, absent bool, mismatch bool, err error) {
 	if r.status != nil {
 		return 0, false, false, r.status
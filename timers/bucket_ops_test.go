@@ -0,0 +1,51 @@
+package timers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/couchbase/gocb"
+)
+
+func TestInsertResult(t *testing.T) {
+	failure := errors.New("temporary failure")
+
+	tests := []struct {
+		name     string
+		err      error
+		mismatch bool
+		rerr     error
+	}{
+		{name: "inserted"},
+		{name: "key exists", err: gocb.ErrKeyExists, mismatch: true},
+		{name: "key not found", err: gocb.ErrKeyNotFound, rerr: gocb.ErrKeyNotFound},
+		{name: "other failure", err: failure, rerr: failure},
+	}
+
+	for _, test := range tests {
+		mismatch, err := insertResult(test.err)
+		if mismatch != test.mismatch || err != test.rerr {
+			t.Errorf("%s: expected mismatch: %t err: %v, got mismatch: %t err: %v",
+				test.name, test.mismatch, test.rerr, mismatch, err)
+		}
+	}
+}
+
+func TestMemStorageInsertConflict(t *testing.T) {
+	mem := NewMemStorage()
+
+	if _, mismatch, err := mem.Insert("default", "span", "first", 0); mismatch || err != nil {
+		t.Fatalf("Expected first insert to succeed, got mismatch: %t err: %v", mismatch, err)
+	}
+
+	// Like KV, inserting a key that's already there is a mismatch rather than a failure
+	_, mismatch, err := mem.Insert("default", "span", "second", 0)
+	if !mismatch || err != nil {
+		t.Errorf("Expected second insert to be a mismatch, got mismatch: %t err: %v", mismatch, err)
+	}
+
+	var value string
+	if _, absent, err := mem.Get("default", "span", &value); absent || err != nil || value != "first" {
+		t.Errorf("Expected first value to be kept, got %q absent: %t err: %v", value, absent, err)
+	}
+}
@@ -0,0 +1,289 @@
+package timers
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/logging"
+	"github.com/couchbase/gocb"
+)
+
+// Rows past either end of span looked up for counters that span doesn't cover, when storage
+// can't list rows that exist
+const spanCheckRows = int64(64)
+
+// Stores checked at a time by CheckStores
+const checkParallelism = 8
+
+// Time given to a Set, Rearm or Delete in flight to write or remove the other half of a timer,
+// before a document found without its counterpart is removed as orphan
+var orphanGracePeriod = 5 * time.Second
+
+type orphan struct {
+	key     string
+	cas     gocb.Cas
+	context bool
+}
+
+// Check checks a single store, as CheckStores does
+func (r *TimerStore) Check(repair bool) *common.TimerStoreReport {
+	return CheckStores([]*TimerStore{r}, repair)[0]
+}
+
+// CheckStores checks stores a few at a time, reporting on each of them in order. With repair,
+// orphans found across stores are removed once, after a single grace period
+func CheckStores(stores []*TimerStore, repair bool) []*common.TimerStoreReport {
+	reports := make([]*common.TimerStoreReport, len(stores))
+	orphans := make([][]*orphan, len(stores))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, checkParallelism)
+	for i, store := range stores {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, store *TimerStore) {
+			defer func() { <-sem; wg.Done() }()
+			reports[i], orphans[i] = store.check(repair)
+		}(i, store)
+	}
+	wg.Wait()
+
+	if !repair {
+		return reports
+	}
+
+	found := false
+	for _, storeOrphans := range orphans {
+		found = found || len(storeOrphans) > 0
+	}
+	if !found {
+		return reports
+	}
+
+	time.Sleep(orphanGracePeriod)
+	for i, store := range stores {
+		for _, o := range orphans[i] {
+			removed, err := store.removeOrphan(o)
+			if err != nil {
+				reports[i].Error = err.Error()
+				break
+			}
+			if removed {
+				reports[i].OrphansRemoved++
+			}
+		}
+	}
+	return reports
+}
+
+// Walks rows of the store, verifying that every alarm has a context pointing back at it and every
+// context pointed at by a superseded alarm still has its own alarm. Rows are listed if storage
+// can, or else span and a few rows past either end of it are walked. With repair, span is widened
+// to cover rows found outside it and persisted again. Orphans are returned to be removed later.
+// Contexts are reachable only through alarms, so a context whose alarm was never written isn't found
+func (r *TimerStore) check(repair bool) (*common.TimerStoreReport, []*orphan) {
+	atomic.AddUint64(&r.stats.checkCounter, 1)
+	report := &common.TimerStoreReport{Vbucket: uint16(r.partn)}
+
+	r.span.lock.Lock()
+	span, dirty := r.span.Span, r.span.dirty
+	r.span.lock.Unlock()
+	step := span.step()
+
+	var extspan Span
+	_, absent, err := r.kv.MustGet(r.bucket, r.kvLocatorSpan(), &extspan)
+	if err != nil {
+		report.Error = err.Error()
+		return report, nil
+	}
+	if !dirty && (absent || extspan.Start > span.Start || extspan.Stop < span.Stop) {
+		logging.Infof("%v Persisted span %+v doesn't cover span %+v", r.log, extspan, span)
+		report.SpanMismatch = true
+	}
+
+	orphans := make([]*orphan, 0)
+	seen := make(map[string]struct{})
+	start, stop := span.Start, span.Stop
+
+	checkRow := func(row int64) error {
+		found, err := r.checkRow(row, report, &orphans, seen)
+		if err != nil || !found {
+			return err
+		}
+
+		// Row at span start is considered scanned, so a counter there is outside span too
+		if row <= span.Start || row > span.Stop {
+			report.RowsOutsideSpan = append(report.RowsOutsideSpan, row)
+			if row-step < start {
+				start = row - step
+			}
+			if row > stop {
+				stop = row
+			}
+		}
+		return nil
+	}
+
+	if rows, listed := r.listRows(); listed {
+		for _, row := range rows {
+			if err = checkRow(row); err != nil {
+				break
+			}
+		}
+	} else {
+		for row := span.Start - spanCheckRows*step; row <= span.Stop+spanCheckRows*step; row += step {
+			if err = checkRow(row); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		report.Error = err.Error()
+		return report, nil
+	}
+
+	for _, o := range orphans {
+		if o.context {
+			report.OrphanContexts = append(report.OrphanContexts, o.key)
+			atomic.AddUint64(&r.stats.orphanContextCounter, 1)
+		} else {
+			report.OrphanAlarms = append(report.OrphanAlarms, o.key)
+			atomic.AddUint64(&r.stats.orphanAlarmCounter, 1)
+		}
+	}
+
+	if !repair {
+		return report, nil
+	}
+
+	if report.SpanMismatch || len(report.RowsOutsideSpan) > 0 {
+		if err = r.rewriteSpan(start, stop); err != nil {
+			report.Error = err.Error()
+			return report, nil
+		}
+		report.SpanRewritten = true
+	}
+
+	return report, orphans
+}
+
+// Checks alarms in a row, reporting if the row counter exists
+func (r *TimerStore) checkRow(row int64, report *common.TimerStoreReport, orphans *[]*orphan, seen map[string]struct{}) (bool, error) {
+	seqEnd := int64(0)
+	_, absent, err := r.kv.MustGet(r.bucket, r.kvLocatorRoot(row), &seqEnd)
+	if err != nil || absent {
+		return false, err
+	}
+	report.RowsChecked++
+
+	for seq := init_seq; seq <= seqEnd; seq++ {
+		key := r.kvLocatorAlarm(row, seq)
+
+		alarm := AlarmRecord{}
+		acas, absent, err := r.kv.MustGet(r.bucket, key, &alarm)
+		if err != nil {
+			return true, err
+		}
+		if absent {
+			continue
+		}
+		report.AlarmsChecked++
+
+		context := ContextRecord{}
+		ccas, absent, err := r.kv.MustGet(r.bucket, alarm.ContextRef, &context)
+		if err != nil {
+			return true, err
+		}
+		if !absent && context.AlarmRef == key {
+			continue
+		}
+
+		// Alarm is cancelled or superseded, which fired timers clean up, but its context may
+		// now point at an alarm that is gone as well
+		*orphans = append(*orphans, &orphan{key: key, cas: acas})
+		if absent {
+			continue
+		}
+		if _, found := seen[alarm.ContextRef]; found {
+			continue
+		}
+		seen[alarm.ContextRef] = struct{}{}
+
+		_, absent, err = r.kv.MustGet(r.bucket, context.AlarmRef, &AlarmRecord{})
+		if err != nil {
+			return true, err
+		}
+		if absent {
+			*orphans = append(*orphans, &orphan{key: alarm.ContextRef, cas: ccas, context: true})
+		}
+	}
+
+	return true, nil
+}
+
+// Removes an orphan unless it changed, or got its counterpart, since it was found
+func (r *TimerStore) removeOrphan(o *orphan) (bool, error) {
+	if o.context {
+		context := ContextRecord{}
+		cas, absent, err := r.kv.MustGet(r.bucket, o.key, &context)
+		if err != nil || absent || cas != o.cas {
+			return false, err
+		}
+
+		_, absent, err = r.kv.MustGet(r.bucket, context.AlarmRef, &AlarmRecord{})
+		if err != nil || !absent {
+			return false, err
+		}
+	} else {
+		alarm := AlarmRecord{}
+		cas, absent, err := r.kv.MustGet(r.bucket, o.key, &alarm)
+		if err != nil || absent || cas != o.cas {
+			return false, err
+		}
+
+		context := ContextRecord{}
+		_, absent, err = r.kv.MustGet(r.bucket, alarm.ContextRef, &context)
+		if err != nil || (!absent && context.AlarmRef == o.key) {
+			return false, err
+		}
+	}
+
+	_, absent, mismatch, err := r.kv.MustRemove(r.bucket, o.key, o.cas)
+	if err != nil || absent || mismatch {
+		return false, err
+	}
+
+	logging.Infof("%v Removed orphan %v", r.log, o.key)
+	atomic.AddUint64(&r.stats.orphanRemovedCounter, 1)
+	return true, nil
+}
+
+// Widens span to cover start to stop and persists it, retrying on concurrent span updates
+func (r *TimerStore) rewriteSpan(start, stop int64) error {
+	r.span.lock.Lock()
+	if r.span.Start > start {
+		r.span.Start = start
+	}
+	if r.span.Stop < stop {
+		r.span.Stop = stop
+	}
+	r.span.dirty = true
+	logging.Infof("%v Rewriting span as %+v", r.log, r.span.Span)
+	r.span.lock.Unlock()
+
+	for {
+		mismatch, err := r.syncSpan()
+		if err != nil {
+			return err
+		}
+		if !mismatch {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	atomic.AddUint64(&r.stats.spanRewriteCounter, 1)
+	return nil
+}
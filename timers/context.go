@@ -0,0 +1,97 @@
+package timers
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+)
+
+const (
+	// Contexts larger than this, as JSON, are compressed before being stored
+	compressContextSize = 512
+
+	// Format marker of contexts stored as deflate compressed JSON. Contexts stored as plain
+	// JSON carry no marker, as do all records written before contexts were compressed
+	contextFormatDeflate = "deflate"
+)
+
+// ContextTooLargeError is returned when a context exceeds size limit even after compression
+type ContextTooLargeError struct {
+	Size      int // Bytes of JSON
+	Compacted int // Bytes as stored
+	Limit     int64
+}
+
+func (e *ContextTooLargeError) Error() string {
+	return fmt.Sprintf("timer context is %d bytes, %d bytes after compression, more than limit of %d bytes",
+		e.Size, e.Compacted, e.Limit)
+}
+
+// Builds record of a context to be stored, compressing context if that makes it smaller. With a
+// positive limit, contexts that take more than limit bytes to store are rejected
+func (r *TimerStore) newContextRecord(context interface{}, limit int64) (*ContextRecord, error) {
+	data, err := json.Marshal(context)
+	if err != nil {
+		return nil, err
+	}
+
+	record := &ContextRecord{Context: json.RawMessage(data)}
+	size := len(data)
+
+	if len(data) > compressContextSize {
+		var buf bytes.Buffer
+		w, err := flate.NewWriter(&buf, flate.BestSpeed)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(data); err != nil {
+			return nil, err
+		}
+		if err = w.Close(); err != nil {
+			return nil, err
+		}
+
+		// Compressed bytes are stored base64 encoded in JSON
+		if encoded := base64.StdEncoding.EncodedLen(buf.Len()); encoded < size {
+			record = &ContextRecord{Format: contextFormatDeflate, Data: buf.Bytes()}
+			size = encoded
+			atomic.AddUint64(&r.stats.contextCompressedCounter, 1)
+		}
+	}
+
+	if limit > 0 && int64(size) > limit {
+		atomic.AddUint64(&r.stats.contextTooLargeCounter, 1)
+		return nil, &ContextTooLargeError{Size: len(data), Compacted: size, Limit: limit}
+	}
+
+	atomic.AddUint64(&r.stats.contextBytesCounter, uint64(len(data)))
+	atomic.AddUint64(&r.stats.contextStoredBytesCounter, uint64(size))
+	return record, nil
+}
+
+// Restores context of a record read from storage, decompressing it if needed
+func (r *ContextRecord) decode() error {
+	switch r.Format {
+	case "":
+		return nil
+
+	case contextFormatDeflate:
+		data, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(r.Data)))
+		if err != nil {
+			return fmt.Errorf("unable to decompress timer context, err: %v", err)
+		}
+
+		var context interface{}
+		if err = json.Unmarshal(data, &context); err != nil {
+			return fmt.Errorf("unable to unmarshal decompressed timer context, err: %v", err)
+		}
+		r.Context, r.Format, r.Data = context, "", nil
+		return nil
+	}
+
+	return fmt.Errorf("unknown timer context format %q", r.Format)
+}
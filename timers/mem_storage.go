@@ -0,0 +1,310 @@
+package timers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/couchbase/gocb"
+)
+
+// MemStorage is an in-memory Storage with cas semantics of KV, to exercise timer stores
+// without a cluster. Expiry is ignored. Faults can be injected to fail operations or to
+// race them with a concurrent writer
+type MemStorage struct {
+	lock   sync.Mutex
+	docs   map[string]*memDoc
+	cas    uint64
+	faults []*Fault
+}
+
+type memDoc struct {
+	value []byte
+	cas   gocb.Cas
+}
+
+// Fault applies to operations named Op - counter, get, insert, list, remove, replace or upsert,
+// or to all of them if empty - on keys containing Match. Operations fail with Err, or if
+// Err is nil, the document is modified right before the operation so it sees a changed cas.
+// Fault applies Count times, or until cleared if Count is 0
+type Fault struct {
+	Op    string
+	Match string
+	Err   error
+	Count int
+}
+
+func NewMemStorage() *MemStorage {
+	return &MemStorage{
+		docs: make(map[string]*memDoc),
+	}
+}
+
+// InjectFault adds a fault, checked after the ones injected earlier
+func (m *MemStorage) InjectFault(fault Fault) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.faults = append(m.faults, &fault)
+}
+
+// ClearFaults removes all injected faults
+func (m *MemStorage) ClearFaults() {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.faults = nil
+}
+
+// Keys returns sorted keys of documents in bucket
+func (m *MemStorage) Keys(bucket string) []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	prefix := bucket + "/"
+	keys := make([]string, 0)
+	for loc := range m.docs {
+		if strings.HasPrefix(loc, prefix) {
+			keys = append(keys, strings.TrimPrefix(loc, prefix))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ListKeys returns sorted keys of documents in bucket starting with prefix
+func (m *MemStorage) ListKeys(bucket, prefix string) ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.fault("list", bucket, prefix); err != nil {
+		return nil, err
+	}
+
+	prefix = memLocator(bucket, prefix)
+	keys := make([]string, 0)
+	for loc := range m.docs {
+		if strings.HasPrefix(loc, prefix) {
+			keys = append(keys, strings.TrimPrefix(loc, bucket+"/"))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Touch changes cas of a document as a concurrent writer would, returning false if it's absent
+func (m *MemStorage) Touch(bucket, key string) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	doc, found := m.docs[memLocator(bucket, key)]
+	if found {
+		doc.cas = m.nextCas()
+	}
+	return found
+}
+
+func (m *MemStorage) Counter(bucket, key string, delta, initial int64, expiry uint32) (count int64, cas gocb.Cas, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err = m.fault("counter", bucket, key); err != nil {
+		return
+	}
+
+	count = initial
+	loc := memLocator(bucket, key)
+	if doc, found := m.docs[loc]; found {
+		count, err = strconv.ParseInt(string(doc.value), 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("document %s is not a counter, err: %v", key, err)
+		}
+		count += delta
+	}
+
+	cas = m.nextCas()
+	m.docs[loc] = &memDoc{value: []byte(strconv.FormatInt(count, 10)), cas: cas}
+	return
+}
+
+func (m *MemStorage) Get(bucket, key string, valuePtr interface{}) (cas gocb.Cas, absent bool, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err = m.fault("get", bucket, key); err != nil {
+		return
+	}
+
+	doc, found := m.docs[memLocator(bucket, key)]
+	if !found {
+		return 0, true, nil
+	}
+	return doc.cas, false, json.Unmarshal(doc.value, valuePtr)
+}
+
+func (m *MemStorage) Insert(bucket, key string, value interface{}, expiry uint32) (rcas gocb.Cas, mismatch bool, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err = m.fault("insert", bucket, key); err != nil {
+		return
+	}
+
+	loc := memLocator(bucket, key)
+	if _, found := m.docs[loc]; found {
+		return 0, true, nil
+	}
+	rcas, err = m.write(loc, value)
+	return
+}
+
+func (m *MemStorage) Remove(bucket, key string, cas gocb.Cas) (rcas gocb.Cas, absent bool, mismatch bool, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err = m.fault("remove", bucket, key); err != nil {
+		return
+	}
+
+	loc := memLocator(bucket, key)
+	doc, found := m.docs[loc]
+	switch {
+	case !found:
+		absent = true
+	case cas != 0 && cas != doc.cas:
+		mismatch = true
+	default:
+		delete(m.docs, loc)
+		rcas = m.nextCas()
+	}
+	return
+}
+
+func (m *MemStorage) Replace(bucket, key string, value interface{}, cas gocb.Cas, expiry uint32) (rcas gocb.Cas, absent bool, mismatch bool, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err = m.fault("replace", bucket, key); err != nil {
+		return
+	}
+
+	loc := memLocator(bucket, key)
+	doc, found := m.docs[loc]
+	switch {
+	case !found:
+		absent = true
+	case cas != 0 && cas != doc.cas:
+		mismatch = true
+	default:
+		rcas, err = m.write(loc, value)
+	}
+	return
+}
+
+func (m *MemStorage) Upsert(bucket, key string, value interface{}, expiry uint32) (cas gocb.Cas, err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err = m.fault("upsert", bucket, key); err != nil {
+		return
+	}
+	return m.write(memLocator(bucket, key), value)
+}
+
+func (m *MemStorage) MustCounter(bucket, key string, delta, initial int64, expiry uint32) (val int64, cas gocb.Cas, err error) {
+	err = MustRun(func() (e error) {
+		val, cas, e = m.Counter(bucket, key, delta, initial, expiry)
+		return
+	})
+	return
+}
+
+func (m *MemStorage) MustGet(bucket, key string, valuePtr interface{}) (cas gocb.Cas, absent bool, err error) {
+	err = MustRun(func() (e error) {
+		cas, absent, e = m.Get(bucket, key, valuePtr)
+		return
+	})
+	return
+}
+
+func (m *MemStorage) MustInsert(bucket, key string, value interface{}, expiry uint32) (rcas gocb.Cas, mismatch bool, err error) {
+	err = MustRun(func() (e error) {
+		rcas, mismatch, e = m.Insert(bucket, key, value, expiry)
+		return
+	})
+	return
+}
+
+func (m *MemStorage) MustRemove(bucket, key string, cas gocb.Cas) (rcas gocb.Cas, absent bool, mismatch bool, err error) {
+	err = MustRun(func() (e error) {
+		rcas, absent, mismatch, e = m.Remove(bucket, key, cas)
+		return
+	})
+	return
+}
+
+func (m *MemStorage) MustReplace(bucket, key string, value interface{}, cas gocb.Cas, expiry uint32) (rcas gocb.Cas, absent, mismatch bool, err error) {
+	err = MustRun(func() (e error) {
+		rcas, absent, mismatch, e = m.Replace(bucket, key, value, cas, expiry)
+		return
+	})
+	return
+}
+
+func (m *MemStorage) MustUpsert(bucket, key string, value interface{}, expiry uint32) (cas gocb.Cas, err error) {
+	err = MustRun(func() (e error) {
+		cas, e = m.Upsert(bucket, key, value, expiry)
+		return
+	})
+	return
+}
+
+// Applies first matching fault, called with lock held
+func (m *MemStorage) fault(op, bucket, key string) error {
+	for i, fault := range m.faults {
+		if fault.Op != "" && fault.Op != op {
+			continue
+		}
+		if !strings.Contains(key, fault.Match) {
+			continue
+		}
+
+		if fault.Count > 0 {
+			fault.Count--
+			if fault.Count == 0 {
+				m.faults = append(m.faults[:i], m.faults[i+1:]...)
+			}
+		}
+
+		if fault.Err != nil {
+			return fault.Err
+		}
+
+		if doc, found := m.docs[memLocator(bucket, key)]; found {
+			doc.cas = m.nextCas()
+		}
+		return nil
+	}
+	return nil
+}
+
+func (m *MemStorage) write(loc string, value interface{}) (gocb.Cas, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0, err
+	}
+
+	cas := m.nextCas()
+	m.docs[loc] = &memDoc{value: data, cas: cas}
+	return cas, nil
+}
+
+func (m *MemStorage) nextCas() gocb.Cas {
+	m.cas++
+	return gocb.Cas(m.cas)
+}
+
+func memLocator(bucket, key string) string {
+	return bucket + "/" + key
+}
@@ -0,0 +1,229 @@
+package timers
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence makes a timer fire repeatedly, either as per a cron expression or at a fixed
+// interval. It's persisted in timer's context and advanced every time the timer fires
+type Recurrence struct {
+	Cron           string `json:"cron,omitempty"`
+	Interval       int64  `json:"interval,omitempty"` // seconds
+	Jitter         int64  `json:"jitter,omitempty"`   // seconds
+	MaxOccurrences int64  `json:"max_occurrences,omitempty"`
+	Occurrence     int64  `json:"occurrence"`
+	ScheduledDue   int64  `json:"scheduled_due"` // Due time of current occurrence, before jitter
+}
+
+// Validate checks that exactly one of cron expression and interval is set and is usable
+// with timers of given resolution
+func (r *Recurrence) Validate(resolution int64) error {
+	switch {
+	case r.Cron == "" && r.Interval == 0:
+		return fmt.Errorf("either cron or interval must be specified")
+	case r.Cron != "" && r.Interval != 0:
+		return fmt.Errorf("only one of cron and interval can be specified")
+	case r.Interval != 0 && r.Interval < resolution:
+		return fmt.Errorf("interval must be at least %d seconds", resolution)
+	case r.Jitter < 0:
+		return fmt.Errorf("jitter must not be negative")
+	case r.MaxOccurrences < 0:
+		return fmt.Errorf("max_occurrences must not be negative")
+	}
+
+	if r.Cron != "" {
+		if _, err := parseCron(r.Cron); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Advance moves recurrence past its current occurrence and returns due time of the next one,
+// including jitter. Occurrences missed while timers weren't being fired are skipped. Returns
+// false once max occurrences have fired
+func (r *Recurrence) Advance(now int64) (int64, bool, error) {
+	if r.MaxOccurrences > 0 && r.Occurrence+1 >= r.MaxOccurrences {
+		return 0, false, nil
+	}
+
+	var next int64
+	if r.Interval > 0 {
+		next = r.ScheduledDue + r.Interval
+		if next <= now {
+			next += ((now-next)/r.Interval + 1) * r.Interval
+		}
+	} else {
+		sched, err := parseCron(r.Cron)
+		if err != nil {
+			return 0, false, err
+		}
+
+		from := r.ScheduledDue
+		if from < now {
+			from = now
+		}
+
+		nextTs, ok := sched.next(time.Unix(from, 0).UTC())
+		if !ok {
+			return 0, false, nil
+		}
+		next = nextTs.Unix()
+	}
+
+	r.Occurrence++
+	r.ScheduledDue = next
+
+	due := next
+	if r.Jitter > 0 {
+		due += rand.Int63n(r.Jitter + 1)
+	}
+	return due, true, nil
+}
+
+// Standard 5 field cron expression - minute, hour, day of month, month and day of week,
+// evaluated in UTC. Each field is a bit set of values it matches
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+type cronField struct {
+	min, max int
+}
+
+var cronFields = []cronField{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 6},  // day of week, 0 is Sunday
+}
+
+// Years searched for a matching time, to give up on expressions like "0 0 30 2 *"
+const cronSearchYears = 5
+
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have %d fields", expr, len(cronFields))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, field := range fields {
+		var err error
+		bits[i], err = parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q is invalid, err: %v", expr, err)
+		}
+	}
+
+	return &cronSchedule{
+		minute:  bits[0],
+		hour:    bits[1],
+		dom:     bits[2],
+		month:   bits[3],
+		dow:     bits[4],
+		domStar: fields[2] == "*",
+		dowStar: fields[4] == "*",
+	}, nil
+}
+
+// Parses comma separated list of "*", "n", "n-m", each optionally followed by "/step"
+func parseCronField(field string, bounds cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if pos := strings.Index(part, "/"); pos >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[pos+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:pos]
+		}
+
+		start, end := bounds.min, bounds.max
+		switch {
+		case part == "*":
+
+		case strings.Contains(part, "-"):
+			rng := strings.SplitN(part, "-", 2)
+			var err1, err2 error
+			start, err1 = strconv.Atoi(rng[0])
+			end, err2 = strconv.Atoi(rng[1])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+
+		default:
+			var err error
+			start, err = strconv.Atoi(part)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			end = start
+			if step > 1 {
+				end = bounds.max
+			}
+		}
+
+		if start < bounds.min || end > bounds.max || start > end {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, bounds.min, bounds.max)
+		}
+
+		for val := start; val <= end; val += step {
+			bits |= 1 << uint(val)
+		}
+	}
+
+	return bits, nil
+}
+
+// Returns first time strictly after t matching the schedule
+func (s *cronSchedule) next(t time.Time) (time.Time, bool) {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = t.Truncate(time.Hour).Add(time.Hour)
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t, true
+	}
+
+	return time.Time{}, false
+}
+
+// As with cron, when both day of month and day of week are restricted, matching either suffices
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
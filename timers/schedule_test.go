@@ -0,0 +1,184 @@
+package timers
+
+import (
+	"testing"
+	"time"
+)
+
+func cronBits(vals ...int) uint64 {
+	var bits uint64
+	for _, val := range vals {
+		bits |= 1 << uint(val)
+	}
+	return bits
+}
+
+func rangeOf(start, end int) []int {
+	vals := make([]int, 0, end-start+1)
+	for val := start; val <= end; val++ {
+		vals = append(vals, val)
+	}
+	return vals
+}
+
+func TestParseCronField(t *testing.T) {
+	minute, dow := cronFields[0], cronFields[4]
+
+	tests := []struct {
+		field  string
+		bounds cronField
+		bits   uint64
+		fail   bool
+	}{
+		{field: "*", bounds: dow, bits: cronBits(0, 1, 2, 3, 4, 5, 6)},
+		{field: "5", bounds: minute, bits: cronBits(5)},
+		{field: "1-5", bounds: dow, bits: cronBits(1, 2, 3, 4, 5)},
+		{field: "1,3,5", bounds: dow, bits: cronBits(1, 3, 5)},
+		{field: "*/15", bounds: minute, bits: cronBits(0, 15, 30, 45)},
+		{field: "10-30/10", bounds: minute, bits: cronBits(10, 20, 30)},
+		{field: "5/20", bounds: minute, bits: cronBits(5, 25, 45)},
+		{field: "0,30-32,58/5", bounds: minute, bits: cronBits(0, 30, 31, 32, 58)},
+		{field: "60", bounds: minute, fail: true},
+		{field: "7", bounds: dow, fail: true},
+		{field: "5-1", bounds: dow, fail: true},
+		{field: "*/0", bounds: minute, fail: true},
+		{field: "*/x", bounds: minute, fail: true},
+		{field: "a", bounds: minute, fail: true},
+		{field: "1-b", bounds: minute, fail: true},
+	}
+
+	for _, test := range tests {
+		bits, err := parseCronField(test.field, test.bounds)
+		if test.fail {
+			if err == nil {
+				t.Errorf("Expected field %q to be rejected, got bits %b", test.field, bits)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Failed to parse field %q, err: %v", test.field, err)
+			continue
+		}
+		if bits != test.bits {
+			t.Errorf("Field %q: expected bits %b, got %b", test.field, test.bits, bits)
+		}
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		expr     string
+		schedule *cronSchedule
+	}{
+		{
+			expr: "0 12 * * 1-5",
+			schedule: &cronSchedule{minute: cronBits(0), hour: cronBits(12), dom: cronBits(rangeOf(1, 31)...),
+				month: cronBits(rangeOf(1, 12)...), dow: cronBits(1, 2, 3, 4, 5), domStar: true},
+		},
+		{
+			expr: "*/30 0 1,15 2 *",
+			schedule: &cronSchedule{minute: cronBits(0, 30), hour: cronBits(0), dom: cronBits(1, 15),
+				month: cronBits(2), dow: cronBits(rangeOf(0, 6)...), dowStar: true},
+		},
+		{expr: "0 12 * *"},
+		{expr: "0 12 * * * *"},
+		{expr: "0 24 * * *"},
+		{expr: "0 0 0 * *"},
+		{expr: "0 0 * 13 *"},
+	}
+
+	for _, test := range tests {
+		schedule, err := parseCron(test.expr)
+		if test.schedule == nil {
+			if err == nil {
+				t.Errorf("Expected expression %q to be rejected", test.expr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Failed to parse expression %q, err: %v", test.expr, err)
+			continue
+		}
+		if *schedule != *test.schedule {
+			t.Errorf("Expression %q: expected schedule %+v, got %+v", test.expr, *test.schedule, *schedule)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		expr string
+		from time.Time
+		next time.Time
+		none bool
+	}{
+		{name: "step", expr: "*/15 * * * *", from: at(2021, 1, 1, 10, 7).Add(30 * time.Second), next: at(2021, 1, 1, 10, 15)},
+		{name: "strictly after", expr: "0 12 * * *", from: at(2021, 1, 1, 12, 0), next: at(2021, 1, 2, 12, 0)},
+		{name: "hour rollover", expr: "30 * * * *", from: at(2021, 1, 1, 10, 45), next: at(2021, 1, 1, 11, 30)},
+		{name: "day rollover", expr: "0 0 * * *", from: at(2021, 1, 31, 23, 59), next: at(2021, 2, 1, 0, 0)},
+		{name: "year rollover", expr: "0 0 1 * *", from: at(2021, 12, 15, 0, 0), next: at(2022, 1, 1, 0, 0)},
+		{name: "month restricted", expr: "0 0 1 3 *", from: at(2021, 3, 2, 0, 0), next: at(2022, 3, 1, 0, 0)},
+		{name: "short months skipped", expr: "0 0 31 * *", from: at(2021, 1, 31, 0, 0), next: at(2021, 3, 31, 0, 0)},
+		{name: "leap day", expr: "0 0 29 2 *", from: at(2021, 1, 1, 0, 0), next: at(2024, 2, 29, 0, 0)},
+		{name: "day of week", expr: "0 9 * * 0", from: at(2021, 6, 1, 0, 0), next: at(2021, 6, 6, 9, 0)},
+		{name: "dom or dow, dow first", expr: "0 0 15 * 1", from: at(2021, 6, 1, 0, 0), next: at(2021, 6, 7, 0, 0)},
+		{name: "dom or dow, dom first", expr: "0 0 15 * 1", from: at(2021, 6, 14, 0, 0), next: at(2021, 6, 15, 0, 0)},
+		{name: "never", expr: "0 0 30 2 *", from: at(2021, 1, 1, 0, 0), none: true},
+	}
+
+	for _, test := range tests {
+		schedule, err := parseCron(test.expr)
+		if err != nil {
+			t.Fatalf("%s: failed to parse expression %q, err: %v", test.name, test.expr, err)
+		}
+
+		next, ok := schedule.next(test.from)
+		if test.none {
+			if ok {
+				t.Errorf("%s: expected no match for %q, got %v", test.name, test.expr, next)
+			}
+			continue
+		}
+		if !ok || !next.Equal(test.next) {
+			t.Errorf("%s: expected %q after %v to be %v, got %v found: %t",
+				test.name, test.expr, test.from, test.next, next, ok)
+		}
+	}
+}
+
+func TestCronDayMatches(t *testing.T) {
+	// 7 June 2021 is a Monday
+	monday := time.Date(2021, 6, 7, 0, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	fifteenth := time.Date(2021, 6, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr    string
+		day     time.Time
+		matches bool
+	}{
+		{expr: "* * * * *", day: tuesday, matches: true},
+		{expr: "* * 15 * *", day: fifteenth, matches: true},
+		{expr: "* * 15 * *", day: monday, matches: false},
+		{expr: "* * * * 1", day: monday, matches: true},
+		{expr: "* * * * 1", day: tuesday, matches: false},
+		{expr: "* * 15 * 1", day: monday, matches: true},
+		{expr: "* * 15 * 1", day: fifteenth, matches: true},
+		{expr: "* * 15 * 1", day: tuesday, matches: false},
+	}
+
+	for _, test := range tests {
+		schedule, err := parseCron(test.expr)
+		if err != nil {
+			t.Fatalf("Failed to parse expression %q, err: %v", test.expr, err)
+		}
+		if matches := schedule.dayMatches(test.day); matches != test.matches {
+			t.Errorf("Expected %q matching %v to be %t, got %t", test.expr, test.day, test.matches, matches)
+		}
+	}
+}
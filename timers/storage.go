@@ -0,0 +1,48 @@
+package timers
+
+import (
+	"sync"
+
+	"github.com/couchbase/gocb"
+)
+
+// Storage holds documents of timer stores. Absent and mismatch report a missing document
+// and a cas mismatch, while Must variants retry any other error and only fail with
+// common.ErrRetryTimeout
+type Storage interface {
+	MustCounter(bucket, key string, delta, initial int64, expiry uint32) (val int64, cas gocb.Cas, err error)
+	MustGet(bucket, key string, valuePtr interface{}) (cas gocb.Cas, absent bool, err error)
+	MustInsert(bucket, key string, value interface{}, expiry uint32) (rcas gocb.Cas, mismatch bool, err error)
+	MustRemove(bucket, key string, cas gocb.Cas) (rcas gocb.Cas, absent bool, mismatch bool, err error)
+	MustReplace(bucket, key string, value interface{}, cas gocb.Cas, expiry uint32) (rcas gocb.Cas, absent, mismatch bool, err error)
+	MustUpsert(bucket, key string, value interface{}, expiry uint32) (cas gocb.Cas, err error)
+}
+
+// KeyLister is implemented by storage that can list keys of documents, so that walks over a
+// whole store visit rows that exist rather than looking up every row of span
+type KeyLister interface {
+	ListKeys(bucket, prefix string) ([]string, error)
+}
+
+var (
+	storage     Storage
+	storageLock sync.RWMutex
+)
+
+// SetStorage makes stores created from here on use given storage instead of KV pool
+// connected to cluster. Passing nil switches back to KV pool
+func SetStorage(s Storage) {
+	storageLock.Lock()
+	defer storageLock.Unlock()
+	storage = s
+}
+
+func storageFor(connstr string) Storage {
+	storageLock.RLock()
+	defer storageLock.RUnlock()
+
+	if storage != nil {
+		return storage
+	}
+	return Pool(connstr)
+}
@@ -120,7 +120,7 @@ func createStores() []*timers.TimerStore {
 
 	uid := "timertest-" + strconv.FormatInt(time.Now().Unix(), 36)
 	for partn := 0; partn < partitions; partn++ {
-		err := timers.Create(uid, partn, connStr, "default")
+		err := timers.Create(uid, partn, connStr, "default", timers.Resolution)
 		if err != nil {
 			log.Printf("partition: %d failed to create store handle, err: %v\n", partn, err)
 		} else {
@@ -31,7 +31,7 @@ func run(partn int, load int, pwg *sync.WaitGroup) {
 	if len(os.Args) == 2 {
 		cstr = os.Args[1]
 	}
-	timers.Create(uid, partn, cstr, "default")
+	timers.Create(uid, partn, cstr, "default", timers.Resolution)
 	store, present := timers.Fetch(uid, partn)
 	if !present {
 		panic("store was absent")
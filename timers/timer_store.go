@@ -5,7 +5,9 @@ package timers
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -18,7 +20,7 @@ import (
 
 // Constants
 const (
-	Resolution  = int64(7) // seconds
+	Resolution  = int64(7) // seconds, default resolution of timers
 	init_seq    = int64(128)
 	tail_time   = int64(60)
 	dict        = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789*&"
@@ -28,6 +30,9 @@ const (
 // Globals
 var (
 	stores *storeMap
+
+	// Time timers are due against, stubbed out by tests
+	clock = time.Now
 )
 
 type storeMap struct {
@@ -38,13 +43,18 @@ type storeMap struct {
 }
 
 type AlarmRecord struct {
-	AlarmDue   int64  `json:"due"`
-	ContextRef string `json:"cxr"`
+	AlarmDue     int64  `json:"due"`
+	ContextRef   string `json:"cxr"`
+	RequestedDue int64  `json:"rqd,omitempty"` // Due time asked for, before rounding to resolution
 }
 
+// ContextRecord holds context as JSON, or compressed as marked by Format, in which case it's
+// restored to Context when read back
 type ContextRecord struct {
 	Context  interface{} `json:"ctx"`
 	AlarmRef string      `json:"alr"`
+	Format   string      `json:"fmt,omitempty"`
+	Data     []byte      `json:"dat,omitempty"`
 }
 
 type TimerEntry struct {
@@ -56,6 +66,12 @@ type TimerEntry struct {
 	alrCas   gocb.Cas
 }
 
+// Seq returns position of the timer among timers due at the same time, in the order
+// they're scanned
+func (e *TimerEntry) Seq() int64 {
+	return e.alarmSeq
+}
+
 // This can be used to delete a timer from outside this project, as follows:
 //  1. Delete context_key from bucket with context_cas, ignore any absent/mismatch error
 //  2. Delete alarm_key from bucket with alarm_cas, log any absent/mismatch error
@@ -71,6 +87,7 @@ type rowIter struct {
 	start   int64
 	stop    int64
 	current int64
+	step    int64
 }
 
 type colIter struct {
@@ -80,9 +97,13 @@ type colIter struct {
 	topCas  gocb.Cas
 }
 
+// Span bounds rows of a partition yet to be scanned. Rows are laid out at multiples of
+// Resolution, which may be finer than resolution of the store if timers were earlier
+// created with a different one
 type Span struct {
-	Start int64 `json:"sta"`
-	Stop  int64 `json:"stp"`
+	Start      int64 `json:"sta"`
+	Stop       int64 `json:"stp"`
+	Resolution int64 `json:"res,omitempty"`
 }
 
 type storeSpan struct {
@@ -94,20 +115,26 @@ type storeSpan struct {
 }
 
 type TimerStore struct {
-	connstr string
-	bucket  string
-	uid     string
-	partn   int
-	log     string
-	span    storeSpan
-	stats   timerStats
+	kv         Storage
+	bucket     string
+	uid        string
+	partn      int
+	log        string
+	resolution int64
+	span       storeSpan
+	stats      timerStats
 }
 
 type TimerIter struct {
-	store *TimerStore
-	row   rowIter
-	col   *colIter
-	entry *TimerEntry
+	store    *TimerStore
+	row      rowIter
+	col      *colIter
+	entry    *TimerEntry
+	readOnly bool // Inspect timers without cleaning up alarms, row counters or span
+
+	// Columns of row resumeRow up to resumeSeq were seen by an earlier scan
+	resumeRow int64
+	resumeSeq int64
 }
 
 type timerStats struct {
@@ -115,6 +142,8 @@ type timerStats struct {
 	cancelSuccessCounter        uint64 `json:"meta_cancel_success"`
 	delCounter                  uint64 `json:"meta_del"`
 	delSuccessCounter           uint64 `json:"meta_del_success"`
+	rearmCounter                uint64 `json:"meta_rearm"`
+	rearmSuccessCounter         uint64 `json:"meta_rearm_success"`
 	setCounter                  uint64 `json:"meta_set"`
 	setSuccessCounter           uint64 `json:"meta_set_success"`
 	timerInPastCounter          uint64 `json:"meta_timer_in_past"`
@@ -133,6 +162,16 @@ type timerStats struct {
 	spanStartChangeCounter      uint64 `json:"meta_span_start_change"`
 	spanStopChangeCounter       uint64 `json:"meta_span_stop_change"`
 	spanCasMismatchCounter      uint64 `json:"meta_span_cas_mismatch"`
+	checkCounter                uint64 `json:"meta_check"`
+	orphanAlarmCounter          uint64 `json:"meta_orphan_alarm"`
+	orphanContextCounter        uint64 `json:"meta_orphan_context"`
+	orphanRemovedCounter        uint64 `json:"meta_orphan_removed"`
+	spanRewriteCounter          uint64 `json:"meta_span_rewrite"`
+	contextBytesCounter         uint64 `json:"meta_context_bytes"`
+	contextStoredBytesCounter   uint64 `json:"meta_context_stored_bytes"`
+	contextCompressedCounter    uint64 `json:"meta_context_compressed"`
+	contextTooLargeCounter      uint64 `json:"meta_context_too_large"`
+	contextDecodeErrCounter     uint64 `json:"meta_context_decode_err"`
 }
 
 type rebalancer interface {
@@ -148,7 +187,7 @@ func SetRebalancer(r rebalancer) {
 	stores.rebalancer = r
 }
 
-func Create(uid string, partn int, connstr string, bucket string) error {
+func Create(uid string, partn int, connstr string, bucket string, resolution int64) error {
 	logPrefix := "TimerStore::Create"
 
 	stores.lock.Lock()
@@ -159,7 +198,7 @@ func Create(uid string, partn int, connstr string, bucket string) error {
 		logging.Warnf("%s Asked to create store %v:%v which exists. Reusing", logPrefix, uid, partn)
 		return nil
 	}
-	store, err := newTimerStore(uid, partn, connstr, bucket)
+	store, err := newTimerStore(uid, partn, connstr, bucket, resolution)
 	if err != nil {
 		return err
 	}
@@ -180,6 +219,39 @@ func Fetch(uid string, partn int) (store *TimerStore, found bool) {
 	return
 }
 
+// Open reads persisted span of a store without registering the store or writing to storage,
+// so that timers of a function that isn't running can be inspected. found is false if span
+// was never persisted, in which case there are no timers to scan
+func Open(uid string, partn int, connstr string, bucket string, resolution int64) (store *TimerStore, found bool, err error) {
+	if resolution <= 0 {
+		resolution = Resolution
+	}
+
+	store = &TimerStore{
+		kv:         storageFor(connstr),
+		bucket:     bucket,
+		uid:        uid,
+		partn:      partn,
+		log:        fmt.Sprintf("timerstore:%v:%v", uid, partn),
+		resolution: resolution,
+		span:       storeSpan{empty: true, dirty: false},
+	}
+
+	extspan := Span{}
+	rcas, absent, err := store.kv.MustGet(bucket, store.kvLocatorSpan(), &extspan)
+	if err != nil || absent {
+		return nil, false, err
+	}
+
+	store.span.empty = false
+	store.span.Span = extspan
+	store.span.spanCas = rcas
+	store.span.Resolution = gcd(extspan.step(), resolution)
+
+	logging.Tracef("%v Opened read only store with span %+v", store.log, store.span)
+	return store, true, nil
+}
+
 func (r *TimerStore) Free() {
 	stores.lock.Lock()
 	delete(stores.entries, mapLocator(r.uid, r.partn))
@@ -187,35 +259,77 @@ func (r *TimerStore) Free() {
 	r.syncSpan()
 }
 
+// Drop forgets a store whose timers have all been deleted and removes its row counters and
+// span, instead of persisting span again as Free does. Row counters are listed if storage
+// can, or else looked up at every row of span
+func (r *TimerStore) Drop() error {
+	stores.lock.Lock()
+	delete(stores.entries, mapLocator(r.uid, r.partn))
+	stores.lock.Unlock()
+
+	r.span.lock.Lock()
+	span, empty := r.span.Span, r.span.empty
+	r.span.lock.Unlock()
+
+	if rows, listed := r.listRows(); listed {
+		for _, row := range rows {
+			if _, _, _, err := r.kv.MustRemove(r.bucket, r.kvLocatorRoot(row), 0); err != nil {
+				return err
+			}
+		}
+	} else if !empty {
+		step := span.step()
+		for row := span.Start; row <= span.Stop; row += step {
+			if _, _, _, err := r.kv.MustRemove(r.bucket, r.kvLocatorRoot(row), 0); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, _, _, err := r.kv.MustRemove(r.bucket, r.kvLocatorSpan(), 0)
+	return err
+}
+
 func (r *TimerStore) Set(due int64, ref string, context interface{}) error {
-	now := time.Now().Unix()
+	return r.SetWithLimit(due, ref, context, 0)
+}
+
+// SetWithLimit creates a timer like Set, failing with ContextTooLargeError if context takes
+// more than limit bytes to store, after compression
+func (r *TimerStore) SetWithLimit(due int64, ref string, context interface{}, limit int64) error {
+	now := clock().Unix()
 	atomic.AddUint64(&r.stats.setCounter, 1)
 
-	if due-now <= Resolution {
+	requested := due
+	if due-now <= r.resolution {
 		atomic.AddUint64(&r.stats.timerInPastCounter, 1)
 		logging.Debugf("%v Moving too close/past timer to next period: %v context %ru", r.log, formatInt(due), context)
-		due = now + Resolution
+		due = now + r.resolution
+	}
+	due = roundUp(due, r.resolution)
+
+	crecord, err := r.newContextRecord(context, limit)
+	if err != nil {
+		return err
 	}
-	due = roundUp(due)
 
-	kv := Pool(r.connstr)
 	pos := r.kvLocatorRoot(due)
-	seq, _, err := kv.MustCounter(r.bucket, pos, 1, init_seq, 0)
+	seq, _, err := r.kv.MustCounter(r.bucket, pos, 1, init_seq, 0)
 	if err != nil {
 		return err
 	}
 
 	akey := r.kvLocatorAlarm(due, seq)
 	ckey := r.kvLocatorContext(ref)
+	crecord.AlarmRef = akey
 
-	arecord := AlarmRecord{AlarmDue: due, ContextRef: ckey}
-	_, err = kv.MustUpsert(r.bucket, akey, arecord, 0)
+	arecord := AlarmRecord{AlarmDue: due, ContextRef: ckey, RequestedDue: requested}
+	_, err = r.kv.MustUpsert(r.bucket, akey, arecord, 0)
 	if err != nil {
 		return err
 	}
 
-	crecord := ContextRecord{Context: context, AlarmRef: akey}
-	_, err = kv.MustUpsert(r.bucket, ckey, crecord, 0)
+	_, err = r.kv.MustUpsert(r.bucket, ckey, crecord, 0)
 	if err != nil {
 		return err
 	}
@@ -229,9 +343,7 @@ func (r *TimerStore) Set(due int64, ref string, context interface{}) error {
 func (r *TimerStore) Delete(entry *TimerEntry) error {
 	logging.Tracef("%v Deleting timer %+v", r.log, entry)
 	atomic.AddUint64(&r.stats.delCounter, 1)
-	kv := Pool(r.connstr)
-
-	_, absent, mismatch, err := kv.MustRemove(r.bucket, entry.AlarmRef, entry.alrCas)
+	_, absent, mismatch, err := r.kv.MustRemove(r.bucket, entry.AlarmRef, entry.alrCas)
 	if err != nil {
 		return err
 	}
@@ -241,7 +353,7 @@ func (r *TimerStore) Delete(entry *TimerEntry) error {
 
 	atomic.AddUint64(&r.stats.delSuccessCounter, 1)
 
-	_, absent, mismatch, err = kv.MustRemove(r.bucket, entry.ContextRef, entry.ctxCas)
+	_, absent, mismatch, err = r.kv.MustRemove(r.bucket, entry.ContextRef, entry.ctxCas)
 	if err != nil {
 		return err
 	}
@@ -257,6 +369,62 @@ func (r *TimerStore) Delete(entry *TimerEntry) error {
 	return nil
 }
 
+// Rearm moves a fired timer to its next due time, keeping its reference. Context is replaced
+// only if timer wasn't cancelled or overridden since it was scanned, which is reported by
+// returning false. Alarm of fired occurrence is deleted in either case
+func (r *TimerStore) Rearm(entry *TimerEntry, due int64, context interface{}) (bool, error) {
+	now := clock().Unix()
+	atomic.AddUint64(&r.stats.rearmCounter, 1)
+
+	requested := due
+	if due-now <= r.resolution {
+		due = now + r.resolution
+	}
+	due = roundUp(due, r.resolution)
+
+	pos := r.kvLocatorRoot(due)
+	seq, _, err := r.kv.MustCounter(r.bucket, pos, 1, init_seq, 0)
+	if err != nil {
+		return false, err
+	}
+
+	akey := r.kvLocatorAlarm(due, seq)
+	arecord := AlarmRecord{AlarmDue: due, ContextRef: entry.ContextRef, RequestedDue: requested}
+	_, err = r.kv.MustUpsert(r.bucket, akey, arecord, 0)
+	if err != nil {
+		return false, err
+	}
+	r.expandSpan(due)
+
+	// New alarm is left behind if context changed, scan deletes it as context doesn't point to it
+	crecord, err := r.newContextRecord(context, 0)
+	if err != nil {
+		return false, err
+	}
+	crecord.AlarmRef = akey
+	_, ctxAbsent, ctxMismatch, err := r.kv.MustReplace(r.bucket, entry.ContextRef, crecord, entry.ctxCas, 0)
+	if err != nil {
+		return false, err
+	}
+
+	_, absent, mismatch, err := r.kv.MustRemove(r.bucket, entry.AlarmRef, entry.alrCas)
+	if err != nil {
+		return false, err
+	}
+	if absent || mismatch {
+		logging.Debugf("%v Timer %v seq %v is missing alarm in rearm: %ru", r.log, entry.AlarmDue, entry.alarmSeq, *entry)
+	}
+
+	if ctxAbsent || ctxMismatch {
+		logging.Debugf("%v Timer %v seq %v was cancelled or overridden before rearm: %ru", r.log, entry.AlarmDue, entry.alarmSeq, *entry)
+		return false, nil
+	}
+
+	logging.Tracef("%v Rearmed timer at %v seq %v with context %ru", r.log, formatInt(due), seq, context)
+	atomic.AddUint64(&r.stats.rearmSuccessCounter, 1)
+	return true, nil
+}
+
 func (r *TimerStore) GetToken(e *TimerEntry) *DeleteToken {
 	util.Assert(func() bool { return e.ctxCas != 0 && e.alrCas != 0 })
 	return &DeleteToken{
@@ -272,11 +440,10 @@ func (r *TimerStore) Cancel(ref string) error {
 	atomic.AddUint64(&r.stats.cancelCounter, 1)
 	logging.Tracef("%v Cancelling timer ref %ru", r.log, ref)
 
-	kv := Pool(r.connstr)
 	cpos := r.kvLocatorContext(ref)
 
 	crecord := ContextRecord{}
-	ccas, absent, err := kv.MustGet(r.bucket, cpos, &crecord)
+	ccas, absent, err := r.kv.MustGet(r.bucket, cpos, &crecord)
 	if err != nil {
 		return err
 	}
@@ -286,7 +453,7 @@ func (r *TimerStore) Cancel(ref string) error {
 		return nil
 	}
 
-	_, absent, mismatch, err := kv.MustRemove(r.bucket, cpos, ccas)
+	_, absent, mismatch, err := r.kv.MustRemove(r.bucket, cpos, ccas)
 	if err != nil {
 		return err
 	}
@@ -296,7 +463,7 @@ func (r *TimerStore) Cancel(ref string) error {
 	}
 
 	arecord := AlarmRecord{}
-	acas, absent, err := kv.MustGet(r.bucket, crecord.AlarmRef, &arecord)
+	acas, absent, err := r.kv.MustGet(r.bucket, crecord.AlarmRef, &arecord)
 	if err != nil {
 		return err
 	}
@@ -306,7 +473,7 @@ func (r *TimerStore) Cancel(ref string) error {
 		return nil
 	}
 
-	_, absent, mismatch, err = kv.MustRemove(r.bucket, crecord.AlarmRef, acas)
+	_, absent, mismatch, err = r.kv.MustRemove(r.bucket, crecord.AlarmRef, acas)
 	if err != nil {
 		return err
 	}
@@ -351,7 +518,7 @@ func ForceSpanSync() {
 
 func (r *TimerStore) ScanDue() *TimerIter {
 	span := r.readSpan()
-	now := roundDown(time.Now().Unix())
+	now := roundDown(clock().Unix(), span.step())
 
 	atomic.AddUint64(&r.stats.scanDueCounter, 1)
 	if span.Start > now {
@@ -370,6 +537,7 @@ func (r *TimerStore) ScanDue() *TimerIter {
 			start:   span.Start,
 			current: span.Start,
 			stop:    stop,
+			step:    span.step(),
 		},
 		col: nil,
 	}
@@ -378,6 +546,117 @@ func (r *TimerStore) ScanDue() *TimerIter {
 	return &iter
 }
 
+// ScanRange iterates over timers due between from and to, both in unix seconds, without
+// firing them or modifying the store. Non positive from or to leave that end of range open
+func (r *TimerStore) ScanRange(from, to int64) *TimerIter {
+	span := r.readSpan()
+
+	start := span.Start
+	if from > start {
+		// Rows are scanned after advancing, so begin a row before the one containing from
+		start = roundDown(from, span.step()) - span.step()
+	}
+
+	stop := span.Stop
+	if to > 0 && to < stop {
+		stop = roundUp(to, span.step())
+	}
+
+	if start >= stop {
+		return nil
+	}
+
+	iter := TimerIter{
+		store: r,
+		entry: nil,
+		row: rowIter{
+			start:   start,
+			current: start,
+			stop:    stop,
+			step:    span.step(),
+		},
+		col:      nil,
+		readOnly: true,
+	}
+
+	logging.Tracef("%v Created read only iterator: %+v", r.log, iter)
+	return &iter
+}
+
+// ScanRangeAfter iterates like ScanRange over timers due till to, resuming past the timer at
+// seq among those due at due, so that timers can be listed a page at a time
+func (r *TimerStore) ScanRangeAfter(due, seq, to int64) *TimerIter {
+	span := r.readSpan()
+
+	stop := span.Stop
+	if to > 0 && to < stop {
+		stop = roundUp(to, span.step())
+	}
+
+	// Rows are scanned after advancing, so begin a row before the one resumed
+	start := due - span.step()
+	if start < span.Start {
+		start = span.Start
+	}
+
+	if start >= stop {
+		return nil
+	}
+
+	iter := TimerIter{
+		store: r,
+		entry: nil,
+		row: rowIter{
+			start:   start,
+			current: start,
+			stop:    stop,
+			step:    span.step(),
+		},
+		col:       nil,
+		readOnly:  true,
+		resumeRow: due,
+		resumeSeq: seq,
+	}
+
+	logging.Tracef("%v Created read only iterator resuming after seq %v: %+v", r.log, seq, iter)
+	return &iter
+}
+
+// Lookup returns timer created with ref, or nil if no such timer is pending
+func (r *TimerStore) Lookup(ref string) (*TimerEntry, error) {
+	logging.Tracef("%v Looking up timer ref %ru", r.log, ref)
+
+	cpos := r.kvLocatorContext(ref)
+
+	crecord := ContextRecord{}
+	ccas, absent, err := r.kv.MustGet(r.bucket, cpos, &crecord)
+	if err != nil {
+		return nil, err
+	}
+	if absent {
+		return nil, nil
+	}
+
+	arecord := AlarmRecord{}
+	acas, absent, err := r.kv.MustGet(r.bucket, crecord.AlarmRef, &arecord)
+	if err != nil {
+		return nil, err
+	}
+	if absent || arecord.ContextRef != cpos {
+		logging.Debugf("%v Timer ref %ru has context without matching alarm %v", r.log, ref, crecord.AlarmRef)
+		return nil, nil
+	}
+
+	if err = crecord.decode(); err != nil {
+		return nil, err
+	}
+
+	// Alarm is keyed by its seq among timers due at the same time
+	seq, _ := strconv.ParseInt(crecord.AlarmRef[strings.LastIndex(crecord.AlarmRef, ":")+1:], 10, 64)
+
+	return &TimerEntry{AlarmRecord: arecord, ContextRecord: crecord, alarmSeq: seq, ctxCas: ccas, alrCas: acas}, nil
+}
+
 func (r *TimerIter) ScanNext() (*TimerEntry, error) {
 	if r == nil {
 		return nil, nil
@@ -407,28 +686,35 @@ func (r *TimerIter) ScanNext() (*TimerEntry, error) {
 func (r *TimerIter) nextRow() (bool, error) {
 	atomic.AddUint64(&r.store.stats.scanRowCounter, 1)
 	logging.Tracef("%v Looking for row after %+v", r.store.log, r.row)
-	kv := Pool(r.store.connstr)
-
 	r.col = nil
 	r.entry = nil
 
 	for r.row.current < r.row.stop {
-		r.row.current += Resolution
+		r.row.current += r.row.step
 
 		pos := r.store.kvLocatorRoot(r.row.current)
 		seq_end := int64(0)
 		atomic.AddUint64(&r.store.stats.scanRowLookupCounter, 1)
-		cas, absent, err := kv.MustGet(r.store.bucket, pos, &seq_end)
+		cas, absent, err := r.store.kv.MustGet(r.store.bucket, pos, &seq_end)
 		if err != nil {
 			return false, err
 		}
 		if !absent {
-			r.col = &colIter{current: init_seq, stop: seq_end, topKey: pos, topCas: cas}
+			current := init_seq
+			if r.row.current == r.resumeRow && r.resumeSeq >= current {
+				if r.resumeSeq >= seq_end {
+					continue
+				}
+				current = r.resumeSeq + 1
+			}
+			r.col = &colIter{current: current, stop: seq_end, topKey: pos, topCas: cas}
 			logging.Tracef("%v Found row %+v", r.store.log, r.row)
 			return true, nil
 		}
 		// below handles shrink when row counter never existed. all others cases go to nextColumn
-		r.store.shrinkSpan(r.row.current)
+		if !r.readOnly {
+			r.store.shrinkSpan(r.row.current)
+		}
 	}
 
 	logging.Tracef("%v Found no more rows looking until %v", r.store.log, r.row.stop)
@@ -444,18 +730,18 @@ func (r *TimerIter) nextColumn() (bool, error) {
 		return false, nil
 	}
 
-	kv := Pool(r.store.connstr)
-	alarm := AlarmRecord{}
-	context := ContextRecord{}
-
 	for r.col.current <= r.col.stop {
 		current := r.col.current
 		r.col.current++
 
+		// Fresh records, as fields left out of a document would otherwise keep earlier values
+		alarm := AlarmRecord{}
+		context := ContextRecord{}
+
 		key := r.store.kvLocatorAlarm(r.row.current, current)
 
 		atomic.AddUint64(&r.store.stats.scanColumnLookupCounter, 1)
-		acas, absent, err := kv.MustGet(r.store.bucket, key, &alarm)
+		acas, absent, err := r.store.kv.MustGet(r.store.bucket, key, &alarm)
 		if err != nil {
 			return false, err
 		}
@@ -465,13 +751,16 @@ func (r *TimerIter) nextColumn() (bool, error) {
 		}
 
 		atomic.AddUint64(&r.store.stats.scanColumnLookupCounter, 1)
-		ccas, absent, err := kv.MustGet(r.store.bucket, alarm.ContextRef, &context)
+		ccas, absent, err := r.store.kv.MustGet(r.store.bucket, alarm.ContextRef, &context)
 		if err != nil {
 			return false, err
 		}
 		if absent || context.AlarmRef != key {
+			if r.readOnly {
+				continue
+			}
 			logging.Debugf("%v Alarm canceled or superseded %v by context %ru, deleting it", r.store.log, alarm, context)
-			_, absent, mismatch, err := kv.MustRemove(r.store.bucket, key, acas)
+			_, absent, mismatch, err := r.store.kv.MustRemove(r.store.bucket, key, acas)
 			if err != nil {
 				return false, err
 			}
@@ -481,8 +770,16 @@ func (r *TimerIter) nextColumn() (bool, error) {
 			continue
 		}
 
+		// Timer is left in place rather than failing the scan, which would stall every later
+		// scan at the same timer
+		if err = context.decode(); err != nil {
+			logging.Errorf("%v Skipping timer %v with undecodable context %ru, err: %v", r.store.log, key, alarm.ContextRef, err)
+			atomic.AddUint64(&r.store.stats.contextDecodeErrCounter, 1)
+			continue
+		}
+
 		r.entry = &TimerEntry{AlarmRecord: alarm, ContextRecord: context, alarmSeq: current, ctxCas: ccas, alrCas: acas}
-		if r.entry.AlarmDue > time.Now().Unix() {
+		if !r.readOnly && r.entry.AlarmDue > clock().Unix() {
 			atomic.AddUint64(&r.store.stats.timerInFutureFiredCounter, 1)
 		}
 
@@ -491,9 +788,9 @@ func (r *TimerIter) nextColumn() (bool, error) {
 
 	// row counter exists and but has no timers. shrink logic depends on all chains reducing to this eventually
 	logging.Tracef("%v Column scan finished for %+v at %+v", r.store.log, r, *r.col)
-	if r.col.topCas != 0 {
+	if r.col.topCas != 0 && !r.readOnly {
 		logging.Debugf("%v Row %v was empty, so removing counter", r.store.log, r.col.topKey)
-		_, absent, mismatch, err := kv.MustRemove(r.store.bucket, r.col.topKey, r.col.topCas)
+		_, absent, mismatch, err := r.store.kv.MustRemove(r.store.bucket, r.col.topKey, r.col.topCas)
 		if err != nil {
 			return false, err
 		}
@@ -519,7 +816,7 @@ func (r *TimerStore) readSpan() Span {
 func (r *TimerStore) expandSpan(point int64) {
 	r.span.lock.Lock()
 	defer r.span.lock.Unlock()
-	util.Assert(func() bool { return point >= roundDown(time.Now().Unix()) })
+	util.Assert(func() bool { return point >= roundDown(clock().Unix(), r.span.step()) })
 
 	if r.span.Start > point {
 		logging.Tracef("Expanding span start to %v", r.span)
@@ -536,7 +833,7 @@ func (r *TimerStore) expandSpan(point int64) {
 func (r *TimerStore) shrinkSpan(start int64) {
 	r.span.lock.Lock()
 	defer r.span.lock.Unlock()
-	util.Assert(func() bool { return start <= roundDown(time.Now().Unix()) })
+	util.Assert(func() bool { return start <= roundDown(clock().Unix(), r.span.step()) })
 
 	if r.span.Start < start {
 		r.span.Start = start
@@ -553,11 +850,10 @@ func (r *TimerStore) syncSpan() (bool, error) {
 	defer r.span.lock.Unlock()
 
 	r.span.dirty = false
-	kv := Pool(r.connstr)
 	pos := r.kvLocatorSpan()
 	extspan := Span{}
 
-	rcas, absent, err := kv.MustGet(r.bucket, pos, &extspan)
+	rcas, absent, err := r.kv.MustGet(r.bucket, pos, &extspan)
 	if err != nil {
 		return false, err
 	}
@@ -567,9 +863,9 @@ func (r *TimerStore) syncSpan() (bool, error) {
 
 	// new, not on disk, not on node
 	case absent && r.span.empty:
-		now := time.Now().Unix()
-		r.span.Span = Span{Start: roundDown(now), Stop: roundUp(now)}
-		wcas, mismatch, err := kv.MustInsert(r.bucket, pos, r.span.Span, 0)
+		now := clock().Unix()
+		r.span.Span = Span{Start: roundDown(now, r.resolution), Stop: roundUp(now, r.resolution), Resolution: r.resolution}
+		wcas, mismatch, err := r.kv.MustInsert(r.bucket, pos, r.span.Span, 0)
 		if err != nil || mismatch {
 			logging.Debugf("%v Error initializing span %+v: mismatch=%v err=%v", r.log, r.span, mismatch, err)
 			return false, err
@@ -581,7 +877,7 @@ func (r *TimerStore) syncSpan() (bool, error) {
 
 	// new, not persisted, but we have data locally
 	case absent && !r.span.empty:
-		wcas, mismatch, err := kv.MustInsert(r.bucket, pos, r.span.Span, 0)
+		wcas, mismatch, err := r.kv.MustInsert(r.bucket, pos, r.span.Span, 0)
 		if err != nil || mismatch {
 			logging.Debugf("%v Error initializing span %+v: mismatch=%v err=%v", r.log, r.span, mismatch, err)
 			return false, err
@@ -595,6 +891,8 @@ func (r *TimerStore) syncSpan() (bool, error) {
 		r.span.empty = false
 		r.span.Span = extspan
 		r.span.spanCas = rcas
+		r.span.Resolution = gcd(extspan.step(), r.resolution)
+		r.span.dirty = r.span.Resolution != extspan.Resolution
 		logging.Tracef("%v Span read and initialized to %+v", r.log, r.span)
 		return false, nil
 	}
@@ -610,7 +908,7 @@ func (r *TimerStore) syncSpan() (bool, error) {
 	// only internal changes, no conflict with persisted version
 	case r.span.spanCas == rcas:
 		logging.Tracef("%v Writing span no conflict %+v", r.log, r.span)
-		wcas, absent, mismatch, err := kv.MustReplace(r.bucket, pos, r.span.Span, rcas, 0)
+		wcas, absent, mismatch, err := r.kv.MustReplace(r.bucket, pos, r.span.Span, rcas, 0)
 		if err != nil || absent || mismatch {
 			logging.Debugf("%v Overwriting span %+v failed: absent=%v mismatch=%v err=%v", r.log, r.span, absent, mismatch, err)
 			return mismatch, err
@@ -621,7 +919,7 @@ func (r *TimerStore) syncSpan() (bool, error) {
 
 	// Merge conflict
 	atomic.AddUint64(&r.stats.spanCasMismatchCounter, 1)
-	stores.conflict = time.Now().Unix()
+	stores.conflict = clock().Unix()
 
 	if r.span.Start > extspan.Start {
 		logging.Debugf("%v Span conflict external write, moving Start: span=%+v extspan=%+v", r.span, extspan)
@@ -633,7 +931,8 @@ func (r *TimerStore) syncSpan() (bool, error) {
 		atomic.AddUint64(&r.stats.spanStopChangeCounter, 1)
 		r.span.Stop = extspan.Stop
 	}
-	wcas, absent, mismatch, err := kv.MustReplace(r.bucket, pos, r.span.Span, rcas, 0)
+	r.span.Resolution = gcd(r.span.step(), extspan.step())
+	wcas, absent, mismatch, err := r.kv.MustReplace(r.bucket, pos, r.span.Span, rcas, 0)
 	if err != nil || absent || mismatch {
 		logging.Debugf("%v Overwriting span %+v failed: absent=%v mismatch=%v err=%v", r.log, r.span, absent, mismatch, err)
 		return mismatch, err
@@ -646,10 +945,10 @@ func (r *TimerStore) syncSpan() (bool, error) {
 func (r *storeMap) syncRoutine() {
 	for {
 		if r.rebalancer != nil && r.rebalancer.RebalanceStatus() {
-			r.conflict = time.Now().Unix()
+			r.conflict = clock().Unix()
 		}
 
-		force := time.Now().Unix()-r.conflict < tail_time
+		force := clock().Unix()-r.conflict < tail_time
 		dirty := make([]*TimerStore, 0)
 		r.lock.RLock()
 
@@ -666,14 +965,19 @@ func (r *storeMap) syncRoutine() {
 	}
 }
 
-func newTimerStore(uid string, partn int, connstr string, bucket string) (*TimerStore, error) {
+func newTimerStore(uid string, partn int, connstr string, bucket string, resolution int64) (*TimerStore, error) {
+	if resolution <= 0 {
+		resolution = Resolution
+	}
+
 	timerstore := TimerStore{
-		connstr: connstr,
-		bucket:  bucket,
-		uid:     uid,
-		partn:   partn,
-		log:     fmt.Sprintf("timerstore:%v:%v", uid, partn),
-		span:    storeSpan{empty: true, dirty: false},
+		kv:         storageFor(connstr),
+		bucket:     bucket,
+		uid:        uid,
+		partn:      partn,
+		log:        fmt.Sprintf("timerstore:%v:%v", uid, partn),
+		resolution: resolution,
+		span:       storeSpan{empty: true, dirty: false},
 	}
 
 	_, err := timerstore.syncSpan()
@@ -716,7 +1020,56 @@ func (r *TimerStore) kvLocatorContext(ref string) string {
 }
 
 func (r *TimerStore) kvLocatorSpan() string {
-	return fmt.Sprintf("%v:tm:%v:sp", r.uid, r.partn)
+	return SpanLocator(r.uid, r.partn)
+}
+
+// SpanLocator returns the key under which span of a partition is persisted
+func SpanLocator(uid string, partn int) string {
+	return fmt.Sprintf("%v:tm:%v:sp", uid, partn)
+}
+
+// Rows of the store that have a counter, in order, if storage can list them. Callers walk
+// every row of span otherwise
+func (r *TimerStore) listRows() ([]int64, bool) {
+	lister, ok := r.kv.(KeyLister)
+	if !ok {
+		return nil, false
+	}
+
+	prefix := fmt.Sprintf("%v:tm:%v:rt:", r.uid, r.partn)
+	keys, err := lister.ListKeys(r.bucket, prefix)
+	if err != nil {
+		logging.Warnf("%v Unable to list rows through query service, which needs a primary index on bucket %v, walking span instead, err: %v",
+			r.log, r.bucket, err)
+		return nil, false
+	}
+
+	rows := make([]int64, 0, len(keys))
+	for _, key := range keys {
+		row, err := strconv.ParseInt(strings.TrimPrefix(key, prefix), encode_base, 64)
+		if err != nil {
+			logging.Warnf("%v Skipping row counter with unexpected key %ru", r.log, key)
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i] < rows[j] })
+	return rows, true
+}
+
+// Drained reports if a span has no rows left to be scanned. Row at span start
+// is always considered as already scanned
+func (s Span) Drained() bool {
+	return s.Start >= s.Stop && s.Stop <= roundUp(clock().Unix(), s.step())
+}
+
+// Spans persisted before resolution was configurable don't record it
+func (s Span) step() int64 {
+	if s.Resolution > 0 {
+		return s.Resolution
+	}
+	return Resolution
 }
 
 func (r *TimerStore) Stats() map[string]uint64 {
@@ -754,16 +1107,28 @@ func newStores() *storeMap {
 	return smap
 }
 
-func roundUp(val int64) int64 {
-	q := val / Resolution
-	r := val % Resolution
+func roundUp(val, resolution int64) int64 {
+	q := val / resolution
+	r := val % resolution
 	if r > 0 {
 		q++
 	}
-	return q * Resolution
+	return q * resolution
+}
+
+func roundDown(val, resolution int64) int64 {
+	q := val / resolution
+	return q * resolution
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
 }
 
-func roundDown(val int64) int64 {
-	q := val / Resolution
-	return q * Resolution
+// Resolution returns granularity in seconds at which timers of the store are due
+func (r *TimerStore) Resolution() int64 {
+	return r.resolution
 }
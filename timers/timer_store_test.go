@@ -0,0 +1,342 @@
+package timers
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newMemStore(t testing.TB, resolution int64) (*TimerStore, *MemStorage) {
+	mem := NewMemStorage()
+	SetStorage(mem)
+	defer SetStorage(nil)
+
+	uid := fmt.Sprintf("memtest-%d", time.Now().UnixNano())
+	if err := Create(uid, 0, "", "default", resolution); err != nil {
+		t.Fatalf("Failed to create store, err: %v", err)
+	}
+
+	store, found := Fetch(uid, 0)
+	if !found {
+		t.Fatalf("Store %s not found after creating it", uid)
+	}
+	return store, mem
+}
+
+func countPending(t *testing.T, store *TimerStore) int {
+	count := 0
+	iter := store.ScanRange(0, 0)
+	for {
+		entry, err := iter.ScanNext()
+		if err != nil {
+			t.Fatalf("Failed to scan timers, err: %v", err)
+		}
+		if entry == nil {
+			return count
+		}
+		count++
+	}
+}
+
+func TestSetLookupCancel(t *testing.T) {
+	store, _ := newMemStore(t, 1)
+	defer store.Free()
+
+	due := time.Now().Unix() + 30
+	for _, ref := range []string{"cb:a", "cb:b"} {
+		if err := store.Set(due, ref, ref); err != nil {
+			t.Fatalf("Failed to set timer %s, err: %v", ref, err)
+		}
+	}
+
+	entry, err := store.Lookup("cb:a")
+	if err != nil || entry == nil {
+		t.Fatalf("Expected to find timer cb:a, entry: %v err: %v", entry, err)
+	}
+	if entry.AlarmDue != due || entry.RequestedDue != due {
+		t.Errorf("Expected due %d, got alarm due %d requested due %d", due, entry.AlarmDue, entry.RequestedDue)
+	}
+
+	if count := countPending(t, store); count != 2 {
+		t.Errorf("Expected 2 pending timers, got %d", count)
+	}
+
+	if err = store.Cancel("cb:a"); err != nil {
+		t.Fatalf("Failed to cancel timer, err: %v", err)
+	}
+
+	if entry, _ = store.Lookup("cb:a"); entry != nil {
+		t.Errorf("Expected cancelled timer to be gone, found %+v", entry)
+	}
+	if count := countPending(t, store); count != 1 {
+		t.Errorf("Expected 1 pending timer after cancel, got %d", count)
+	}
+}
+
+func TestScanDueFiresOnce(t *testing.T) {
+	store, mem := newMemStore(t, 1)
+	defer store.Free()
+
+	now := time.Now()
+	clock = func() time.Time { return now }
+	defer func() { clock = time.Now }()
+
+	if err := store.Set(now.Unix(), "cb:now", "context"); err != nil {
+		t.Fatalf("Failed to set timer, err: %v", err)
+	}
+	now = now.Add(3 * time.Second)
+
+	fired := 0
+	iter := store.ScanDue()
+	for {
+		entry, err := iter.ScanNext()
+		if err != nil {
+			t.Fatalf("Failed to scan due timers, err: %v", err)
+		}
+		if entry == nil {
+			break
+		}
+		if err = store.Delete(entry); err != nil {
+			t.Fatalf("Failed to delete fired timer, err: %v", err)
+		}
+		fired++
+	}
+	if fired != 1 {
+		t.Fatalf("Expected 1 timer to fire, got %d", fired)
+	}
+
+	iter = store.ScanDue()
+	if entry, _ := iter.ScanNext(); entry != nil {
+		t.Errorf("Expected no timer to fire again, got %+v", entry)
+	}
+
+	for _, key := range mem.Keys("default") {
+		if key != store.kvLocatorSpan() {
+			t.Errorf("Expected only span to remain, found %s", key)
+		}
+	}
+}
+
+func TestSyncSpanConflict(t *testing.T) {
+	store, mem := newMemStore(t, 1)
+	defer store.Free()
+
+	if err := store.Set(time.Now().Unix()+60, "cb:later", "context"); err != nil {
+		t.Fatalf("Failed to set timer, err: %v", err)
+	}
+
+	if !mem.Touch("default", store.kvLocatorSpan()) {
+		t.Fatalf("Expected span to be persisted")
+	}
+
+	if _, err := store.syncSpan(); err != nil {
+		t.Fatalf("Failed to sync span, err: %v", err)
+	}
+	if atomic.LoadUint64(&store.stats.spanCasMismatchCounter) == 0 {
+		t.Errorf("Expected span cas mismatch to be detected")
+	}
+
+	var extspan Span
+	if _, absent, _ := mem.Get("default", store.kvLocatorSpan(), &extspan); absent {
+		t.Fatalf("Expected span to be persisted after merge")
+	}
+	if extspan != store.readSpan() {
+		t.Errorf("Expected persisted span %+v to match merged span %+v", extspan, store.readSpan())
+	}
+}
+
+func TestTransientFault(t *testing.T) {
+	store, mem := newMemStore(t, 1)
+	defer store.Free()
+
+	mem.InjectFault(Fault{Op: "upsert", Match: ":cx:", Err: errors.New("temporary failure"), Count: 1})
+
+	if err := store.Set(time.Now().Unix()+60, "cb:retried", "context"); err != nil {
+		t.Fatalf("Expected set to succeed after retrying, err: %v", err)
+	}
+	if entry, err := store.Lookup("cb:retried"); err != nil || entry == nil {
+		t.Errorf("Expected to find timer after retry, entry: %v err: %v", entry, err)
+	}
+}
+
+func TestCheckRepairsOrphanAlarm(t *testing.T) {
+	store, mem := newMemStore(t, 1)
+	defer store.Free()
+
+	grace := orphanGracePeriod
+	orphanGracePeriod = 0
+	defer func() { orphanGracePeriod = grace }()
+
+	if err := store.Set(time.Now().Unix()+60, "cb:orphan", "context"); err != nil {
+		t.Fatalf("Failed to set timer, err: %v", err)
+	}
+	entry, err := store.Lookup("cb:orphan")
+	if err != nil || entry == nil {
+		t.Fatalf("Expected to find timer, entry: %v err: %v", entry, err)
+	}
+	if _, absent, _, _ := mem.Remove("default", entry.ContextRef, 0); absent {
+		t.Fatalf("Expected context %s to exist", entry.ContextRef)
+	}
+
+	report := store.Check(false)
+	if len(report.OrphanAlarms) != 1 || report.OrphanAlarms[0] != entry.AlarmRef {
+		t.Fatalf("Expected orphan alarm %s, got report %+v", entry.AlarmRef, report)
+	}
+	if report.OrphansRemoved != 0 {
+		t.Errorf("Expected nothing to be removed without repair, got report %+v", report)
+	}
+
+	report = store.Check(true)
+	if report.OrphansRemoved != 1 {
+		t.Fatalf("Expected orphan alarm to be removed, got report %+v", report)
+	}
+	for _, key := range mem.Keys("default") {
+		if key == entry.AlarmRef {
+			t.Errorf("Expected alarm %s to be removed", key)
+		}
+	}
+
+	if report = store.Check(false); !report.Consistent() {
+		t.Errorf("Expected store to be consistent after repair, got report %+v", report)
+	}
+}
+
+func TestCheckListsRowsOutsideSpan(t *testing.T) {
+	store, mem := newMemStore(t, 1)
+	defer store.Free()
+
+	due := time.Now().Unix() + 60
+	if err := store.Set(due, "cb:inside", "context"); err != nil {
+		t.Fatalf("Failed to set timer, err: %v", err)
+	}
+
+	// Well past rows looked up around span when rows can't be listed
+	row := due + 100*spanCheckRows
+	if _, _, err := mem.Counter("default", store.kvLocatorRoot(row), 1, init_seq, 0); err != nil {
+		t.Fatalf("Failed to create row counter, err: %v", err)
+	}
+
+	report := store.Check(false)
+	if len(report.RowsOutsideSpan) != 1 || report.RowsOutsideSpan[0] != row {
+		t.Errorf("Expected row %d outside span, got report %+v", row, report)
+	}
+	if report.RowsChecked != 2 || report.AlarmsChecked != 1 {
+		t.Errorf("Expected 2 rows and 1 alarm checked, got report %+v", report)
+	}
+
+	mem.InjectFault(Fault{Op: "list", Err: errors.New("no index"), Count: 1})
+	report = store.Check(false)
+	if len(report.RowsOutsideSpan) != 0 || report.AlarmsChecked != 1 {
+		t.Errorf("Expected span to be walked when rows can't be listed, got report %+v", report)
+	}
+}
+
+func TestDropRemovesEmptiedStore(t *testing.T) {
+	store, mem := newMemStore(t, 1)
+
+	if err := store.Set(time.Now().Unix()+60, "cb:moved", "context"); err != nil {
+		t.Fatalf("Failed to set timer, err: %v", err)
+	}
+
+	iter := store.ScanRange(0, 0)
+	for {
+		entry, err := iter.ScanNext()
+		if err != nil {
+			t.Fatalf("Failed to scan timers, err: %v", err)
+		}
+		if entry == nil {
+			break
+		}
+		if err = store.Delete(entry); err != nil {
+			t.Fatalf("Failed to delete timer, err: %v", err)
+		}
+	}
+
+	if err := store.Drop(); err != nil {
+		t.Fatalf("Failed to drop store, err: %v", err)
+	}
+	if keys := mem.Keys("default"); len(keys) != 0 {
+		t.Errorf("Expected no documents to remain, found %v", keys)
+	}
+	if _, found := Fetch(store.uid, store.partn); found {
+		t.Errorf("Expected dropped store to be forgotten")
+	}
+}
+
+func TestCompressedContext(t *testing.T) {
+	store, mem := newMemStore(t, 1)
+	defer store.Free()
+
+	context := map[string]interface{}{"context": strings.Repeat("reminder ", 200)}
+	if err := store.Set(time.Now().Unix()+60, "cb:large", context); err != nil {
+		t.Fatalf("Failed to set timer, err: %v", err)
+	}
+
+	entry, err := store.Lookup("cb:large")
+	if err != nil || entry == nil {
+		t.Fatalf("Expected to find timer, entry: %v err: %v", entry, err)
+	}
+	if !reflect.DeepEqual(entry.Context, context) {
+		t.Errorf("Expected context %v, got %v", context, entry.Context)
+	}
+
+	record := ContextRecord{}
+	if _, absent, _ := mem.Get("default", entry.ContextRef, &record); absent || record.Format != contextFormatDeflate {
+		t.Errorf("Expected context to be stored compressed, got %+v", record)
+	}
+
+	err = store.SetWithLimit(time.Now().Unix()+60, "cb:limited", context, 16)
+	if _, ok := err.(*ContextTooLargeError); !ok {
+		t.Errorf("Expected context over limit to be rejected, got err: %v", err)
+	}
+	if entry, _ = store.Lookup("cb:limited"); entry != nil {
+		t.Errorf("Expected rejected timer to be absent, found %+v", entry)
+	}
+}
+
+func TestScanSkipsUndecodableContext(t *testing.T) {
+	store, mem := newMemStore(t, 1)
+	defer store.Free()
+
+	due := time.Now().Unix() + 60
+	for _, ref := range []string{"cb:broken", "cb:intact"} {
+		if err := store.Set(due, ref, ref); err != nil {
+			t.Fatalf("Failed to set timer %s, err: %v", ref, err)
+		}
+	}
+
+	entry, err := store.Lookup("cb:broken")
+	if err != nil || entry == nil {
+		t.Fatalf("Expected to find timer, entry: %v err: %v", entry, err)
+	}
+
+	record := entry.ContextRecord
+	record.Context, record.Format, record.Data = nil, contextFormatDeflate, []byte("not deflated")
+	if _, err = mem.Upsert("default", entry.ContextRef, record, 0); err != nil {
+		t.Fatalf("Failed to corrupt context, err: %v", err)
+	}
+
+	if count := countPending(t, store); count != 1 {
+		t.Errorf("Expected scan to skip undecodable timer and find 1 timer, got %d", count)
+	}
+	if errs := atomic.LoadUint64(&store.stats.contextDecodeErrCounter); errs != 1 {
+		t.Errorf("Expected 1 undecodable context, got %d", errs)
+	}
+}
+
+func BenchmarkSet(b *testing.B) {
+	store, _ := newMemStore(b, Resolution)
+	defer store.Free()
+
+	due := time.Now().Unix() + 3600
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := store.Set(due, fmt.Sprintf("cb:%d", i), "context"); err != nil {
+			b.Fatalf("Failed to set timer, err: %v", err)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/couchbase/eventing/common"
+	"github.com/couchbase/eventing/timers"
+	"github.com/couchbase/gocb"
+)
+
+// Archive is a gzip compressed stream of JSON records, one per line, laid out as header,
+// function definition, checkpoint blobs, timers and trailer. Trailer carries counts, so
+// a truncated archive is caught on import
+const archiveVersion = 1
+
+const (
+	kindHeader     = "header"
+	kindFunction   = "function"
+	kindCheckpoint = "checkpoint"
+	kindTimer      = "timer"
+	kindTrailer    = "trailer"
+)
+
+const (
+	resumeTimestamp = "timestamp"
+	resumeSeqNos    = "seqnos"
+)
+
+type record struct {
+	Kind       string                 `json:"kind"`
+	Header     *archiveHeader         `json:"header,omitempty"`
+	Function   map[string]interface{} `json:"function,omitempty"`
+	Checkpoint *checkpointRecord      `json:"checkpoint,omitempty"`
+	Timer      *timerRecord           `json:"timer,omitempty"`
+	Trailer    *archiveTrailer        `json:"trailer,omitempty"`
+}
+
+type archiveHeader struct {
+	Version        int    `json:"version"`
+	FunctionName   string `json:"function_name"`
+	FunctionID     uint32 `json:"function_id"`
+	MetadataPrefix string `json:"metadata_prefix"`
+	NumVbuckets    int    `json:"num_vbuckets"`
+	ExportedAt     string `json:"exported_at"`
+	ResumeFrom     string `json:"resume_from"` // RFC3339, mutations older than this were processed
+}
+
+type checkpointRecord struct {
+	Vb   int                    `json:"vb"`
+	Blob map[string]interface{} `json:"blob"`
+}
+
+type timerRecord struct {
+	Vb      int         `json:"vb"`
+	Due     int64       `json:"due"`
+	Ref     string      `json:"ref"`
+	Context interface{} `json:"context"`
+}
+
+type archiveTrailer struct {
+	Checkpoints uint64 `json:"checkpoints"`
+	Timers      uint64 `json:"timers"`
+}
+
+// Subset of function definition the tool needs to look at, rest of it is carried as is
+type functionDef struct {
+	Name       string `json:"appname"`
+	FunctionID uint32 `json:"function_id"`
+	UsingTimer bool   `json:"using_timer"`
+	DepCfg     struct {
+		MetadataBucket string `json:"metadata_bucket"`
+		SourceBucket   string `json:"source_bucket"`
+	} `json:"depcfg"`
+	Settings map[string]interface{} `json:"settings"`
+}
+
+func (f *functionDef) userPrefix() string {
+	if val, ok := f.Settings["user_prefix"].(string); ok && val != "" {
+		return val
+	}
+	return "eventing"
+}
+
+func (f *functionDef) metadataPrefix() string {
+	return common.NewKey(f.userPrefix(), strconv.Itoa(int(f.FunctionID)), "").GetPrefix()
+}
+
+func (f *functionDef) checkpointKey(vb int) string {
+	vbKey := fmt.Sprintf("%s::vb::%d", f.Name, vb)
+	return common.NewKey(f.userPrefix(), strconv.Itoa(int(f.FunctionID)), vbKey).Raw()
+}
+
+func (f *functionDef) timerResolution() int64 {
+	if val, ok := f.Settings["timer_resolution"].(float64); ok && val > 0 {
+		return int64(val)
+	}
+	return timers.Resolution
+}
+
+type archiveWriter struct {
+	file *os.File
+	gz   *gzip.Writer
+	enc  *json.Encoder
+}
+
+func createArchive(path string) (*archiveWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(file)
+	return &archiveWriter{file: file, gz: gz, enc: json.NewEncoder(gz)}, nil
+}
+
+func (w *archiveWriter) write(r *record) error {
+	return w.enc.Encode(r)
+}
+
+func (w *archiveWriter) close() error {
+	if err := w.gz.Close(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
+
+type archiveReader struct {
+	file *os.File
+	gz   *gzip.Reader
+	dec  *json.Decoder
+}
+
+func openArchive(path string) (*archiveReader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(bufio.NewReader(file))
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &archiveReader{file: file, gz: gz, dec: json.NewDecoder(gz)}, nil
+}
+
+// Returns next record, or nil at end of archive
+func (r *archiveReader) read() (*record, error) {
+	rec := &record{}
+	if err := r.dec.Decode(rec); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return rec, nil
+}
+
+func (r *archiveReader) close() {
+	r.gz.Close()
+	r.file.Close()
+}
+
+func openBucket(name string) (*gocb.Bucket, error) {
+	cluster, err := gocb.Connect(kvConnStr)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cluster.Authenticate(gocb.PasswordAuthenticator{Username: username, Password: password})
+	if err != nil {
+		return nil, err
+	}
+	return cluster.OpenBucket(name, "")
+}
+
+func makeRequest(method, url string, payload []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add("content-type", "application/json")
+	req.SetBasicAuth(username, password)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s returned %s: %s", method, url, res.Status, string(data))
+	}
+	return data, nil
+}
+
+// Fetches function definition, both as is and parsed
+func getFunction(name string) (map[string]interface{}, *functionDef, error) {
+	data, err := makeRequest("GET", eventingAddr+"/api/v1/functions/"+name, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw := make(map[string]interface{})
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("unable to unmarshal function: %s, err: %v", name, err)
+	}
+
+	def := &functionDef{}
+	if err = json.Unmarshal(data, def); err != nil {
+		return nil, nil, fmt.Errorf("unable to unmarshal function: %s, err: %v", name, err)
+	}
+	return raw, def, nil
+}
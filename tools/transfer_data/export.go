@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/couchbase/eventing/timers"
+	"github.com/couchbase/gocb"
+)
+
+// Writes definition, checkpoint blobs and pending timers of a paused function to archive.
+// Function has to be paused, so checkpoints and timers stay put while being read
+func exportFunction() error {
+	raw, def, err := getFunction(functionName)
+	if err != nil {
+		return err
+	}
+
+	deployed, _ := def.Settings["deployment_status"].(bool)
+	processing, _ := def.Settings["processing_status"].(bool)
+	if !deployed || processing {
+		return fmt.Errorf("function: %s must be paused before export", functionName)
+	}
+
+	bucket, err := openBucket(def.DepCfg.MetadataBucket)
+	if err != nil {
+		return fmt.Errorf("unable to open metadata bucket: %s, err: %v", def.DepCfg.MetadataBucket, err)
+	}
+	defer bucket.Close()
+
+	w, err := createArchive(archivePath)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	header := &archiveHeader{
+		Version:        archiveVersion,
+		FunctionName:   def.Name,
+		FunctionID:     def.FunctionID,
+		MetadataPrefix: def.metadataPrefix(),
+		NumVbuckets:    numVbuckets,
+		ExportedAt:     now.Format(time.RFC3339),
+		ResumeFrom:     now.Add(-overlap).Format(time.RFC3339),
+	}
+
+	trailer, err := writeFunction(w, header, raw, def, bucket)
+	if err != nil {
+		w.close()
+		return err
+	}
+
+	if err = w.close(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported function: %s prefix: %s checkpoints: %d timers: %d to %s, resume from: %s\n",
+		def.Name, header.MetadataPrefix, trailer.Checkpoints, trailer.Timers, archivePath, header.ResumeFrom)
+	return nil
+}
+
+func writeFunction(w *archiveWriter, header *archiveHeader, raw map[string]interface{}, def *functionDef,
+	bucket *gocb.Bucket) (*archiveTrailer, error) {
+
+	trailer := &archiveTrailer{}
+
+	if err := w.write(&record{Kind: kindHeader, Header: header}); err != nil {
+		return nil, err
+	}
+	if err := w.write(&record{Kind: kindFunction, Function: raw}); err != nil {
+		return nil, err
+	}
+
+	for vb := 0; vb < numVbuckets; vb++ {
+		blob := make(map[string]interface{})
+		_, err := bucket.Get(def.checkpointKey(vb), &blob)
+		if err == gocb.ErrKeyNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("vb: %d unable to read checkpoint, err: %v", vb, err)
+		}
+
+		if err = w.write(&record{Kind: kindCheckpoint, Checkpoint: &checkpointRecord{Vb: vb, Blob: blob}}); err != nil {
+			return nil, err
+		}
+		trailer.Checkpoints++
+	}
+
+	if def.UsingTimer {
+		timers.SetTestAuth(username, password)
+		for vb := 0; vb < numVbuckets; vb++ {
+			count, err := writeVbTimers(w, def, vb)
+			if err != nil {
+				return nil, fmt.Errorf("vb: %d unable to read timers, err: %v", vb, err)
+			}
+			trailer.Timers += count
+		}
+	}
+
+	return trailer, w.write(&record{Kind: kindTrailer, Trailer: trailer})
+}
+
+func writeVbTimers(w *archiveWriter, def *functionDef, vb int) (uint64, error) {
+	// Store is opened read only, as creating it would persist a span on source cluster
+	store, found, err := timers.Open(def.metadataPrefix(), vb, kvConnStr, def.DepCfg.MetadataBucket, def.timerResolution())
+	if err != nil || !found {
+		return 0, err
+	}
+
+	var count uint64
+	iter := store.ScanRange(0, 0)
+	for {
+		entry, err := iter.ScanNext()
+		if err != nil {
+			return count, err
+		}
+		if entry == nil {
+			return count, nil
+		}
+
+		context, _ := entry.Context.(map[string]interface{})
+		callback, _ := context["callback"].(string)
+
+		// Timers stored before references were kept can only be told apart by their context key
+		reference, _ := context["reference"].(string)
+		if reference == "" {
+			reference = entry.ContextRef
+		}
+
+		due := entry.RequestedDue
+		if due == 0 {
+			due = entry.AlarmDue
+		}
+
+		timer := &timerRecord{Vb: vb, Due: due, Ref: callback + ":" + reference, Context: entry.Context}
+		if err = w.write(&record{Kind: kindTimer, Timer: timer}); err != nil {
+			return count, err
+		}
+		count++
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	couchbase "github.com/couchbase/eventing/dcp"
+	"github.com/couchbase/eventing/timers"
+	"github.com/couchbase/gocb"
+)
+
+// Checkpoint fields naming eventing nodes and workers of source cluster, cleared so vbuckets
+// are picked up by whichever node target cluster assigns them to
+var vbOwnerFields = []string{
+	"assigned_worker",
+	"current_vb_owner",
+	"node_requested_vb_stream",
+	"node_uuid",
+	"node_uuid_requested_vb_stream",
+	"previous_assigned_worker",
+	"previous_node_uuid",
+	"previous_vb_owner",
+	"worker_requested_vb_stream",
+}
+
+// Creates function from archive on target cluster, undeployed, with checkpoint blobs and
+// pending timers rewritten under metadata prefix it gets there. Deploying it afterwards
+// resumes processing where it was left on source cluster. If import fails midway, whatever
+// was written is removed along with the function, so that it can be run again
+func importFunction() (err error) {
+	r, err := openArchive(archivePath)
+	if err != nil {
+		return err
+	}
+	defer r.close()
+
+	rec, err := r.read()
+	if err != nil {
+		return err
+	}
+	if rec == nil || rec.Kind != kindHeader {
+		return fmt.Errorf("archive: %s doesn't start with header", archivePath)
+	}
+	header := rec.Header
+	if header.Version > archiveVersion {
+		return fmt.Errorf("archive version: %d is newer than supported version: %d", header.Version, archiveVersion)
+	}
+	if header.NumVbuckets != numVbuckets {
+		return fmt.Errorf("archive has %d vbuckets, but target cluster has %d", header.NumVbuckets, numVbuckets)
+	}
+
+	rec, err = r.read()
+	if err != nil {
+		return err
+	}
+	if rec == nil || rec.Kind != kindFunction {
+		return fmt.Errorf("archive: %s doesn't carry function definition", archivePath)
+	}
+
+	def, err := createFunction(header, rec.Function)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err == nil {
+			return
+		}
+		if rerr := deleteFunction(def.Name); rerr != nil {
+			fmt.Printf("Unable to delete partially imported function: %s, err: %v\n", def.Name, rerr)
+		}
+	}()
+
+	// Checkpointed vbuuids are those of source cluster, which DCP on target cluster doesn't
+	// know of and would answer with a rollback to 0
+	var flogs couchbase.FailoverLog
+	if resume == resumeSeqNos {
+		flogs, err = getFailoverLogs(def.DepCfg.SourceBucket)
+		if err != nil {
+			return fmt.Errorf("unable to read failover logs of source bucket: %s, err: %v", def.DepCfg.SourceBucket, err)
+		}
+	}
+
+	bucket, err := openBucket(def.DepCfg.MetadataBucket)
+	if err != nil {
+		return fmt.Errorf("unable to open metadata bucket: %s, err: %v", def.DepCfg.MetadataBucket, err)
+	}
+	defer bucket.Close()
+
+	timers.SetTestAuth(username, password)
+	stores := make(map[int]*timers.TimerStore)
+	checkpoints := make([]string, 0)
+	defer func() {
+		if err != nil {
+			discardImport(bucket, checkpoints, stores)
+			return
+		}
+
+		timers.ForceSpanSync()
+		for _, store := range stores {
+			store.Free()
+		}
+	}()
+
+	counts := &archiveTrailer{}
+	for {
+		rec, err = r.read()
+		if err != nil {
+			return err
+		}
+		if rec == nil {
+			return fmt.Errorf("archive: %s is truncated, imported checkpoints: %d timers: %d",
+				archivePath, counts.Checkpoints, counts.Timers)
+		}
+
+		switch rec.Kind {
+		case kindCheckpoint:
+			vb := rec.Checkpoint.Vb
+			var blob map[string]interface{}
+			if blob, err = rewriteCheckpoint(vb, rec.Checkpoint.Blob, flogs); err != nil {
+				return err
+			}
+
+			key := def.checkpointKey(vb)
+			checkpoints = append(checkpoints, key)
+			if _, err = bucket.Upsert(key, blob, 0); err != nil {
+				return fmt.Errorf("vb: %d unable to write checkpoint, err: %v", vb, err)
+			}
+			counts.Checkpoints++
+
+		case kindTimer:
+			vb := rec.Timer.Vb
+			store, ok := stores[vb]
+			if !ok {
+				prefix := def.metadataPrefix()
+				err = timers.Create(prefix, vb, kvConnStr, def.DepCfg.MetadataBucket, def.timerResolution())
+				if err != nil {
+					return fmt.Errorf("vb: %d unable to create timer store, err: %v", vb, err)
+				}
+				store, _ = timers.Fetch(prefix, vb)
+				stores[vb] = store
+			}
+
+			if err = store.Set(rec.Timer.Due, rec.Timer.Ref, rec.Timer.Context); err != nil {
+				return fmt.Errorf("vb: %d unable to create timer: %s, err: %v", vb, rec.Timer.Ref, err)
+			}
+			counts.Timers++
+
+		case kindTrailer:
+			if *rec.Trailer != *counts {
+				return fmt.Errorf("archive: %s has checkpoints: %d timers: %d but imported checkpoints: %d timers: %d",
+					archivePath, rec.Trailer.Checkpoints, rec.Trailer.Timers, counts.Checkpoints, counts.Timers)
+			}
+
+			fmt.Printf("Imported function: %s prefix: %s checkpoints: %d timers: %d from %s, exported at: %s\n",
+				def.Name, def.metadataPrefix(), counts.Checkpoints, counts.Timers, archivePath, header.ExportedAt)
+			fmt.Printf("Deploy function: %s to resume processing\n", def.Name)
+			return nil
+
+		default:
+			return fmt.Errorf("archive: %s has unexpected record: %s", archivePath, rec.Kind)
+		}
+	}
+}
+
+// Creates function undeployed on target cluster, under new name and buckets if asked for,
+// and returns its definition as stored there, which carries function id it was assigned
+func createFunction(header *archiveHeader, fn map[string]interface{}) (*functionDef, error) {
+	name := functionName
+	if name == "" {
+		name = header.FunctionName
+	}
+
+	fn["appname"] = name
+	delete(fn, "function_id")
+	delete(fn, "function_instance_id")
+
+	if depcfg, ok := fn["depcfg"].(map[string]interface{}); ok {
+		if sourceBucket != "" {
+			depcfg["source_bucket"] = sourceBucket
+		}
+		if metadataBucket != "" {
+			depcfg["metadata_bucket"] = metadataBucket
+		}
+	}
+
+	settings, ok := fn["settings"].(map[string]interface{})
+	if !ok {
+		settings = make(map[string]interface{})
+		fn["settings"] = settings
+	}
+	settings["deployment_status"] = false
+	settings["processing_status"] = false
+
+	// Seq nos of target cluster don't line up with checkpointed ones, unless its buckets were
+	// restored with seq nos intact, so by default mutations are picked from a point in time. As
+	// cas of documents is preserved by XDCR, it tells apart mutations already processed
+	if resume == resumeTimestamp {
+		settings["dcp_stream_boundary"] = "from_timestamp"
+		settings["dcp_stream_boundary_timestamp"] = header.ResumeFrom
+		delete(settings, "dcp_stream_boundary_seqnos")
+	}
+
+	payload, err := json.Marshal(fn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = makeRequest("POST", eventingAddr+"/api/v1/functions/"+name, payload); err != nil {
+		return nil, err
+	}
+
+	_, def, err := getFunction(name)
+	if err != nil {
+		if rerr := deleteFunction(name); rerr != nil {
+			fmt.Printf("Unable to delete created function: %s, err: %v\n", name, rerr)
+		}
+		return nil, err
+	}
+	return def, nil
+}
+
+func deleteFunction(name string) error {
+	_, err := makeRequest("DELETE", eventingAddr+"/api/v1/functions/"+name, nil)
+	return err
+}
+
+// Removes checkpoint blobs and timers written by an import that failed midway. Timers are
+// deleted one by one before row counters and span of their store are dropped
+func discardImport(bucket *gocb.Bucket, checkpoints []string, stores map[int]*timers.TimerStore) {
+	for _, key := range checkpoints {
+		if _, err := bucket.Remove(key, 0); err != nil && err != gocb.ErrKeyNotFound {
+			fmt.Printf("Unable to remove checkpoint: %s, err: %v\n", key, err)
+		}
+	}
+
+	for vb, store := range stores {
+		if err := discardTimers(store); err != nil {
+			fmt.Printf("vb: %d unable to remove imported timers, err: %v\n", vb, err)
+		}
+	}
+}
+
+func discardTimers(store *timers.TimerStore) error {
+	iter := store.ScanRange(0, 0)
+	for {
+		entry, err := iter.ScanNext()
+		if err != nil {
+			return err
+		}
+		if entry == nil {
+			break
+		}
+
+		if err = store.Delete(entry); err != nil {
+			return err
+		}
+	}
+	return store.Drop()
+}
+
+// Reads failover logs of every vbucket of bucket on target cluster, over DCP
+func getFailoverLogs(bucketName string) (couchbase.FailoverLog, error) {
+	u, err := url.Parse(clusterAddr)
+	if err != nil {
+		return nil, err
+	}
+	u.User = url.UserPassword(username, password)
+
+	client, err := couchbase.Connect(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := client.GetPool("default")
+	if err != nil {
+		return nil, err
+	}
+
+	bucket, err := pool.GetBucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	defer bucket.Close()
+
+	vbs := make([]uint16, 0, numVbuckets)
+	for vb := 0; vb < numVbuckets; vb++ {
+		vbs = append(vbs, uint16(vb))
+	}
+
+	dcpConfig := map[string]interface{}{
+		"genChanSize":    10000,
+		"dataChanSize":   50,
+		"numConnections": 1,
+		"activeVbOnly":   true,
+	}
+	return bucket.GetFailoverLogs(0xABCD, vbs, dcpConfig)
+}
+
+func rewriteCheckpoint(vb int, blob map[string]interface{}, flogs couchbase.FailoverLog) (map[string]interface{}, error) {
+	for _, field := range vbOwnerFields {
+		blob[field] = ""
+	}
+	blob["dcp_stream_status"] = "stopped"
+	blob["dcp_stream_requested"] = false
+	blob["ownership_history"] = []interface{}{}
+
+	// Stream of a vbucket starts from its checkpointed seq no only once bootstrap stream
+	// request was made, otherwise from feed boundary of function, with vbuuid picked when
+	// stream is requested
+	if resume == resumeTimestamp {
+		blob["bootstrap_stream_req_done"] = false
+		blob["last_processed_seq_no"] = 0
+		blob["vb_uuid"] = 0
+		return blob, nil
+	}
+
+	// Seq no is resumed from under the vbuuid of target cluster whose history covers it
+	seqNo, _ := blob["last_processed_seq_no"].(float64)
+	flog, ok := flogs[uint16(vb)]
+	if !ok {
+		return nil, fmt.Errorf("vb: %d has no failover log on target cluster", vb)
+	}
+
+	vbuuid, _, err := flog.FetchLogForSeqNo(uint64(seqNo))
+	if err != nil {
+		return nil, fmt.Errorf("vb: %d has no failover log entry for seq no: %d, err: %v", vb, uint64(seqNo), err)
+	}
+	blob["vb_uuid"] = vbuuid
+	return blob, nil
+}
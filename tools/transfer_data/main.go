@@ -5,83 +5,85 @@ import (
 	"fmt"
 	"os"
 	"strings"
-
-	"github.com/couchbase/eventing/timer_transfer"
+	"time"
 )
 
-var mode, addr, ftype string
-var localFile, remoteFile string
-var readDirectory string
-var resumeID int
+var mode, archivePath, functionName string
+var eventingAddr, clusterAddr, kvConnStr, username, password string
+var sourceBucket, metadataBucket, resume string
+var numVbuckets int
+var overlap time.Duration
 var showSample bool
 
 func usage() {
-	fmt.Println("Start server:")
-	fmt.Println("./transfer_data -mode server -dir /tmp")
+	fmt.Println("Export a paused function, with its checkpoints and pending timers, from source cluster:")
+	fmt.Println("./transfer_data -mode export -function credit_score -archive credit_score.gz \\")
+	fmt.Println("    -eventing http://10.1.1.1:8096 -kv couchbase://10.1.1.1 -user Administrator -pass asdasd")
 	fmt.Println()
-	fmt.Println("Start client to download directory from rpc server:")
-	fmt.Println("./transfer_data -remotefile package -localfile download_dir -type dir -addr 127.0.0.1:53342")
+	fmt.Println("Import it into target cluster, undeployed, ready to be deployed:")
+	fmt.Println("./transfer_data -mode import -archive credit_score.gz \\")
+	fmt.Println("    -eventing http://10.2.2.2:8096 -kv couchbase://10.2.2.2 -user Administrator -pass asdasd")
 	fmt.Println()
-	fmt.Println("Start client to download file from rpc server:")
-	fmt.Println("./transfer_data -remotefile d.img -localfile . -addr 127.0.0.1:53342")
+	fmt.Println("Import under a different name and buckets, resuming from checkpointed seq nos as")
+	fmt.Println("target source bucket shares seq nos with source cluster:")
+	fmt.Println("./transfer_data -mode import -archive credit_score.gz -function credit_score_dc2 \\")
+	fmt.Println("    -source_bucket cards -metadata_bucket cards_meta -resume seqnos -cluster http://10.2.2.2:8091 \\")
+	fmt.Println("    -eventing http://10.2.2.2:8096 -kv couchbase://10.2.2.2 -user Administrator -pass asdasd")
 	fmt.Println()
 }
 
 func init() {
-	flag.StringVar(&mode, "mode", "client", "run mode [client|server]")
-	flag.StringVar(&ftype, "type", "file", "type to download - either file or dir")
-	flag.StringVar(&addr, "addr", ":12427", "bind or connect addr")
-	flag.StringVar(&localFile, "localfile", "", "save to localfile")
-	flag.StringVar(&remoteFile, "remotefile", "", "download from remotefile")
-	flag.StringVar(&readDirectory, "dir", "./", "read directory")
-	flag.IntVar(&resumeID, "resumeID", 0, "resume download")
+	flag.StringVar(&mode, "mode", "", "run mode [export|import]")
+	flag.StringVar(&archivePath, "archive", "", "archive to write on export or read on import")
+	flag.StringVar(&functionName, "function", "", "function to export, or name to import it under (defaults to exported name)")
+	flag.StringVar(&eventingAddr, "eventing", "http://127.0.0.1:8096", "eventing REST endpoint of cluster")
+	flag.StringVar(&clusterAddr, "cluster", "http://127.0.0.1:8091", "cluster REST endpoint, to read failover logs from on import with -resume seqnos")
+	flag.StringVar(&kvConnStr, "kv", "couchbase://127.0.0.1", "connection string of cluster holding metadata bucket")
+	flag.StringVar(&username, "user", "", "cluster username")
+	flag.StringVar(&password, "pass", "", "cluster password")
+	flag.IntVar(&numVbuckets, "vbuckets", 1024, "number of vbuckets of buckets on cluster")
+	flag.DurationVar(&overlap, "overlap", 5*time.Minute, "on export, how far before export time to resume processing mutations")
+	flag.StringVar(&sourceBucket, "source_bucket", "", "on import, source bucket to use instead of exported one")
+	flag.StringVar(&metadataBucket, "metadata_bucket", "", "on import, metadata bucket to use instead of exported one")
+	flag.StringVar(&resume, "resume", resumeTimestamp, "on import, resume processing from [timestamp|seqnos]")
 	flag.BoolVar(&showSample, "s", false, "sample examples")
 	flag.Parse()
 }
 
 func main() {
-
 	if showSample {
 		usage()
 		return
 	}
 
-	mode = strings.ToLower(mode)
-	if mode == "client" && remoteFile == "" {
-		fmt.Printf("client mode needs remotefile.\n")
+	if archivePath == "" {
+		fmt.Println("archive must be specified")
 		os.Exit(1)
 	}
-	if mode == "client" && localFile == "" {
-		fmt.Println("set localfile as remotefile")
-		switch ftype {
-		case "file":
-			localFile = remoteFile
-		case "dir":
-			localFile = remoteFile + ".zip"
+
+	var err error
+	switch strings.ToLower(mode) {
+	case "export":
+		if functionName == "" {
+			fmt.Println("export needs function")
+			os.Exit(1)
 		}
-	}
+		err = exportFunction()
 
-	switch mode {
-	case "server":
-		server := timer.NewTimerTransfer("credit_score", readDirectory, "127.0.0.1:25000", "worker_0")
-		server.Serve()
+	case "import":
+		if resume != resumeTimestamp && resume != resumeSeqNos {
+			fmt.Printf("resume must be one of %s or %s\n", resumeTimestamp, resumeSeqNos)
+			os.Exit(1)
+		}
+		err = importFunction()
 
 	default:
-		client := timer.NewRPCClient(addr, "credit_score", "worker_0")
-		if err := client.DialPath("/worker_0/"); err != nil {
-			panic(err)
-		}
-		switch ftype {
-		case "dir":
-			err := client.DownloadDir(remoteFile, localFile)
-			if err != nil {
-				fmt.Printf("DownloadDir err: %v\n", err)
-			}
-		case "file":
-			err := client.DownloadAt(remoteFile, localFile, resumeID)
-			if err != nil {
-				fmt.Printf("DownloadAt err: %v\n", err)
-			}
-		}
+		fmt.Println("mode must be one of export or import")
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Printf("%s failed, err: %v\n", mode, err)
+		os.Exit(1)
 	}
 }
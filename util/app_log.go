@@ -0,0 +1,251 @@
+package util
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cm "github.com/couchbase/eventing/common"
+)
+
+// Layout of timestamps application log lines start with
+const AppLogTimeLayout = "2006-01-02T15:04:05.000-07:00"
+
+// Application log lines are "<timestamp> [<level>] <message>", while further lines of a multi
+// line message follow as they are
+var appLogLineRegex = regexp.MustCompile(`^(\S+) \[(\w+)\] (.*)$`)
+
+type appLogReader struct {
+	current *cm.AppLogEntry
+	entries []*cm.AppLogEntry
+	filter  *cm.AppLogFilter
+	grep    *regexp.Regexp
+}
+
+// ReadAppLog reads entries matching filter from application log at path and from its rotated
+// files. When following the log, a current file smaller than offset is taken to have been
+// rotated since, so rest of it is read from latest rotated file
+func ReadAppLog(path string, filter *cm.AppLogFilter) (*cm.AppLogTail, error) {
+	r := &appLogReader{filter: filter}
+	if filter.Grep != "" {
+		grep, err := regexp.Compile(filter.Grep)
+		if err != nil {
+			return nil, err
+		}
+		r.grep = grep
+	}
+
+	tail := &cm.AppLogTail{}
+	rotated := appLogRotatedFiles(path)
+
+	if filter.Offset >= 0 {
+		offset := filter.Offset
+		fi, err := os.Stat(path)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil && fi.Size() < offset {
+			if len(rotated) > 0 {
+				if _, err = r.readFile(rotated[len(rotated)-1], offset); err != nil {
+					return nil, err
+				}
+			}
+			offset = 0
+		}
+		if tail.Offset, err = r.readFile(path, offset); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, file := range append(rotated, path) {
+			fi, err := os.Stat(file)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+
+			// Rotated files last written before start of range have nothing of interest
+			if file != path && fi.ModTime().Before(filter.Since) {
+				continue
+			}
+
+			offset, err := r.readFile(file, 0)
+			if err != nil {
+				return nil, err
+			}
+			if file == path {
+				tail.Offset = offset
+			}
+		}
+	}
+
+	r.flush()
+	tail.Entries = r.latest()
+	return tail, nil
+}
+
+// Reads complete lines of file from offset and returns offset past the last of them, so a
+// line still being written is read in full next time. Offsets into compressed files are
+// into their uncompressed content
+func (r *appLogReader) readFile(file string, offset int64) (int64, error) {
+	fp, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	defer fp.Close()
+
+	var reader io.Reader = fp
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(fp)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+
+		if _, err = io.CopyN(ioutil.Discard, gz, offset); err != nil && err != io.EOF {
+			return 0, err
+		}
+		reader = gz
+	} else if _, err = fp.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	br := bufio.NewReader(reader)
+	for {
+		line, err := br.ReadString('\n')
+		if err == io.EOF {
+			return offset, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		offset += int64(len(line))
+		r.line(strings.TrimRight(line, "\r\n"))
+	}
+}
+
+func (r *appLogReader) line(line string) {
+	if match := appLogLineRegex.FindStringSubmatch(line); match != nil {
+		if ts, err := time.Parse(AppLogTimeLayout, match[1]); err == nil {
+			r.flush()
+			r.current = &cm.AppLogEntry{Level: match[2], Message: match[3], Timestamp: ts}
+			return
+		}
+	}
+
+	if r.current != nil {
+		r.current.Message += "\n" + line
+		return
+	}
+	// Rest of a message read before
+	r.current = &cm.AppLogEntry{Message: line}
+}
+
+func (r *appLogReader) flush() {
+	entry := r.current
+	r.current = nil
+	if entry == nil {
+		return
+	}
+
+	if !r.filter.Since.IsZero() && entry.Timestamp.Before(r.filter.Since) {
+		return
+	}
+	if r.grep != nil && !r.grep.MatchString(entry.Message) {
+		return
+	}
+
+	r.entries = append(r.entries, entry)
+	if r.filter.Limit > 0 && len(r.entries) >= 2*r.filter.Limit {
+		r.entries = r.latest()
+	}
+}
+
+func (r *appLogReader) latest() []*cm.AppLogEntry {
+	entries := r.entries
+	if r.filter.Limit > 0 && len(entries) > r.filter.Limit {
+		entries = entries[len(entries)-r.filter.Limit:]
+	}
+	return append(make([]*cm.AppLogEntry, 0, len(entries)), entries...)
+}
+
+// Lists rotated files of application log at path, compressed or not, oldest first
+func appLogRotatedFiles(path string) []string {
+	files, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil
+	}
+
+	indexes := make(map[string]int64)
+	rotated := make([]string, 0, len(files))
+	for _, file := range files {
+		suffix := strings.TrimSuffix(strings.TrimPrefix(file, path+"."), ".gz")
+		index, err := strconv.ParseInt(suffix, 10, 64)
+		if err != nil {
+			continue
+		}
+		indexes[file] = index
+		rotated = append(rotated, file)
+	}
+
+	sort.Slice(rotated, func(i, j int) bool {
+		return indexes[rotated[i]] < indexes[rotated[j]]
+	})
+	return rotated
+}
+
+// GetAppLog reads application log of a function on each of eventing nodes, keyed by node
+func GetAppLog(urlSuffix string, nodeAddrs []string) (map[string]*cm.AppLogTail, NodeErrors) {
+	tails := make(map[string]*cm.AppLogTail)
+
+	errs := requestNodes("util::GetAppLog", "GET", urlSuffix, nodeAddrs, HTTPRequestTimeout,
+		func(nodeAddr string, buf []byte) error {
+			var tail cm.AppLogTail
+			if err := decodeNodeResponse(buf, &tail); err != nil {
+				return err
+			}
+
+			for _, entry := range tail.Entries {
+				entry.Node = nodeAddr
+			}
+			tails[nodeAddr] = &tail
+			return nil
+		})
+
+	return tails, errs
+}
+
+// MergeAppLogs orders entries read on all nodes by time and keeps limit latest of them
+func MergeAppLogs(tails map[string]*cm.AppLogTail, limit int) []*cm.AppLogEntry {
+	nodes := make([]string, 0, len(tails))
+	for node := range tails {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	entries := make([]*cm.AppLogEntry, 0)
+	for _, node := range nodes {
+		entries = append(entries, tails[node].Entries...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries
+}
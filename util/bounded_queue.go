@@ -41,9 +41,6 @@ func NewBoundedQueue(maxcount, maxsize uint64) *BoundedQueue {
 
 func (q *BoundedQueue) Push(elem Element) error {
 	elemsz := elem.Size()
-	if elemsz > q.maxsize {
-		return ErrorSize
-	}
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	var next uint64
@@ -51,6 +48,10 @@ func (q *BoundedQueue) Push(elem Element) error {
 		if q.closed == true {
 			return ErrorClosed
 		}
+		// Checked on every wakeup, as quota may have been lowered meanwhile
+		if elemsz > q.maxsize {
+			return ErrorSize
+		}
 		next = (q.rear + 1) % q.maxcount
 		if next != q.front && q.size+elemsz <= q.maxsize {
 			break
@@ -112,3 +113,23 @@ func (q *BoundedQueue) IsClosed() bool {
 func (q *BoundedQueue) Count() uint64 {
 	return q.count
 }
+
+// Size returns memory held by elements in queue
+func (q *BoundedQueue) Size() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+// SetMaxSize revises memory quota of queue. Pushes blocked on a full queue go through if quota
+// is raised, while a lowered quota only blocks pushes until queue drains below it
+func (q *BoundedQueue) SetMaxSize(maxsize uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	grown := maxsize > q.maxsize
+	q.maxsize = maxsize
+	if grown && q.waitfull > 0 {
+		q.waitfull = 0
+		q.notfull.Broadcast()
+	}
+}
@@ -0,0 +1,222 @@
+package util
+
+import (
+	"encoding/json"
+	"math"
+	"math/bits"
+	"strconv"
+
+	cm "github.com/couchbase/eventing/common"
+)
+
+// Values below histSubBuckets get a bucket each, while every power of two above is split into
+// histSubBuckets/2 buckets, keeping bucket width within 1/64 of value like HDR histograms do.
+// Eventing-consumer buckets the same way, so histograms merge by adding up counts of buckets
+const (
+	histSubBucketBits  = 7
+	histSubBuckets     = 1 << histSubBucketBits
+	histHalfSubBuckets = histSubBuckets / 2
+	histMaxExponent    = 40
+	histBuckets        = histSubBuckets + (histMaxExponent-histSubBucketBits+1)*histHalfSubBuckets
+)
+
+// Histogram counts latencies, in microseconds, accurately enough for percentiles and cheaply
+// enough to be merged across workers and nodes. It marshals into a map from lower bound of
+// buckets to their counts, as eventing-consumer reports histograms in. Not safe for concurrent
+// use
+type Histogram struct {
+	counts []uint64
+	total  uint64
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{counts: make([]uint64, histBuckets)}
+}
+
+// HistogramFromBuckets builds a histogram from counts keyed by values falling in buckets,
+// skipping keys that aren't values
+func HistogramFromBuckets(buckets map[string]uint64) *Histogram {
+	h := NewHistogram()
+	for key, count := range buckets {
+		value, err := strconv.ParseInt(key, 10, 64)
+		if err != nil {
+			continue
+		}
+		h.RecordN(value, count)
+	}
+	return h
+}
+
+func histIndex(value int64) int {
+	if value < histSubBuckets {
+		if value < 0 {
+			return 0
+		}
+		return int(value)
+	}
+
+	exponent := bits.Len64(uint64(value)) - 1
+	if exponent > histMaxExponent {
+		return histBuckets - 1
+	}
+
+	shift := uint(exponent - histSubBucketBits + 1)
+	return histSubBuckets + (exponent-histSubBucketBits)*histHalfSubBuckets +
+		int(value>>shift) - histHalfSubBuckets
+}
+
+func histLowerBound(index int) int64 {
+	if index < histSubBuckets {
+		return int64(index)
+	}
+
+	exponent := histSubBucketBits + (index-histSubBuckets)/histHalfSubBuckets
+	subBucket := histHalfSubBuckets + (index-histSubBuckets)%histHalfSubBuckets
+	return int64(subBucket) << uint(exponent-histSubBucketBits+1)
+}
+
+// Highest value falling in bucket at index
+func histHighestEquivalent(index int) int64 {
+	if index < histSubBuckets {
+		return int64(index)
+	}
+	if index == histBuckets-1 {
+		return math.MaxInt64
+	}
+	return histLowerBound(index+1) - 1
+}
+
+func (h *Histogram) Record(value int64) {
+	h.RecordN(value, 1)
+}
+
+func (h *Histogram) RecordN(value int64, count uint64) {
+	h.counts[histIndex(value)] += count
+	h.total += count
+}
+
+func (h *Histogram) Merge(o *Histogram) {
+	for i, count := range o.counts {
+		h.counts[i] += count
+	}
+	h.total += o.total
+}
+
+// Sub takes away counts of an earlier snapshot of this histogram. It returns false, leaving
+// histogram as is, if o counts more in some bucket, as it can't be a snapshot of this one then
+func (h *Histogram) Sub(o *Histogram) bool {
+	for i, count := range o.counts {
+		if count > h.counts[i] {
+			return false
+		}
+	}
+
+	for i, count := range o.counts {
+		h.counts[i] -= count
+	}
+	h.total -= o.total
+	return true
+}
+
+func (h *Histogram) Count() uint64 {
+	return h.total
+}
+
+// Percentile returns value at or below which p percent of values recorded fall, as highest
+// value of the bucket it falls in
+func (h *Histogram) Percentile(p float64) int64 {
+	if h.total == 0 {
+		return 0
+	}
+
+	target := uint64(math.Ceil(p / 100 * float64(h.total)))
+	if target < 1 {
+		target = 1
+	}
+
+	var seen uint64
+	for i, count := range h.counts {
+		seen += count
+		if seen >= target {
+			return histHighestEquivalent(i)
+		}
+	}
+	return h.Max()
+}
+
+func (h *Histogram) Max() int64 {
+	for i := len(h.counts) - 1; i >= 0; i-- {
+		if h.counts[i] > 0 {
+			return histHighestEquivalent(i)
+		}
+	}
+	return 0
+}
+
+func (h *Histogram) Summary() *cm.LatencySummary {
+	return &cm.LatencySummary{
+		Count: h.total,
+		Max:   h.Max(),
+		P50:   h.Percentile(50),
+		P90:   h.Percentile(90),
+		P99:   h.Percentile(99),
+		P999:  h.Percentile(99.9),
+	}
+}
+
+// Buckets returns counts of non empty buckets keyed by their lower bound
+func (h *Histogram) Buckets() map[string]uint64 {
+	buckets := make(map[string]uint64)
+	for i, count := range h.counts {
+		if count > 0 {
+			buckets[strconv.FormatInt(histLowerBound(i), 10)] = count
+		}
+	}
+	return buckets
+}
+
+func (h *Histogram) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Buckets())
+}
+
+func (h *Histogram) UnmarshalJSON(data []byte) error {
+	var buckets map[string]uint64
+	if err := json.Unmarshal(data, &buckets); err != nil {
+		return err
+	}
+
+	*h = *HistogramFromBuckets(buckets)
+	return nil
+}
+
+// GetLatencyHistograms gathers latency histograms of functions from each of eventing nodes and
+// merges them, keyed by function and kind of latency
+func GetLatencyHistograms(urlSuffix string, nodeAddrs []string) (map[string]map[string]*Histogram, NodeErrors) {
+	histograms := make(map[string]map[string]*Histogram)
+
+	errs := requestNodes("util::GetLatencyHistograms", "GET", urlSuffix, nodeAddrs, HTTPRequestTimeout,
+		func(nodeAddr string, buf []byte) error {
+			var nodeHistograms map[string]map[string]*Histogram
+			if err := decodeNodeResponse(buf, &nodeHistograms); err != nil {
+				return err
+			}
+
+			for appName, appHistograms := range nodeHistograms {
+				if _, ok := histograms[appName]; !ok {
+					histograms[appName] = make(map[string]*Histogram)
+				}
+				for kind, h := range appHistograms {
+					if h == nil {
+						continue
+					}
+					if _, ok := histograms[appName][kind]; !ok {
+						histograms[appName][kind] = NewHistogram()
+					}
+					histograms[appName][kind].Merge(h)
+				}
+			}
+			return nil
+		})
+
+	return histograms, errs
+}
@@ -0,0 +1,178 @@
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Port of Histogram::Index in v8_consumer/include/histogram.h, including clamping done by
+// Histogram::Add, which buckets of both sides have to agree with to be merged
+func cppHistIndex(sample int64) int {
+	if sample < histSubBuckets {
+		if sample < 0 {
+			return 0
+		}
+		return int(sample)
+	}
+
+	exponent := int64(histSubBucketBits)
+	for (sample >> uint(exponent+1)) > 0 {
+		exponent++
+	}
+
+	shift := uint(exponent - histSubBucketBits + 1)
+	index := histSubBuckets + (exponent-histSubBucketBits)*histHalfSubBuckets + ((sample >> shift) - histHalfSubBuckets)
+	if index >= histBuckets {
+		index = histBuckets - 1
+	}
+	return int(index)
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	if histBuckets != 2304 {
+		t.Fatalf("Expected 2304 buckets as eventing-consumer has, got %d", histBuckets)
+	}
+
+	tests := []struct {
+		value      int64
+		index      int
+		lowerBound int64
+	}{
+		{value: -5, index: 0, lowerBound: 0},
+		{value: 0, index: 0, lowerBound: 0},
+		{value: 127, index: 127, lowerBound: 127},
+		{value: 128, index: 128, lowerBound: 128},
+		{value: 129, index: 128, lowerBound: 128},
+		{value: 130, index: 129, lowerBound: 130},
+		{value: 255, index: 191, lowerBound: 254},
+		{value: 256, index: 192, lowerBound: 256},
+		{value: 1000, index: 317, lowerBound: 1000},
+		{value: 1007, index: 317, lowerBound: 1000},
+		{value: 1 << 40, index: 2240, lowerBound: 1 << 40},
+		{value: 1 << 41, index: 2303, lowerBound: 127 << 34},
+	}
+
+	for _, test := range tests {
+		index := histIndex(test.value)
+		if index != test.index || index != cppHistIndex(test.value) {
+			t.Errorf("Value: %d expected index %d, got %d, eventing-consumer: %d",
+				test.value, test.index, index, cppHistIndex(test.value))
+			continue
+		}
+		if lowerBound := histLowerBound(index); lowerBound != test.lowerBound {
+			t.Errorf("Value: %d expected lower bound %d, got %d", test.value, test.lowerBound, lowerBound)
+		}
+	}
+
+	for value := int64(0); value < 1<<20; value += 7 {
+		if histIndex(value) != cppHistIndex(value) {
+			t.Fatalf("Value: %d falls in bucket %d, but in %d on eventing-consumer", value, histIndex(value), cppHistIndex(value))
+		}
+	}
+}
+
+func TestHistogramBucketBounds(t *testing.T) {
+	for i := 0; i < histBuckets-1; i++ {
+		lowerBound, highest := histLowerBound(i), histHighestEquivalent(i)
+		if histIndex(lowerBound) != i || histIndex(highest) != i {
+			t.Fatalf("Bucket %d spans %d to %d, which fall in buckets %d and %d",
+				i, lowerBound, highest, histIndex(lowerBound), histIndex(highest))
+		}
+		if highest+1 != histLowerBound(i+1) {
+			t.Fatalf("Bucket %d ends at %d, but next one starts at %d", i, highest, histLowerBound(i+1))
+		}
+		if width := highest - lowerBound + 1; width > 1 && width*64 > lowerBound {
+			t.Fatalf("Bucket %d is %d wide, more than 1/64 of %d", i, width, lowerBound)
+		}
+	}
+}
+
+func TestHistogramPercentile(t *testing.T) {
+	h := NewHistogram()
+	if h.Percentile(50) != 0 || h.Max() != 0 {
+		t.Errorf("Expected empty histogram to report 0, got p50: %d max: %d", h.Percentile(50), h.Max())
+	}
+
+	for value := int64(1); value <= 100; value++ {
+		h.Record(value)
+	}
+
+	tests := []struct {
+		p     float64
+		value int64
+	}{
+		{p: 0, value: 1},
+		{p: 50, value: 50},
+		{p: 90, value: 90},
+		{p: 99, value: 99},
+		{p: 100, value: 100},
+	}
+	for _, test := range tests {
+		if value := h.Percentile(test.p); value != test.value {
+			t.Errorf("Expected p%v to be %d, got %d", test.p, test.value, value)
+		}
+	}
+
+	// Above sub buckets, percentile is highest value of bucket it falls in
+	h.RecordN(1000, 100)
+	if value := h.Percentile(99); value != 1007 {
+		t.Errorf("Expected p99 to be 1007, got %d", value)
+	}
+	if h.Max() != 1007 || h.Count() != 200 {
+		t.Errorf("Expected max: 1007 count: 200, got max: %d count: %d", h.Max(), h.Count())
+	}
+}
+
+func TestHistogramSub(t *testing.T) {
+	h := NewHistogram()
+	h.RecordN(10, 5)
+	h.RecordN(1000, 3)
+
+	snapshot := NewHistogram()
+	snapshot.Merge(h)
+
+	h.RecordN(10, 2)
+	h.Record(5000)
+
+	if !h.Sub(snapshot) {
+		t.Fatalf("Expected snapshot to be taken away")
+	}
+	expected := map[string]uint64{"10": 2, "4992": 1}
+	if !reflect.DeepEqual(h.Buckets(), expected) || h.Count() != 3 {
+		t.Errorf("Expected buckets %v count: 3, got %v count: %d", expected, h.Buckets(), h.Count())
+	}
+
+	// Histogram counting more isn't a snapshot, histogram is left as is
+	bigger := NewHistogram()
+	bigger.RecordN(10, 3)
+	if h.Sub(bigger) {
+		t.Errorf("Expected histogram counting more in a bucket to be refused")
+	}
+	if !reflect.DeepEqual(h.Buckets(), expected) || h.Count() != 3 {
+		t.Errorf("Expected histogram to be left as is, got %v count: %d", h.Buckets(), h.Count())
+	}
+}
+
+func TestHistogramJSON(t *testing.T) {
+	h := NewHistogram()
+	h.RecordN(3, 2)
+	h.Record(1000)
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatalf("Failed to marshal histogram, err: %v", err)
+	}
+	if string(data) != `{"1000":1,"3":2}` {
+		t.Errorf("Expected buckets keyed by lower bound, got %s", data)
+	}
+
+	// Buckets reported by eventing-consumer are keyed by lower bound as well
+	decoded := &Histogram{}
+	if err = json.Unmarshal([]byte(`{"3":2,"1000":1,"invalid":4}`), decoded); err != nil {
+		t.Fatalf("Failed to unmarshal histogram, err: %v", err)
+	}
+	if !reflect.DeepEqual(decoded.Buckets(), h.Buckets()) || decoded.Count() != 3 {
+		t.Errorf("Expected %v count: 3, got %v count: %d", h.Buckets(), decoded.Buckets(), decoded.Count())
+	}
+}
@@ -0,0 +1,130 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/couchbase/eventing/logging"
+)
+
+// NodeErrors are failures of a request made to eventing nodes, keyed by node address
+type NodeErrors map[string]error
+
+func (e NodeErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, node := range e.Nodes() {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", node, e[node]))
+	}
+	return strings.Join(msgs, ", ")
+}
+
+// Nodes returns addresses of nodes that failed, in order
+func (e NodeErrors) Nodes() []string {
+	nodes := make([]string, 0, len(e))
+	for node := range e {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Messages returns failures keyed by node address, as they're reported in responses
+func (e NodeErrors) Messages() map[string]string {
+	if len(e) == 0 {
+		return nil
+	}
+
+	msgs := make(map[string]string, len(e))
+	for node, err := range e {
+		msgs[node] = err.Error()
+	}
+	return msgs
+}
+
+// Error response of an eventing node, of which only what's needed to report it is decoded
+type nodeErrorResponse struct {
+	Name        string `json:"name"`
+	RuntimeInfo struct {
+		Info interface{} `json:"info"`
+	} `json:"runtime_info"`
+}
+
+// requestNodes makes request to urlSuffix on each of nodeAddrs in parallel, handing response
+// of each node to decode, one node at a time. Responses are closed as soon as they're read,
+// and nodes failing the request, or responding with an error, are reported by address rather
+// than failing the request to rest of them
+func requestNodes(logPrefix, method, urlSuffix string, nodeAddrs []string, timeout time.Duration,
+	decode func(nodeAddr string, buf []byte) error) NodeErrors {
+
+	netClient := NewClient(timeout)
+	errs := make(NodeErrors)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(len(nodeAddrs))
+
+	for _, nodeAddr := range nodeAddrs {
+		go func(nodeAddr string) {
+			defer wg.Done()
+
+			endpointURL := fmt.Sprintf("http://%s%s", nodeAddr, urlSuffix)
+			buf, err := requestNode(netClient, method, endpointURL)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err == nil {
+				err = decode(nodeAddr, buf)
+			}
+			if err != nil {
+				logging.Errorf("%s Request to url: %rs failed, err: %v", logPrefix, endpointURL, err)
+				errs[nodeAddr] = err
+			}
+		}(nodeAddr)
+	}
+
+	wg.Wait()
+	return errs
+}
+
+func requestNode(netClient *Client, method, endpointURL string) ([]byte, error) {
+	req, err := NewRequest(method, endpointURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := netClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	buf, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body, err: %v", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		var errResp nodeErrorResponse
+		if err = json.Unmarshal(buf, &errResp); err != nil || errResp.Name == "" {
+			return nil, fmt.Errorf("%s: %s", res.Status, strings.TrimSpace(string(buf)))
+		}
+		return nil, fmt.Errorf("%s: %v", errResp.Name, errResp.RuntimeInfo.Info)
+	}
+
+	return buf, nil
+}
+
+// Decodes JSON response of a node, quoting the response if it isn't what's expected
+func decodeNodeResponse(buf []byte, v interface{}) error {
+	if err := json.Unmarshal(buf, v); err != nil {
+		return fmt.Errorf("failed to unmarshal response, err: %v, response: %s", err, strings.TrimSpace(string(buf)))
+	}
+	return nil
+}
@@ -17,6 +17,9 @@ var (
 	metakvMaxDocSize   int = 4096
 	CrcTable           *crc32.Table
 	HTTPRequestTimeout = 5 * time.Second
+
+	// Checking timer stores walks every row of their spans
+	TimerCheckRequestTimeout = 30 * time.Minute
 )
 
 func init() {
@@ -0,0 +1,22 @@
+package util
+
+import (
+	cm "github.com/couchbase/eventing/common"
+)
+
+// GetStatsHistory gathers stats history of a function from each of eventing nodes, keyed by node
+func GetStatsHistory(urlSuffix string, nodeAddrs []string) (map[string][]*cm.StatsSample, NodeErrors) {
+	history := make(map[string][]*cm.StatsSample)
+
+	errs := requestNodes("util::GetStatsHistory", "GET", urlSuffix, nodeAddrs, HTTPRequestTimeout,
+		func(nodeAddr string, buf []byte) error {
+			var samples []*cm.StatsSample
+			if err := decodeNodeResponse(buf, &samples); err != nil {
+				return err
+			}
+			history[nodeAddr] = samples
+			return nil
+		})
+
+	return history, errs
+}
@@ -395,6 +395,133 @@ func GetEventProcessingStats(urlSuffix string, nodeAddrs []string) (map[string]i
 	return pStats, nil
 }
 
+func GetRollbackHistory(urlSuffix string, nodeAddrs []string) ([]*cm.RollbackEntry, NodeErrors) {
+	history := make([]*cm.RollbackEntry, 0)
+
+	errs := requestNodes("util::GetRollbackHistory", "GET", urlSuffix, nodeAddrs, HTTPRequestTimeout,
+		func(nodeAddr string, buf []byte) error {
+			var nodeHistory []*cm.RollbackEntry
+			if err := decodeNodeResponse(buf, &nodeHistory); err != nil {
+				return err
+			}
+			history = append(history, nodeHistory...)
+			return nil
+		})
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp < history[j].Timestamp
+	})
+
+	return history, errs
+}
+
+// GetVbLag gathers lag of vbuckets of a function from each of eventing nodes
+func GetVbLag(urlSuffix string, nodeAddrs []string) ([]*cm.VbLag, NodeErrors) {
+	lags := make([]*cm.VbLag, 0)
+
+	errs := requestNodes("util::GetVbLag", "GET", urlSuffix, nodeAddrs, HTTPRequestTimeout,
+		func(nodeAddr string, buf []byte) error {
+			var nodeLags []*cm.VbLag
+			if err := decodeNodeResponse(buf, &nodeLags); err != nil {
+				return err
+			}
+			lags = append(lags, nodeLags...)
+			return nil
+		})
+
+	return lags, errs
+}
+
+func GetCrashHistory(urlSuffix string, nodeAddrs []string) ([]*cm.CrashEntry, NodeErrors) {
+	history := make([]*cm.CrashEntry, 0)
+
+	errs := requestNodes("util::GetCrashHistory", "GET", urlSuffix, nodeAddrs, HTTPRequestTimeout,
+		func(nodeAddr string, buf []byte) error {
+			var nodeHistory []*cm.CrashEntry
+			if err := decodeNodeResponse(buf, &nodeHistory); err != nil {
+				return err
+			}
+			history = append(history, nodeHistory...)
+			return nil
+		})
+
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].Timestamp < history[j].Timestamp
+	})
+
+	return history, errs
+}
+
+func GetPendingTimers(urlSuffix string, nodeAddrs []string) ([]*cm.PendingTimer, NodeErrors) {
+	pending := make([]*cm.PendingTimer, 0)
+
+	errs := requestNodes("util::GetPendingTimers", "GET", urlSuffix, nodeAddrs, HTTPRequestTimeout,
+		func(nodeAddr string, buf []byte) error {
+			var nodeTimers []*cm.PendingTimer
+			if err := decodeNodeResponse(buf, &nodeTimers); err != nil {
+				return err
+			}
+			pending = append(pending, nodeTimers...)
+			return nil
+		})
+
+	SortPendingTimers(pending)
+	return pending, errs
+}
+
+// SortPendingTimers orders timers by due time, then vbucket and then seq among timers
+// due at the same time, the order they're listed and paged through in
+func SortPendingTimers(pending []*cm.PendingTimer) {
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Due != pending[j].Due {
+			return pending[i].Due < pending[j].Due
+		}
+		if pending[i].Vbucket != pending[j].Vbucket {
+			return pending[i].Vbucket < pending[j].Vbucket
+		}
+		return pending[i].Seq < pending[j].Seq
+	})
+}
+
+func CancelTimer(urlSuffix string, nodeAddrs []string) (bool, NodeErrors) {
+	cancelled := false
+
+	errs := requestNodes("util::CancelTimer", "DELETE", urlSuffix, nodeAddrs, HTTPRequestTimeout,
+		func(nodeAddr string, buf []byte) error {
+			var nodeCancelled bool
+			if err := decodeNodeResponse(buf, &nodeCancelled); err != nil {
+				return err
+			}
+			cancelled = cancelled || nodeCancelled
+			return nil
+		})
+
+	return cancelled, errs
+}
+
+func CheckTimers(urlSuffix string, nodeAddrs []string) (*cm.TimerCheckReport, NodeErrors) {
+	report := &cm.TimerCheckReport{
+		Stores:    make([]*cm.TimerStoreReport, 0),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+
+	errs := requestNodes("util::CheckTimers", "POST", urlSuffix, nodeAddrs, TimerCheckRequestTimeout,
+		func(nodeAddr string, buf []byte) error {
+			var nodeReport cm.TimerCheckReport
+			if err := decodeNodeResponse(buf, &nodeReport); err != nil {
+				return err
+			}
+			report.Merge(&nodeReport)
+			return nil
+		})
+
+	sort.SliceStable(report.Stores, func(i, j int) bool {
+		return report.Stores[i].Vbucket < report.Stores[j].Vbucket
+	})
+
+	return report, errs
+}
+
 func GetProgress(urlSuffix string, nodeAddrs []string) (*cm.RebalanceProgress, map[string]interface{}, map[string]error) {
 	logPrefix := "util::GetProgress"
 
@@ -1132,6 +1259,50 @@ func ToStringArray(from interface{}) (to []string) {
 	return
 }
 
+// URLWithoutPassword returns URL with password left out, to be logged or reported in stats
+func URLWithoutPassword(u *url.URL) string {
+	if u.User == nil {
+		return u.String()
+	}
+	stripped := *u
+	stripped.User = url.User(u.User.Username())
+	return stripped.String()
+}
+
+// RedactURL returns raw URL with password left out, or a placeholder if it doesn't parse
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid URL>"
+	}
+	return URLWithoutPassword(u)
+}
+
+// RedactURLSettings returns copy of settings with passwords left out of app_log_sinks and
+// trace_export_url, as these URLs may carry credentials of collectors
+func RedactURLSettings(settings map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(settings))
+	for key, val := range settings {
+		redacted[key] = val
+	}
+
+	if rawURL, ok := settings["trace_export_url"].(string); ok && rawURL != "" {
+		redacted["trace_export_url"] = RedactURL(rawURL)
+	}
+	if sinks, ok := settings["app_log_sinks"].([]interface{}); ok {
+		redactedSinks := make([]interface{}, len(sinks))
+		for i, sink := range sinks {
+			if rawURL, ok := sink.(string); ok {
+				redactedSinks[i] = RedactURL(rawURL)
+			} else {
+				redactedSinks[i] = sink
+			}
+		}
+		redacted["app_log_sinks"] = redactedSinks
+	}
+	return redacted
+}
+
 func FloatEquals(a, b float64) bool {
 	return math.Abs(a-b) <= EPSILON
 }
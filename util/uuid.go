@@ -13,6 +13,7 @@ import (
 	"bytes"
 	crypt "crypto/rand"
 	"encoding/binary"
+	"encoding/hex"
 	"io"
 	rnd "math/rand"
 	"strconv"
@@ -51,3 +52,12 @@ func SeedProcess() {
 		rnd.Seed(int64(seed))
 	}
 }
+
+// RandomHexID returns size random bytes hex encoded, as trace and span IDs are
+func RandomHexID(size int) (string, error) {
+	id := make([]byte, size)
+	if _, err := io.ReadFull(crypt.Reader, id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -38,11 +38,12 @@ enum v8_worker_opcode {
   oGetCompileInfo,
   oGetLcbExceptions,
   oGetCurlLatencyStats,
+  oGetDcpLatencyStats,
   oVersion,
   V8_Worker_Opcode_Unknown
 };
 
-enum dcp_opcode { oDelete, oMutation, DCP_Opcode_Unknown };
+enum dcp_opcode { oDelete, oMutation, oRollback, DCP_Opcode_Unknown };
 
 enum filter_opcode { oVbFilter, oProcessedSeqNo, Filter_Opcode_Unknown };
 
@@ -73,6 +74,7 @@ enum msg_type {
   mTimer_Response,
   mBucket_Ops_Response,
   mFilterAck,
+  mTrace_Response,
   Msg_Unknown
 };
 
@@ -89,6 +91,7 @@ enum v8_worker_config_opcode {
   oQueueSize,
   oLcbExceptions,
   oCurlLatencyStats,
+  oDcpLatencyStats,
   V8_Worker_Config_Opcode_Unknown
 };
 
@@ -96,4 +99,6 @@ enum doc_timer_response_opcode { timerResponse };
 
 enum bucket_ops_response_opcode { checkpointResponse };
 
+enum trace_response_opcode { traceSpans };
+
 #endif
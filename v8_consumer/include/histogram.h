@@ -15,10 +15,21 @@
 #include <iostream>
 #include <vector>
 
+// Samples below HIST_SUB_BUCKETS get a bucket each, while every power of two
+// above is split into HIST_SUB_BUCKETS / 2 buckets, keeping bucket width within
+// 1/64 of value like HDR histograms do. Go side of eventing buckets the same
+// way (util.Histogram), so histograms merge by adding up counts of buckets
+#define HIST_SUB_BUCKET_BITS 7
+#define HIST_SUB_BUCKETS (1 << HIST_SUB_BUCKET_BITS)
+#define HIST_HALF_SUB_BUCKETS (HIST_SUB_BUCKETS / 2)
+#define HIST_MAX_EXPONENT 40
+
 class Histogram {
 public:
-  Histogram(int64_t f, int64_t t, int64_t w) : from_(f), till_(t), width_(w) {
-    buckets_ = 1 + ((till_ - from_) / width_);
+  Histogram() {
+    buckets_ = HIST_SUB_BUCKETS +
+               (HIST_MAX_EXPONENT - HIST_SUB_BUCKET_BITS + 1) *
+                   HIST_HALF_SUB_BUCKETS;
     hgram_.assign(buckets_, 0);
 
     init_ = false;
@@ -33,6 +44,9 @@ public:
   void Add(int64_t sample);
   int64_t Mean();
 
+  static int64_t Index(int64_t sample);
+  static int64_t LowerBound(int64_t index);
+
   int64_t Buckets() { return buckets_; };
   std::vector<int64_t> Hgram() { return hgram_; };
   int64_t Min() { return min_val_; };
@@ -46,10 +60,7 @@ private:
   std::vector<int64_t> hgram_;
 
   int64_t buckets_;
-  int64_t from_;
   int64_t sum_;
-  int64_t till_;
-  int64_t width_;
   int64_t samples_;
 
   bool init_;
@@ -68,16 +79,39 @@ inline void Histogram::Add(int64_t sample) {
     max_val_ = sample;
   }
 
-  if (sample < from_) {
-    hgram_[0]++;
-  } else if (sample >= till_) {
-    hgram_[hgram_.size() - 1]++;
-  } else {
-    size_t index = ((sample - from_) / width_) + 1;
-    if ((index > 0) && (index < (hgram_.size() - 1))) {
-      hgram_[index]++;
-    }
+  auto index = Index(sample);
+  if (index >= buckets_) {
+    index = buckets_ - 1;
+  }
+  hgram_[index]++;
+}
+
+inline int64_t Histogram::Index(int64_t sample) {
+  if (sample < HIST_SUB_BUCKETS) {
+    return sample < 0 ? 0 : sample;
+  }
+
+  int64_t exponent = HIST_SUB_BUCKET_BITS;
+  while ((sample >> (exponent + 1)) > 0) {
+    exponent++;
+  }
+
+  auto shift = exponent - HIST_SUB_BUCKET_BITS + 1;
+  return HIST_SUB_BUCKETS +
+         (exponent - HIST_SUB_BUCKET_BITS) * HIST_HALF_SUB_BUCKETS +
+         ((sample >> shift) - HIST_HALF_SUB_BUCKETS);
+}
+
+inline int64_t Histogram::LowerBound(int64_t index) {
+  if (index < HIST_SUB_BUCKETS) {
+    return index;
   }
+
+  auto exponent = HIST_SUB_BUCKET_BITS +
+                  (index - HIST_SUB_BUCKETS) / HIST_HALF_SUB_BUCKETS;
+  auto sub_bucket = HIST_HALF_SUB_BUCKETS +
+                    (index - HIST_SUB_BUCKETS) % HIST_HALF_SUB_BUCKETS;
+  return sub_bucket << (exponent - HIST_SUB_BUCKET_BITS + 1);
 }
 
 inline int64_t Histogram::Mean() {
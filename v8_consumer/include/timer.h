@@ -23,6 +23,18 @@ struct EpochInfo {
   int64_t epoch;
 };
 
+// Optional schedule passed to createTimer, making the timer fire repeatedly
+struct Recurrence {
+  Recurrence()
+      : is_recurring(false), interval(0), jitter(0), max_occurrences(0) {}
+
+  bool is_recurring;
+  std::string cron;
+  int64_t interval;
+  int64_t jitter;
+  int64_t max_occurrences;
+};
+
 struct TimerInfo {
   TimerInfo() : epoch(0), vb(0), seq_num(0) {}
 
@@ -35,6 +47,7 @@ struct TimerInfo {
   std::string callback;
   std::string reference;
   std::string context;
+  Recurrence recurrence;
 };
 
 class Timer {
@@ -46,6 +59,8 @@ public:
 
 private:
   EpochInfo Epoch(const v8::Local<v8::Value> &date_val);
+  bool ParseRecurrence(const v8::Local<v8::Value> &schedule_val,
+                       Recurrence &recurrence);
   bool ValidateArgs(const v8::FunctionCallbackInfo<v8::Value> &args);
 
   v8::Isolate *isolate_;
@@ -55,12 +55,6 @@ typedef std::chrono::nanoseconds nsecs;
 
 #define SECS_TO_NS 1000 * 1000 * 1000ULL
 
-// Histogram to capture latency stats. Latency buckets have granularity of 1ms,
-// starting from 100us to 10s
-#define HIST_FROM 100
-#define HIST_TILL 1000 * 1000 * 10
-#define HIST_WIDTH 1000
-
 #define NUM_VBUCKETS 1024
 
 extern int64_t timer_context_size;
@@ -75,17 +69,28 @@ typedef struct timer_msg_s {
   std::string timer_entry;
 } timer_msg_t;
 
+// Report of a sampled event being traced, sent back to Go world
+typedef struct trace_msg_s {
+  std::size_t GetSize() const { return trace_entry.length(); }
+
+  std::string trace_entry;
+} trace_msg_t;
+
 // Header frame structure for messages from Go world
 typedef struct header_s {
   std::size_t GetSize() const {
     return metadata.length() + sizeof(event) + sizeof(opcode) +
-           sizeof(partition) + metadata.length();
+           sizeof(partition) + metadata.length() + sizeof(dcp_ts) +
+           trace_id.length();
   }
 
   uint8_t event;
   uint8_t opcode;
   int16_t partition;
   std::string metadata;
+  int64_t dcp_ts; // Unix time in ns the event was received from DCP, if any
+  // Set if the event is sampled for tracing
+  std::string trace_id;
 } header_t;
 
 // Flatbuffer encoded message from Go world
@@ -132,7 +137,8 @@ enum RETURN_CODE {
   kFailedInitBucketHandle,
   kOnUpdateCallFail,
   kOnDeleteCallFail,
-  kToLocalFailed
+  kToLocalFailed,
+  kOnRollbackCallFail
 };
 
 class Bucket;
@@ -149,6 +155,8 @@ extern std::atomic<int64_t> on_update_success;
 extern std::atomic<int64_t> on_update_failure;
 extern std::atomic<int64_t> on_delete_success;
 extern std::atomic<int64_t> on_delete_failure;
+extern std::atomic<int64_t> on_rollback_success;
+extern std::atomic<int64_t> on_rollback_failure;
 
 extern std::atomic<int64_t> timer_create_failure;
 
@@ -208,6 +216,7 @@ public:
   int SendUpdate(std::string value, std::string meta, int vb_no, int64_t seq_no,
                  std::string doc_type);
   int SendDelete(std::string meta, int vb_no, int64_t seq_no);
+  int SendRollback(std::string meta);
   void SendTimer(std::string callback, std::string timer_ctx);
   std::string CompileHandler(std::string handler);
   CodeVersion IdentifyVersion(std::string handler);
@@ -224,9 +233,14 @@ public:
 
   void UpdateHistogram(Time::time_point t);
   void UpdateCurlLatencyHistogram(const Time::time_point &start);
+  void UpdateDcpLatencyHistogram(int64_t dcp_ts);
+
+  void RecordTrace(const std::string &trace_id, int64_t dequeue_ts);
 
   void GetTimerMessages(std::vector<uv_buf_t> &messages, size_t window_size);
 
+  void GetTraceMessages(std::vector<uv_buf_t> &messages, size_t window_size);
+
   void GetBucketOpsMessages(std::vector<uv_buf_t> &messages);
 
   int UpdateVbFilter(const std::string &metadata);
@@ -253,6 +267,7 @@ public:
   v8::Persistent<v8::Context> context_;
   v8::Persistent<v8::Function> on_update_;
   v8::Persistent<v8::Function> on_delete_;
+  v8::Persistent<v8::Function> on_rollback_;
 
   std::string app_name_;
   std::string script_to_execute_;
@@ -272,6 +287,7 @@ public:
   std::thread processing_thr_;
   std::thread *terminator_thr_;
   Queue<timer_msg_t> *timer_queue_;
+  Queue<trace_msg_t> *trace_queue_;
   Queue<worker_msg_t> *worker_queue_;
 
   ConnectionPool *conn_pool_;
@@ -281,6 +297,7 @@ public:
 
   Histogram *histogram_;
   Histogram *curl_latency_;
+  Histogram *dcp_latency_;
   IsolateData data_;
 
 private:
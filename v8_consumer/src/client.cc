@@ -73,6 +73,10 @@ std::unique_ptr<header_t> ParseHeader(message_t *parsed_message) {
     parsed_header->partition = header->partition();
 
     parsed_header->metadata = header->metadata()->str();
+    parsed_header->dcp_ts = header->dcp_ts();
+    if (header->trace_id() != nullptr) {
+      parsed_header->trace_id = header->trace_id()->str();
+    }
 
     return parsed_header;
   }
@@ -406,11 +410,7 @@ std::string ToString(const std::vector<int64_t> &histogram) {
         out << ",";
       }
 
-      if (i == 0) {
-        out << R"(")" << HIST_FROM << R"(":)" << histogram[i];
-      } else {
-        out << R"(")" << i * HIST_WIDTH << R"(":)" << histogram[i];
-      }
+      out << R"(")" << Histogram::LowerBound(i) << R"(":)" << histogram[i];
     }
 
     if (i == histogram.size() - 1) {
@@ -548,6 +548,23 @@ void AppWorker::RouteMessageWithResponse(header_t *parsed_header,
       resp_msg_->opcode = oCurlLatencyStats;
       msg_priority_ = true;
       break;
+    case oGetDcpLatencyStats:
+      if (workers_.size() > 0) {
+        latency_buckets = workers_[0]->dcp_latency_->Buckets();
+        agg_hgram.assign(latency_buckets, 0);
+      }
+      for (const auto &w : workers_) {
+        worker_hgram = w.second->dcp_latency_->Hgram();
+        for (std::string::size_type i = 0; i < worker_hgram.size(); i++) {
+          agg_hgram[i] += worker_hgram[i];
+        }
+      }
+
+      resp_msg_->msg.assign(ToString(agg_hgram));
+      resp_msg_->msg_type = mV8_Worker_Config;
+      resp_msg_->opcode = oDcpLatencyStats;
+      msg_priority_ = true;
+      break;
     case oGetFailureStats:
       fstats.str(std::string());
       fstats << R"({"bucket_op_exception_count":)";
@@ -584,7 +601,9 @@ void AppWorker::RouteMessageWithResponse(header_t *parsed_header,
       estats << on_update_success << R"(, "on_update_failure":)";
       estats << on_update_failure << R"(, "on_delete_success":)";
       estats << on_delete_success << R"(, "on_delete_failure":)";
-      estats << on_delete_failure << R"(, "timer_create_failure":)";
+      estats << on_delete_failure << R"(, "on_rollback_success":)";
+      estats << on_rollback_success << R"(, "on_rollback_failure":)";
+      estats << on_rollback_failure << R"(, "timer_create_failure":)";
       estats << timer_create_failure << R"(, "messages_parsed":)";
       estats << messages_parsed << R"(, "dcp_delete_msg_counter":)";
       estats << dcp_delete_msg_counter << R"(, "dcp_mutation_msg_counter":)";
@@ -700,6 +719,15 @@ void AppWorker::RouteMessageWithResponse(header_t *parsed_header,
         ++mutation_events_lost;
       }
       break;
+    case oRollback:
+      worker_index = partition_thr_map_[parsed_header->partition];
+      if (workers_[worker_index] != nullptr) {
+        workers_[worker_index]->Enqueue(parsed_header, parsed_message);
+      } else {
+        LOG(logError) << "Rollback event lost: worker " << worker_index
+                      << " is null" << std::endl;
+      }
+      break;
     default:
       LOG(logError) << "Opcode " << getDCPOpcode(parsed_header->opcode)
                     << "is not implemented for eDCP" << std::endl;
@@ -892,6 +920,21 @@ void AppWorker::WriteResponses() {
       }
     }
 
+    // Report traces of sampled events
+    for (const auto &w : workers_) {
+      std::vector<uv_buf_t> messages;
+      w.second->GetTraceMessages(messages, batch_size);
+      if (messages.empty()) {
+        continue;
+      }
+
+      sleep = false;
+      WriteResponseWithRetry(feedback_conn_handle_, messages, batch_size);
+      for (auto &buf : messages) {
+        delete buf.base;
+      }
+    }
+
     if (sleep) {
       std::this_thread::sleep_for(std::chrono::milliseconds(100));
     }
@@ -52,6 +52,8 @@ v8_worker_opcode getV8WorkerOpcode(int8_t opcode) {
     return oGetLcbExceptions;
   if (opcode == 12)
     return oGetCurlLatencyStats;
+  if (opcode == 13)
+    return oGetDcpLatencyStats;
   return V8_Worker_Opcode_Unknown;
 }
 
@@ -60,6 +62,8 @@ dcp_opcode getDCPOpcode(int8_t opcode) {
     return oDelete;
   if (opcode == 2)
     return oMutation;
+  if (opcode == 3)
+    return oRollback;
   return DCP_Opcode_Unknown;
 }
 
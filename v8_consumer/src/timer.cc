@@ -76,6 +76,11 @@ bool Timer::CreateTimerImpl(const v8::FunctionCallbackInfo<v8::Value> &args) {
   timer_info.reference = utils->ToCPPString(args[2]);
   timer_info.context = JSONStringify(isolate_, args[3]);
 
+  if (args.Length() > 4 && !args[4]->IsUndefined() &&
+      !ParseRecurrence(args[4], timer_info.recurrence)) {
+    return false;
+  }
+
   if (timer_info.context.size() > timer_context_size) {
     js_exception->ThrowEventingError(
         "The context payload size is more than the configured size:" +
@@ -90,6 +95,57 @@ bool Timer::CreateTimerImpl(const v8::FunctionCallbackInfo<v8::Value> &args) {
   return true;
 }
 
+// Schedule is an object with either "cron" (5 field cron expression, in UTC) or
+// "interval" (seconds), along with optional "jitter" (seconds) and
+// "max_occurrences". Cron expression is validated when timer is stored
+bool Timer::ParseRecurrence(const v8::Local<v8::Value> &schedule_val,
+                            Recurrence &recurrence) {
+  auto js_exception = UnwrapData(isolate_)->js_exception;
+  auto utils = UnwrapData(isolate_)->utils;
+  v8::HandleScope handle_scope(isolate_);
+
+  if (!schedule_val->IsObject()) {
+    js_exception->ThrowEventingError(
+        "Fifth argument must be a JavaScript object describing the schedule");
+    return false;
+  }
+
+  auto cron_val = utils->GetPropertyFromObject(schedule_val, "cron");
+  if (!cron_val.IsEmpty() && !cron_val->IsUndefined()) {
+    if (!cron_val->IsString()) {
+      js_exception->ThrowEventingError("cron must be a JavaScript string");
+      return false;
+    }
+    recurrence.cron = utils->ToCPPString(cron_val);
+  }
+
+  const char *fields[] = {"interval", "jitter", "max_occurrences"};
+  int64_t *values[] = {&recurrence.interval, &recurrence.jitter,
+                       &recurrence.max_occurrences};
+  for (int i = 0; i < 3; ++i) {
+    auto val = utils->GetPropertyFromObject(schedule_val, fields[i]);
+    if (val.IsEmpty() || val->IsUndefined()) {
+      continue;
+    }
+
+    if (!val->IsNumber() || val.As<v8::Number>()->Value() < 0) {
+      js_exception->ThrowEventingError(std::string(fields[i]) +
+                                       " must be a non-negative number");
+      return false;
+    }
+    *values[i] = val.As<v8::Number>()->IntegerValue();
+  }
+
+  if (recurrence.cron.empty() == (recurrence.interval == 0)) {
+    js_exception->ThrowEventingError(
+        "Schedule must specify exactly one of cron and interval");
+    return false;
+  }
+
+  recurrence.is_recurring = true;
+  return true;
+}
+
 bool Timer::ValidateArgs(const v8::FunctionCallbackInfo<v8::Value> &args) {
   auto js_exception = UnwrapData(isolate_)->js_exception;
   if (args.kArgsLength < 3) {
@@ -180,6 +236,34 @@ std::string TimerInfo::ToJSON(v8::Isolate *isolate,
     }
   }
 
+  if (recurrence.is_recurring) {
+    auto schedule = v8::Object::New(isolate);
+    if (!recurrence.cron.empty() &&
+        !TO(schedule->Set(context, v8Str(isolate, "cron"),
+                          v8Str(isolate, recurrence.cron)),
+            &success) &&
+        !success) {
+      return json;
+    }
+
+    const char *fields[] = {"interval", "jitter", "max_occurrences"};
+    int64_t values[] = {recurrence.interval, recurrence.jitter,
+                        recurrence.max_occurrences};
+    for (int i = 0; i < 3; ++i) {
+      auto value = v8::Number::New(isolate, values[i]);
+      if (!TO(schedule->Set(context, v8Str(isolate, fields[i]), value),
+              &success) &&
+          !success) {
+        return json;
+      }
+    }
+
+    auto key = v8Str(isolate, "recurrence");
+    if (!TO(entry->Set(context, key, schedule), &success) && !success) {
+      return json;
+    }
+  }
+
   json = JSONStringify(isolate, entry);
   return json;
 }
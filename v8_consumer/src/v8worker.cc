@@ -28,6 +28,8 @@ std::atomic<int64_t> on_update_success = {0};
 std::atomic<int64_t> on_update_failure = {0};
 std::atomic<int64_t> on_delete_success = {0};
 std::atomic<int64_t> on_delete_failure = {0};
+std::atomic<int64_t> on_rollback_success = {0};
+std::atomic<int64_t> on_rollback_failure = {0};
 
 std::atomic<int64_t> timer_create_failure = {0};
 
@@ -43,6 +45,13 @@ std::atomic<int64_t> enqueued_timer_msg_counter = {0};
 
 std::atomic<int64_t> timer_callback_missing_counter = {0};
 
+// Wall clock time, comparable with timestamps taken in Go world
+static int64_t UnixTimeNs() {
+  return std::chrono::duration_cast<nsecs>(
+             std::chrono::system_clock::now().time_since_epoch())
+      .count();
+}
+
 v8::Local<v8::ObjectTemplate> V8Worker::NewGlobalObj() const {
   v8::EscapableHandleScope handle_scope(isolate_);
 
@@ -131,8 +140,9 @@ V8Worker::V8Worker(v8::Platform *platform, handler_config_t *h_config,
   std::ostringstream oss;
   oss << "\"" << function_id << "-" << function_instance_id << "\"";
   function_instance_id_.assign(oss.str());
-  histogram_ = new Histogram(HIST_FROM, HIST_TILL, HIST_WIDTH);
-  curl_latency_ = new Histogram(HIST_FROM, HIST_TILL, HIST_WIDTH);
+  histogram_ = new Histogram();
+  curl_latency_ = new Histogram();
+  dcp_latency_ = new Histogram();
   thread_exit_cond_.store(false);
   for (int i = 0; i < NUM_VBUCKETS; i++) {
     vb_seq_[i] = atomic_ptr_t(new std::atomic<int64_t>(0));
@@ -220,6 +230,7 @@ V8Worker::V8Worker(v8::Platform *platform, handler_config_t *h_config,
   delete config;
 
   this->timer_queue_ = new Queue<timer_msg_t>();
+  this->trace_queue_ = new Queue<trace_msg_t>();
   this->worker_queue_ = new Queue<worker_msg_t>();
 
   std::thread r_thr(&V8Worker::RouteMessage, this);
@@ -247,12 +258,15 @@ V8Worker::~V8Worker() {
   context_.Reset();
   on_update_.Reset();
   on_delete_.Reset();
+  on_rollback_.Reset();
   delete conn_pool_;
   delete n1ql_handle_;
   delete settings_;
   delete histogram_;
   delete curl_latency_;
+  delete dcp_latency_;
   delete timer_queue_;
+  delete trace_queue_;
   delete worker_queue_;
 }
 
@@ -381,6 +395,16 @@ int V8Worker::V8WorkerLoad(std::string script_to_execute) {
     on_delete_.Reset(isolate_, on_delete_fun);
   }
 
+  // OnRollback is optional and only invoked when the function is deployed
+  // with deliver_rollback_event set
+  v8::Local<v8::Value> on_rollback_def;
+  if (TO_LOCAL(global->Get(context, v8Str(isolate_, "OnRollback")),
+               &on_rollback_def) &&
+      on_rollback_def->IsFunction()) {
+    auto on_rollback_fun = on_rollback_def.As<v8::Function>();
+    on_rollback_.Reset(isolate_, on_rollback_fun);
+  }
+
   if (!bucket_handles_.empty()) {
     auto bucket_handle = bucket_handles_.begin();
 
@@ -413,6 +437,7 @@ void V8Worker::RouteMessage() {
     if (!worker_queue_->Pop(msg)) {
       continue;
     }
+    int64_t dequeue_ts = msg.header->trace_id.empty() ? 0 : UnixTimeNs();
     payload = flatbuf::payload::GetPayload(
         (const void *)msg.payload->payload.c_str());
 
@@ -437,6 +462,8 @@ void V8Worker::RouteMessage() {
             }
           } else {
             this->SendDelete(msg.header->metadata, vb_no, seq_no);
+            UpdateDcpLatencyHistogram(msg.header->dcp_ts);
+            RecordTrace(msg.header->trace_id, dequeue_ts);
           }
         }
         break;
@@ -453,9 +480,14 @@ void V8Worker::RouteMessage() {
             }
           } else {
             this->SendUpdate(val, msg.header->metadata, vb_no, seq_no, "json");
+            UpdateDcpLatencyHistogram(msg.header->dcp_ts);
+            RecordTrace(msg.header->trace_id, dequeue_ts);
           }
         }
         break;
+      case oRollback:
+        this->SendRollback(msg.header->metadata);
+        break;
       default:
         break;
       }
@@ -550,6 +582,35 @@ void V8Worker::UpdateCurlLatencyHistogram(const Time::time_point &start) {
   curl_latency_->Add(ns.count() / 1000);
 }
 
+// Latency from eventing receiving the event from DCP till its handler is done,
+// across processes, hence by wall clock
+void V8Worker::UpdateDcpLatencyHistogram(int64_t dcp_ts) {
+  if (dcp_ts <= 0) {
+    return;
+  }
+
+  auto now = UnixTimeNs();
+  if (now > dcp_ts) {
+    dcp_latency_->Add((now - dcp_ts) / 1000);
+  }
+}
+
+// Reports when a sampled event was picked off worker queue and when its handler
+// was done, which Go world completes its trace with
+void V8Worker::RecordTrace(const std::string &trace_id, int64_t dequeue_ts) {
+  if (trace_id.empty()) {
+    return;
+  }
+
+  std::ostringstream trace;
+  trace << R"({"trace_id":")" << trace_id << R"(", "dequeue_ts":)"
+        << dequeue_ts << R"(, "done_ts":)" << UnixTimeNs() << "}";
+
+  trace_msg_t msg;
+  msg.trace_entry = trace.str();
+  trace_queue_->Push(msg);
+}
+
 int V8Worker::SendUpdate(std::string value, std::string meta, int vb_no,
                          int64_t seq_no, std::string doc_type) {
   Time::time_point start_time = Time::now();
@@ -684,6 +745,43 @@ int V8Worker::SendDelete(std::string meta, int vb_no, int64_t seq_no) {
   return kSuccess;
 }
 
+int V8Worker::SendRollback(std::string meta) {
+  v8::Locker locker(isolate_);
+  v8::Isolate::Scope isolate_scope(isolate_);
+  v8::HandleScope handle_scope(isolate_);
+
+  auto context = context_.Get(isolate_);
+  v8::Context::Scope context_scope(context);
+
+  LOG(logInfo) << "Got rollback event, meta: " << meta << std::endl;
+  if (on_rollback_.IsEmpty()) {
+    return kSuccess;
+  }
+
+  v8::TryCatch try_catch(isolate_);
+
+  v8::Local<v8::Value> args[1];
+  if (!TO_LOCAL(v8::JSON::Parse(context, v8Str(isolate_, meta)), &args[0])) {
+    return kToLocalFailed;
+  }
+
+  auto on_rollback = on_rollback_.Get(isolate_);
+
+  execute_flag_ = true;
+  execute_start_time_ = Time::now();
+  on_rollback->Call(context->Global(), 1, args);
+  execute_flag_ = false;
+  if (try_catch.HasCaught()) {
+    LOG(logDebug) << "OnRollback Exception: "
+                  << ExceptionString(isolate_, &try_catch) << std::endl;
+    on_rollback_failure++;
+    return kOnRollbackCallFail;
+  }
+
+  on_rollback_success++;
+  return kSuccess;
+}
+
 void V8Worker::SendTimer(std::string callback, std::string timer_ctx) {
   LOG(logTrace) << "Got timer event, context:" << RU(timer_ctx)
                 << " callback:" << callback << std::endl;
@@ -882,6 +980,22 @@ void V8Worker::GetTimerMessages(std::vector<uv_buf_t> &messages,
   }
 }
 
+void V8Worker::GetTraceMessages(std::vector<uv_buf_t> &messages,
+                                size_t window_size) {
+  size_t trace_count = std::min(trace_queue_->Count(), window_size);
+
+  for (int64_t idx = 0; idx < trace_count; ++idx) {
+    trace_msg_t trace_msg;
+    if (!trace_queue_->Pop(trace_msg))
+      break;
+    auto curr_messages =
+        BuildResponse(trace_msg.trace_entry, mTrace_Response, traceSpans);
+    for (auto &msg : curr_messages) {
+      messages.push_back(msg);
+    }
+  }
+}
+
 void V8Worker::GetBucketOpsMessages(std::vector<uv_buf_t> &messages) {
   for (int vb = 0; vb < NUM_VBUCKETS; ++vb) {
     auto seq = vb_seq_[vb].get()->load(std::memory_order_seq_cst);
@@ -1040,6 +1154,7 @@ int64_t V8Worker::GetBucketopsSeqno(int vb_no) {
 void V8Worker::SetThreadExitFlag() {
   thread_exit_cond_.store(true);
   timer_queue_->Close();
+  trace_queue_->Close();
   worker_queue_->Close();
 }
 